@@ -0,0 +1,286 @@
+package whileinterp
+
+import "strconv"
+
+/*
+    Acceleration of the counting loops: when a WHILE only shifts its variables by a fixed amount on every
+    iteration (e.g. "WHILE(x != y) DO x = inc(x) OD"), its number of iterations is computed from the condition
+    and the iterations are applied at once. The nested loops whose iterations are the same on every iteration
+    of the outer loop are summarized too (e.g. a multiplication: "WHILE(a > 0) DO c = val(b); WHILE(c > 0) DO
+    r = inc(r); c = dec(c) OD; a = dec(a) OD").
+
+    The accelerated loops count the same steps as if every iteration was executed: a loop that doesn't
+    terminate (or exhausts the steps) is executed normally from the last iteration that fits on the steps,
+    and so is a loop paused by PauseAfter from the last iteration before the pause.
+*/
+
+// SetAcceleration enables the closed-form execution of the counting loops of the programs of the interpreter
+// (enabled by default; disable it to execute every iteration one by one, e.g. for teaching)
+func (in *Interpreter) SetAcceleration(on bool) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.noAccel = !on
+}
+
+// accelerates returns whether the programs of the interpreter execute the counting loops at once
+// return bool
+func (in *Interpreter) accelerates() bool {
+	if in == nil {
+		return true
+	}
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return !in.noAccel
+}
+
+// loopSummary is the effect of one iteration of a loop body on its variables
+type loopSummary struct {
+	modified map[string]bool //variables modified by the body
+	entry func(name string) (int, bool) //value of a variable when the loop is entered (false if it isn't known)
+	effect map[string]symValue //value of the modified variables after the statements summarized
+	steps int //steps of the statements summarized
+}
+
+// newLoopSummary initializes the summary of a WHILE, whose variables have the given values when it is entered
+// return *loopSummary
+func newLoopSummary(s stmt, entry func(name string) (int, bool)) *loopSummary {
+	modified := map[string]bool{}
+	getModifiedVars(s.body, modified)
+	return &loopSummary{modified: modified, entry: entry, effect: map[string]symValue{}}
+}
+
+// symOf returns the value of a variable after the statements summarized, relative to the start of the iteration
+// return symValue
+func (ls *loopSummary) symOf(name string) symValue {
+	if sv, ok := ls.effect[name]; ok {
+		return sv
+	}
+	if ls.modified[name] {
+		return symValue{base: name, known: true}
+	}
+	if n, ok := ls.entry(name); ok { //the variables not modified keep their value
+		return symValue{off: n, known: true}
+	}
+	return symValue{}
+}
+
+// value returns the value of a number, a variable or a function call after the statements summarized
+// return symValue
+func (ls *loopSummary) value(v *valueExpr) symValue {
+	switch v.kind {
+	case numberValue:
+		n, _ := strconv.Atoi(v.text)
+		return symValue{off: n, known: true}
+	case varValue:
+		return ls.symOf(v.text)
+	case indexValue: //the elements of the arrays aren't summarized
+		return symValue{}
+	}
+
+	switch v.text {
+	case "zero":
+		return symValue{known: true}
+	case "val":
+		return ls.value(v.params[0])
+	case "inc", "dec":
+		sv := ls.value(v.params[0])
+		if v.text == "inc" {
+			sv.off++
+		} else {
+			sv.off--
+		}
+		return sv
+	}
+	return symValue{} //the functions defined with PROC can't be accelerated
+}
+
+// summarizeBody summarizes the statements of a loop body
+// return bool (false if the statements can't be summarized)
+func (ls *loopSummary) summarizeBody(stmts []stmt) bool {
+	for _, s := range stmts {
+		ls.steps++
+		switch s.op {
+		case assignOPSTRING:
+			sv := ls.value(s.value)
+			if !sv.known || s.index != nil {
+				return false
+			}
+			ls.effect[s.name] = sv
+		case whileFuncSTRING:
+			if !ls.summarizeNested(s) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// summarizeNested summarizes a nested WHILE, whose iterations must be the same on every iteration of the loop
+// return bool (false if the nested WHILE can't be summarized)
+func (ls *loopSummary) summarizeNested(s stmt) bool {
+	inner := newLoopSummary(s, func(name string) (int, bool) {
+		sv := ls.symOf(name)
+		return sv.off, sv.known && sv.base == ""
+	})
+	if !inner.summarizeBody(s.body) {
+		return false
+	}
+	n, finite, ok := inner.iterations(s.cond)
+	if !ok || !finite {
+		return false
+	}
+
+	ls.steps += n * (inner.steps + 1) //the condition is a step on every iteration
+	for name := range inner.modified {
+		sv, current := inner.effect[name], ls.symOf(name)
+		switch {
+		case !current.known:
+			return false
+		case sv.base == name:
+			current.off += n * sv.off
+			ls.effect[name] = current
+		case n > 0:
+			ls.effect[name] = sv
+		}
+	}
+	return true
+}
+
+// iterations returns the number of iterations of the loop summarized, counted with the comparisons of its condition
+// return int, bool (false if the loop doesn't terminate), bool (false if the iterations can't be counted)
+func (ls *loopSummary) iterations(cond *logicExpr) (int, bool, bool) {
+	for name := range ls.modified { //every variable must be shifted or set to a constant on every iteration
+		if sv := ls.symOf(name); !sv.known || (sv.base != name && sv.base != "") {
+			return 0, false, false
+		}
+	}
+	comps, ok := conjuncts(cond, true)
+	if !ok {
+		return 0, false, false
+	}
+
+	n, finite := 0, false
+	for _, c := range comps {
+		first, ok := linearOf(c.first)
+		second, ok2 := linearOf(c.second)
+		if !ok || !ok2 {
+			return 0, false, false
+		}
+
+		diff := second.sub(first)
+		g, dg := diff.constant, 0 //difference when the loop is entered and its change on every iteration
+		for name, coef := range diff.coefs {
+			if coef == 0 {
+				continue
+			}
+			value, ok := ls.entry(name)
+			if !ok {
+				return 0, false, false
+			}
+			g += coef * value
+			if ls.modified[name] {
+				sv := ls.symOf(name)
+				if sv.base != name {
+					return 0, false, false
+				}
+				dg += coef * sv.off
+			}
+		}
+
+		if cn, cfinite := countIterations(c.op, g, dg); cfinite && (!finite || cn < n) {
+			n, finite = cn, true
+		}
+	}
+	return n, finite, true
+}
+
+// countIterations returns the number of iterations while a comparison holds, where the second value minus
+// the first value is g when the loop is entered and changes dg on every iteration
+// return int, bool (false if the comparison always holds)
+func countIterations(op string, g int, dg int) (int, bool) {
+	switch op {
+	case littleofOPSTRING:
+		return countPositive(g, dg)
+	case littleofOrIsOPSTRING:
+		return countPositive(g + 1, dg)
+	case biggerofOPSTRING:
+		return countPositive(-g, -dg)
+	case biggerofOrIsOPSTRING:
+		return countPositive(-g + 1, -dg)
+	case isOPSTRING:
+		if g != 0 {
+			return 0, true
+		}
+		if dg == 0 {
+			return 0, false
+		}
+		return 1, true
+	default: //the difference must reach zero exactly
+		if g == 0 {
+			return 0, true
+		}
+		if dg == 0 || (g > 0) == (dg > 0) || g % dg != 0 {
+			return 0, false
+		}
+		return -g / dg, true
+	}
+}
+
+// countPositive returns the number of iterations while a value h, changing dh on every iteration, is positive
+// return int, bool (false if the value is always positive)
+func countPositive(h int, dh int) (int, bool) {
+	if h <= 0 {
+		return 0, true
+	}
+	if dh >= 0 {
+		return 0, false
+	}
+	return (h - dh - 1) / -dh, true
+}
+
+// accelerateWhile applies at once the iterations of a counting loop of a program that fit on its steps
+// return bool (true if the loop has finished)
+func (p *program) accelerateWhile(s stmt) bool {
+	ls := newLoopSummary(s, func(name string) (int, bool) {
+		v, err := p.getVar(name)
+		return v.value, err == nil
+	})
+	if !ls.summarizeBody(s.body) {
+		return false
+	}
+	n, finite, ok := ls.iterations(s.cond)
+	if !ok {
+		return false
+	}
+
+	cost := ls.steps + 1 //the condition is a step on every iteration
+	limit := p.maxSteps
+	if p.pauseAt > 0 && (limit == 0 || p.pauseAt < limit) { //the iteration where the pause falls is executed normally
+		limit = p.pauseAt
+	}
+	if limit > 0 {
+		left := (limit - p.steps) / cost
+		if left < 0 {
+			left = 0
+		}
+		if !finite || n > left { //the last iterations are executed normally
+			n, finite = left, false
+		}
+	} else if !finite { //the loop is executed normally forever
+		return false
+	}
+
+	for name := range ls.modified {
+		sv := ls.symOf(name)
+		if sv.base == name {
+			v, _ := p.getVar(name)
+			p.setVar(&variable{name: name, value: v.value + n*sv.off})
+		} else if n > 0 {
+			p.setVar(&variable{name: name, value: sv.off})
+		}
+	}
+	p.steps += n * cost
+	return finite
+}
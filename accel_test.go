@@ -0,0 +1,101 @@
+package whileinterp
+
+import (
+    "reflect"
+    "testing"
+)
+
+var testAccelCodes = []string{
+    "WHILE(x0 != x1) DO x0 = inc(x0) OD",
+    "x0 = val(x1); c := val(x2); WHILE(c > 0) DO x0 = inc(x0); c = dec(c) OD",
+    "a := val(x1); c := 0; WHILE(a > 0) DO c = val(x2); WHILE(c > 0) DO x0 = inc(x0); c = dec(c) OD; a = dec(a) OD",
+    "c := val(x1); WHILE(c != 0) DO c = dec(dec(c)); x0 = inc(x0) OD",
+    "c := 0; WHILE(x0 < x1) DO x0 = inc(x0); c = 5 OD; x0 = val(c)",
+    "WHILE(x0 < x1 AND NOT x0 == 3) DO x0 = inc(x0) OD",
+    "WHILE(x0 == 0 OR x0 == 2) DO x0 = inc(inc(x0)) OD",
+    "WHILE(x0 == 0) DO x0 = inc(inc(x0)) OD",
+    "c := val(x2); WHILE(inc(x1) >= dec(c)) DO x1 = dec(x1); x0 = inc(x0) OD",
+    "WHILE(x0 <= x1) DO x1 = inc(x1); x0 = inc(inc(x0)) OD",
+    "WHILE(x1 > 0) DO x0 = inc(x0) OD",
+    "WHILE(x0 < x1) DO t := inc(x0); x0 = val(t) OD",
+}
+
+/*********************** TESTING ***********************/
+func TestAccelerateSameSteps(t *testing.T) {
+    for _, code := range testAccelCodes {
+        for x1 := 0; x1 < 5; x1++ {
+            for x2 := 0; x2 < 4; x2++ {
+                for maxSteps := 1; maxSteps < 60; maxSteps++ {
+                    slow, slowErr := doTestComputeAccel(code, false, maxSteps, x1, x2, t)
+                    fast, fastErr := doTestComputeAccel(code, true, maxSteps, x1, x2, t)
+                    if slowErr != fastErr || slow.steps != fast.steps || !reflect.DeepEqual(slow.vars, fast.vars) {
+                        t.Error("unexpected accelerated execution of '", code, "' with ", x1, ", ", x2, " and ", maxSteps, " steps:",
+                            "\n returned: ", fast.vars, " ", fast.steps, " ", fastErr, "\n expected: ", slow.vars, " ", slow.steps, " ", slowErr)
+                        return
+                    }
+                }
+            }
+        }
+    }
+}
+
+func TestAccelerateLargeLoops(t *testing.T) {
+    mult := "a := val(x1); c := 0; WHILE(a > 0) DO c = val(x2); WHILE(c > 0) DO x0 = inc(x0); c = dec(c) OD; a = dec(a) OD"
+    result, err := ComputeCode(mult, 0, 100000, 300000)
+    if err != nil || result != 30000000000 {
+        t.Error("unexpected returned value: ", result, " ", err)
+    }
+
+    result, err = ComputeCode("WHILE(x1 > 0) DO x0 = inc(x0) OD", 1000000000000, 1) //never terminates
+    if err != ErrUndefined {
+        t.Error("expected undefined, returned: ", result, " ", err)
+    }
+}
+
+func TestAccelerateNotCounting(t *testing.T) {
+    code := "PROC f(a) DO RETURN inc(a) OD; WHILE(x0 < x1) DO x0 = f(x0) OD"
+    slow, slowErr := doTestComputeAccel(code, false, 0, 3, 0, t)
+    fast, fastErr := doTestComputeAccel(code, true, 0, 3, 0, t)
+    if slowErr != nil || fastErr != nil || slow.steps != fast.steps || !reflect.DeepEqual(slow.vars, fast.vars) {
+        t.Error("unexpected execution: ", fast.vars, " ", slow.vars, " ", fastErr, " ", slowErr)
+    }
+}
+
+func TestCountIterations(t *testing.T) {
+    tests := []struct {
+        op string
+        g, dg int
+        n int
+        finite bool
+    }{
+        {"<", 5, -2, 3, true},
+        {"<", 5, 1, 0, false},
+        {"<", 0, -1, 0, true},
+        {"<=", 4, -2, 3, true},
+        {">", -6, 3, 2, true},
+        {">=", -6, 3, 3, true},
+        {"==", 0, 2, 1, true},
+        {"==", 0, 0, 0, false},
+        {"!=", 6, -2, 3, true},
+        {"!=", 5, -2, 0, false},
+        {"!=", -4, -1, 0, false},
+    }
+    for _, test := range tests {
+        if n, finite := countIterations(test.op, test.g, test.dg); n != test.n || finite != test.finite {
+            t.Error("unexpected iterations for ", test, ": ", n, " ", finite)
+        }
+    }
+}
+
+func doTestComputeAccel(code string, accelerate bool, maxSteps int, x1 int, x2 int, t *testing.T) (*program, error) {
+    p, _ := initFunction([]int{x1, x2})
+    p.accelerate = accelerate
+    p.maxSteps = maxSteps
+    if err := p.getStmts(code); err != nil {
+        t.Fatal(err)
+    }
+    if errs := p.check(code); len(errs) > 0 {
+        t.Fatal(errs)
+    }
+    return p, p.parseProgram()
+}
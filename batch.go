@@ -0,0 +1,128 @@
+package whileinterp
+
+import (
+	"errors"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// BatchResult is the result of the execution of a program with one environment (or a function with one input vector)
+type BatchResult struct {
+	Env map[string]int //initial values of the variables
+	Inputs []int //inputs saved on x1..xk (nil for a program)
+	Result int //value of x0 (0 if the execution failed or x0 isn't declared)
+	Vars []SnapshotVar //variables of the main program at the end, in their order of declaration
+	Steps int //number of statements executed
+	Err error //error of the execution (ErrUndefined if the steps were exhausted)
+}
+
+// Batch executes the program with every environment over a pool of workers (the number of CPUs if workers < 1),
+// limiting every execution to maxSteps statements (0 for no limit). An environment gives the initial values of
+// variables: the declarations of the main program of its variables take its values instead of their own, and
+// the rest are declared before the program (e.g. x0..xk of a function).
+// The results are returned in the same order as the environments
+// return []*BatchResult
+func (prog *Program) Batch(envs []map[string]int, workers int, maxSteps int) []*BatchResult {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(envs) {
+		workers = len(envs)
+	}
+
+	results := make([]*BatchResult, len(envs))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs { //every worker writes only the results of its environments
+				results[i] = prog.execEnv(envs[i], maxSteps)
+			}
+		}()
+	}
+
+	for i := range envs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// execEnv executes the program on a new program with the initial values of the variables of an environment
+// return *BatchResult
+func (prog *Program) execEnv(env map[string]int, maxSteps int) *BatchResult {
+	result := &BatchResult{Env: env}
+	p := prog.funcs.newProgram()
+	p.name = prog.name
+	p.code = prog.code
+	p.maxSteps = maxSteps
+	p.stmts = append([]stmt{}, prog.stmts...) //the statements are shared by the workers, only the copy is modified
+
+	declared := map[string]bool{}
+	for i, s := range p.stmts {
+		if s.op != declareOPSTRING {
+			continue
+		}
+		declared[s.name] = true
+		if val, ok := env[s.name]; ok {
+			if isArrayDecl(s) {
+				result.Err = errors.New("Batch: variable '" + s.name + "' of the environment is an array")
+				return result
+			}
+			p.stmts[i].value = &valueExpr{kind: numberValue, text: strconv.Itoa(val), offset: s.value.offset}
+		}
+	}
+
+	names := []string{}
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if env[name] < 0 {
+			result.Err = errors.New("Batch: value '" + strconv.Itoa(env[name]) + "' of variable '" + name + "' is not a natural number")
+			return result
+		}
+		if !declared[name] {
+			p.addVar(&variable{name: name, value: env[name]})
+		}
+	}
+
+	result.Err = p.parseProgram()
+	result.Steps = p.steps
+	result.Vars = frameVars(p)
+	if x0, err := p.getVar(resultVarSTRING); err == nil && result.Err == nil {
+		result.Result = x0.value
+	}
+	return result
+}
+
+// Batch computes the function with every input vector over a pool of workers (the number of CPUs if workers < 1),
+// limiting every computation to maxSteps statements (0 for no limit), as Program.Batch with x0..xk.
+// The results are returned in the same order as the inputs
+// return []*BatchResult
+func (f *Function) Batch(inputs [][]int, workers int, maxSteps int) []*BatchResult {
+	results := make([]*BatchResult, len(inputs))
+	envs, indices := []map[string]int{}, []int{}
+	for i, in := range inputs {
+		env, err := f.env(in)
+		if err != nil {
+			results[i] = &BatchResult{Inputs: in, Err: err}
+			continue
+		}
+		envs = append(envs, env)
+		indices = append(indices, i)
+	}
+
+	prog := &Program{name: f.name, code: f.code, stmts: f.stmts, funcs: f.funcs}
+	for j, result := range prog.Batch(envs, workers, maxSteps) {
+		result.Inputs = inputs[indices[j]]
+		results[indices[j]] = result
+	}
+	return results
+}
@@ -0,0 +1,123 @@
+package whileinterp
+
+import (
+    "reflect"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+const testFuncAdd = `c := val(x2)
+WHILE(c > 0) DO
+    x1 = inc(x1)
+    c = dec(c)
+OD
+x0 = val(x1)
+`
+
+/*********************** TESTING ***********************/
+func TestBatchInOrder(t *testing.T) {
+    f, err := ParseFunction(testFuncAdd, 2)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    inputs := [][]int{}
+    for a := 0; a < 20; a++ {
+        for b := 0; b < 20; b++ {
+            inputs = append(inputs, []int{a, b})
+        }
+    }
+    for _, workers := range []int{0, 1, 3, 1000} {
+        results := f.Batch(inputs, workers, 0)
+        if len(results) != len(inputs) {
+            t.Fatal("unexpected number of results: ", len(results))
+        }
+        for i, r := range results {
+            _, steps, _ := f.compute(0, inputs[i])
+            if r.Err != nil || r.Result != inputs[i][0] + inputs[i][1] || r.Steps != steps || !reflect.DeepEqual(r.Inputs, inputs[i]) {
+                t.Error("unexpected result for ", inputs[i], " with ", workers, " workers: ", r)
+            }
+        }
+    }
+}
+
+func TestBatchErrors(t *testing.T) {
+    f, err := ParseFunction(testFuncDiverge, 1)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    results := f.Batch([][]int{{0}, {1}, {-1}, {1, 2}}, 2, 100)
+    if r := results[0]; r.Err != nil || r.Result != 0 || r.Steps != 1 {
+        t.Error("unexpected result: ", r)
+    }
+    if r := results[1]; r.Err != ErrUndefined || r.Steps != 101 {
+        t.Error("unexpected result: ", r)
+    }
+    if r := results[2]; r.Err == nil || r.Err.Error() != "initFunction: input '-1' is not a natural number" {
+        t.Error("unexpected result: ", r)
+    }
+    if r := results[3]; r.Err == nil || r.Err.Error() != "Compute: function expects 1 input(s), 2 given" {
+        t.Error("unexpected result: ", r)
+    }
+}
+
+func TestBatchEmpty(t *testing.T) {
+    f, _ := ParseFunction(testFuncIdentity, 1)
+    if results := f.Batch(nil, 4, 0); len(results) != 0 {
+        t.Error("unexpected results: ", results)
+    }
+}
+
+func TestBatchProgram(t *testing.T) {
+    code := "n := 3; r := 0; a := array(2); WHILE(n > 0) DO r = inc(r); n = dec(n) OD; a[1] = val(r)"
+    prog, err := ParseCode(code)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    formatted := formatStmts(prog.stmts)
+    envs := []map[string]int{}
+    for n := 0; n < 50; n++ {
+        envs = append(envs, map[string]int{"n": n, "y": 7})
+    }
+    for _, workers := range []int{0, 1, 3, 1000} {
+        results := prog.Batch(envs, workers, 0)
+        if len(results) != len(envs) {
+            t.Fatal("unexpected number of results: ", len(results))
+        }
+        for n, r := range results {
+            expected, _ := ParseCode(strings.Replace(code, "n := 3", "n := " + strconv.Itoa(n), 1))
+            e := expected.NewExecution(0)
+            e.Run()
+            expecVars := []SnapshotVar{{Name: "y", Value: 7}, {Name: "n"}, {Name: "r", Value: n}, {Name: "a", Array: []int{0, n}}}
+            if r.Err != nil || r.Steps != e.Steps() || !reflect.DeepEqual(r.Vars, expecVars) || !reflect.DeepEqual(r.Env, envs[n]) {
+                t.Error("unexpected result for ", envs[n], " with ", workers, " workers: ", r.Vars, " ", r.Steps, " ", r.Err)
+            }
+        }
+    }
+    if returned := formatStmts(prog.stmts); returned != formatted {
+        t.Error("expected the program not modified by the environments: ", returned)
+    }
+}
+
+func TestBatchProgramErrors(t *testing.T) {
+    prog, _ := ParseCode("a := array(1); x := 0; WHILE(x == 0) DO a[0] = inc(a[0]) OD")
+    results := prog.Batch([]map[string]int{{"x": 1}, {"x": 0}, {"a": 2}, {"x": -1}, nil}, 2, 10)
+    if r := results[0]; r.Err != nil || r.Steps != 3 {
+        t.Error("unexpected result: ", r)
+    }
+    if r := results[1]; r.Err != ErrUndefined || r.Steps != 11 {
+        t.Error("unexpected result: ", r)
+    }
+    if r := results[2]; r.Err == nil || r.Err.Error() != "Batch: variable 'a' of the environment is an array" {
+        t.Error("unexpected result: ", r)
+    }
+    if r := results[3]; r.Err == nil || r.Err.Error() != "Batch: value '-1' of variable 'x' is not a natural number" {
+        t.Error("unexpected result: ", r)
+    }
+    if r := results[4]; r.Err != ErrUndefined || r.Env != nil {
+        t.Error("unexpected result: ", r)
+    }
+}
@@ -0,0 +1,175 @@
+package whileinterp
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+/*
+    Structured data on naturals with the Cantor pairing function <a, b> (the same one of the Gödel numbering),
+    registered on an interpreter with RegisterCantor:
+
+        pair(a, b) -> <a, b>             fst(<a, b>) -> a             snd(<a, b>) -> b
+        nil()      -> 0                  cons(h, t)  -> <h, t> + 1
+        head(nil)  -> 0                  head(cons(h, t)) -> h
+        tail(nil)  -> 0                  tail(cons(h, t)) -> t
+
+    The values are computed on big naturals, and an error stops the execution if a result doesn't fit an int.
+    FormatCantor decodes a value with a shape: "N" (a natural), "<S,T>" (a pair of the shapes S and T) and
+    "[S]" (a list of the shape S), e.g. "[<N,N>]" decodes a list of pairs as "[<1, 2>, <3, 4>]".
+*/
+
+// cantorFuncs defines the functions registered by RegisterCantor
+var cantorFuncs = []struct {
+	name string
+	arity int
+	fn func(args []*big.Int) *big.Int
+}{
+	{"pair", 2, func(args []*big.Int) *big.Int { return pair(args[0], args[1]) }},
+	{"fst", 1, func(args []*big.Int) *big.Int { a, _ := unpair(args[0]); return a }},
+	{"snd", 1, func(args []*big.Int) *big.Int { _, b := unpair(args[0]); return b }},
+	{"nil", 0, func(args []*big.Int) *big.Int { return new(big.Int) }},
+	{"cons", 2, func(args []*big.Int) *big.Int { return new(big.Int).Add(pair(args[0], args[1]), big.NewInt(1)) }},
+	{"head", 1, func(args []*big.Int) *big.Int { h, _ := uncons(args[0]); return h }},
+	{"tail", 1, func(args []*big.Int) *big.Int { _, t := uncons(args[0]); return t }},
+}
+
+// RegisterCantor registers the functions pair, fst, snd, nil, cons, head and tail on the interpreter
+// (an argument below zero, e.g. dec(0), stops the execution with an error)
+// return error (if any of them is already registered)
+func (in *Interpreter) RegisterCantor() error {
+	for _, cf := range cantorFuncs {
+		name, fn := cf.name, cf.fn
+		err := in.RegisterFunc(name, cf.arity, func(args []int) (int, error) {
+			bigArgs := make([]*big.Int, len(args))
+			for i, arg := range args {
+				if arg < 0 {
+					return 0, errors.New("argument '" + strconv.Itoa(arg) + "' is not a natural")
+				}
+				bigArgs[i] = big.NewInt(int64(arg))
+			}
+			result := fn(bigArgs)
+			if !result.IsInt64() || int64(int(result.Int64())) != result.Int64() {
+				return 0, errors.New("result '" + result.String() + "' is too big")
+			}
+			return int(result.Int64()), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uncons returns the head and the tail of a list (nil for the empty list)
+// return *big.Int, *big.Int
+func uncons(l *big.Int) (*big.Int, *big.Int) {
+	if l.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	return unpair(new(big.Int).Sub(l, big.NewInt(1)))
+}
+
+// cantorShape is the shape of a value decoded by FormatCantor
+type cantorShape struct {
+	kind byte //'N' (a natural), '<' (a pair) or '[' (a list)
+	items []*cantorShape //shapes of the components of a pair, or of the items of a list
+}
+
+// cantorShapeParser parses a shape, ignoring the spaces
+type cantorShapeParser struct {
+	shape string //shape to parse
+	pos int //position of the next character
+}
+
+// next returns the next character that is not a space (0 at the end of the shape), and consumes it
+// return byte
+func (sp *cantorShapeParser) next() byte {
+	for sp.pos < len(sp.shape) && sp.shape[sp.pos] == ' ' {
+		sp.pos++
+	}
+	if sp.pos == len(sp.shape) {
+		return 0
+	}
+	sp.pos++
+	return sp.shape[sp.pos - 1]
+}
+
+// expect consumes the next character, which must be c
+// return error
+func (sp *cantorShapeParser) expect(c byte) error {
+	if sp.next() != c {
+		return errors.New("FormatCantor: '" + string(c) + "' expected at " + strconv.Itoa(sp.pos) + " of the shape '" + sp.shape + "'")
+	}
+	return nil
+}
+
+// parse parses a shape: "N", "<S,T>" or "[S]"
+// return *cantorShape, error
+func (sp *cantorShapeParser) parse() (*cantorShape, error) {
+	switch c := sp.next(); c {
+		case 'N':
+			return &cantorShape{kind: c}, nil
+		case '<':
+			first, err := sp.parse()
+			if err != nil {
+				return nil, err
+			}
+			if err := sp.expect(','); err != nil {
+				return nil, err
+			}
+			second, err := sp.parse()
+			if err != nil {
+				return nil, err
+			}
+			return &cantorShape{kind: c, items: []*cantorShape{first, second}}, sp.expect('>')
+		case '[':
+			item, err := sp.parse()
+			if err != nil {
+				return nil, err
+			}
+			return &cantorShape{kind: c, items: []*cantorShape{item}}, sp.expect(']')
+		default:
+			return nil, errors.New("FormatCantor: 'N', '<' or '[' expected at " + strconv.Itoa(sp.pos) + " of the shape '" + sp.shape + "'")
+	}
+}
+
+// format returns a value decoded with the shape
+// return string
+func (cs *cantorShape) format(n *big.Int) string {
+	switch cs.kind {
+		case '<':
+			a, b := unpair(n)
+			return "<" + cs.items[0].format(a) + ", " + cs.items[1].format(b) + ">"
+		case '[':
+			items := []string{}
+			for n.Sign() > 0 { //every tail is lower than its list
+				var h *big.Int
+				h, n = uncons(n)
+				items = append(items, cs.items[0].format(h))
+			}
+			return "[" + strings.Join(items, ", ") + "]"
+		default:
+			return n.String()
+	}
+}
+
+// FormatCantor decodes a natural with the Cantor pairing as the given shape: "N" (a natural), "<S,T>" (a pair)
+// or "[S]" (a list), e.g. FormatCantor(cons(1, cons(2, nil())), "[N]") is "[1, 2]"
+// return string, error
+func FormatCantor(n int, shape string) (string, error) {
+	if n < 0 {
+		return "", errors.New("FormatCantor: value '" + strconv.Itoa(n) + "' is not a natural number")
+	}
+	sp := &cantorShapeParser{shape: shape}
+	cs, err := sp.parse()
+	if err != nil {
+		return "", err
+	}
+	if sp.next() != 0 {
+		return "", errors.New("FormatCantor: end of the shape '" + shape + "' expected at " + strconv.Itoa(sp.pos))
+	}
+	return cs.format(big.NewInt(int64(n))), nil
+}
@@ -0,0 +1,121 @@
+package whileinterp
+
+import (
+    "testing"
+)
+
+// doTestCantorExec executes a code with the Cantor functions and returns the value of a variable
+// return int
+func doTestCantorExec(code string, name string, t *testing.T) int {
+    in := NewInterpreter()
+    if err := in.RegisterCantor(); err != nil {
+        t.Fatal(err)
+    }
+    prog, err := in.ParseCode(code)
+    if err != nil {
+        t.Fatal(err)
+    }
+    e := prog.NewExecution(0)
+    if err := e.Run(); err != nil {
+        t.Fatal(err)
+    }
+    value, err := e.Var(name)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return value
+}
+
+/*********************** TESTING ***********************/
+func TestCantorPair(t *testing.T) {
+    tests := map[string]int{
+        "p := pair(3, 4); r := fst(p)": 3,
+        "p := pair(3, 4); r := snd(p)": 4,
+        "r := pair(1, 2)": 8,
+        "r := fst(snd(pair(1, pair(5, 6))))": 5,
+    }
+    for code, expected := range tests {
+        if returned := doTestCantorExec(code, "r", t); returned != expected {
+            t.Error("unexpected value of '", code, "'\n returned: ", returned, "\n expected: ", expected)
+        }
+    }
+}
+
+func TestCantorList(t *testing.T) {
+    code := "l := nil(); i := 3; WHILE(i > 0) DO l = cons(i, l); i = dec(i) OD; s := 0; r := val(l); " +
+        "WHILE(r != nil()) DO s = inc(s); h := head(r); r = tail(r) OD; e := head(nil()); f := tail(nil())"
+    for name, expected := range map[string]int{"s": 3, "r": 0, "e": 0, "f": 0} {
+        if returned := doTestCantorExec(code, name, t); returned != expected {
+            t.Error("unexpected value of '", name, "'\n returned: ", returned, "\n expected: ", expected)
+        }
+    }
+
+    l := doTestCantorExec(code, "l", t)
+    if formatted, err := FormatCantor(l, "[N]"); err != nil || formatted != "[1, 2, 3]" {
+        t.Error("unexpected list: ", formatted, " ", err)
+    }
+}
+
+func TestCantorTooBig(t *testing.T) {
+    in := NewInterpreter()
+    in.RegisterCantor()
+    prog, _ := in.ParseCode("p := pair(4000000000, 4000000000)")
+    err := prog.NewExecution(0).Run()
+    if err == nil || err.Error() != "execFunc: function 'pair' failed: result '32000000008000000000' is too big" {
+        t.Error("expected an error with a too big result, returned: ", err)
+    }
+
+    if err := in.RegisterCantor(); err == nil {
+        t.Error("expected an error registering the functions twice")
+    }
+}
+
+func TestCantorNegative(t *testing.T) {
+    in := NewInterpreter()
+    in.RegisterCantor()
+    for _, name := range []string{"fst", "snd", "head", "tail"} {
+        prog, err := in.ParseCode("x := dec(0); y := " + name + "(x)")
+        if err != nil {
+            t.Fatal(err)
+        }
+        expected := "execFunc: function '" + name + "' failed: argument '-1' is not a natural"
+        if err := prog.NewExecution(0).Run(); err == nil || err.Error() != expected {
+            t.Error("expected an error with a negative argument of '", name, "', returned: ", err)
+        }
+    }
+}
+
+func TestFormatCantor(t *testing.T) {
+    in := NewInterpreter()
+    in.RegisterCantor()
+    prog, _ := in.ParseCode("a := cons(pair(1, 2), cons(pair(3, 4), nil())); b := pair(cons(5, nil()), 6); c := pair(0, 0)")
+    e := prog.NewExecution(0)
+    e.Run()
+
+    tests := []struct {
+        name string
+        shape string
+        expected string
+    }{
+        {"a", "[<N,N>]", "[<1, 2>, <3, 4>]"},
+        {"a", "[N]", "[8, 32]"},
+        {"b", "< [N] , N >", "<[5], 6>"},
+        {"c", "<N,[N]>", "<0, []>"},
+        {"c", "N", "0"},
+    }
+    for _, test := range tests {
+        value, _ := e.Var(test.name)
+        if returned, err := FormatCantor(value, test.shape); err != nil || returned != test.expected {
+            t.Error("unexpected format of '", test.name, "' as '", test.shape, "'\n returned: ", returned, " ", err, "\n expected: ", test.expected)
+        }
+    }
+
+    for _, shape := range []string{"", "<N>", "[N", "N N", "X"} {
+        if _, err := FormatCantor(1, shape); err == nil {
+            t.Error("expected an error with the shape '", shape, "'")
+        }
+    }
+    if _, err := FormatCantor(-1, "N"); err == nil {
+        t.Error("expected an error with a negative value")
+    }
+}
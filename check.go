@@ -0,0 +1,306 @@
+package whileinterp
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CheckError is an error found by the static checker on a position of the code
+type CheckError struct {
+	Pos Position //position of the error on the code
+	Msg string //description of the error
+}
+
+// Error returns the error with the format "line:column: message"
+// return string
+func (e *CheckError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// CheckErrors lists every error found by the static checker
+type CheckErrors []*CheckError
+
+// Error returns every error found, one per line
+// return string
+func (errs CheckErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, e := range errs {
+		lines[i] = e.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checker checks the statements of a program before they are executed
+type checker struct {
+	name string //name of the source of the code, shown on the positions
+	code string //source code of the program (used to compute the positions)
+	declared map[string]bool //variables visible on the current statement (declared on the block or an enclosing one)
+	local map[string]bool //variables declared on the current block
+	expired map[string]bool //variables declared on a block already closed (not visible anymore)
+	arrays map[string]bool //variables visible on the current statement that are arrays
+	procs map[string]int //number of parameters of every function defined on the program
+	imported map[string]string //library that defines every imported function
+	funcs *Interpreter //interpreter whose registered functions can be called (nil for only the predefined ones)
+	errs CheckErrors //errors found until the current statement
+}
+
+// addError saves an error found on an offset of the source code
+func (c *checker) addError(offset int, msg string) {
+	c.errs = append(c.errs, &CheckError{Pos: newPosition(c.name, c.code, offset), Msg: msg})
+}
+
+// checkStmts checks a list of statements
+func (c *checker) checkStmts(stmts []stmt) {
+	for _, s := range stmts {
+		switch s.op {
+		case whileFuncSTRING:
+			c.checkLogicExpr(s.cond)
+			c.checkBlock(s.body)
+		case procSTRING:
+			c.checkProc(s)
+		case declareOPSTRING:
+			if isArrayDecl(s) { //the value is checked before the variable is declared
+				c.checkArray(s.value)
+			} else {
+				c.checkValue(s.value)
+			}
+
+			if c.local[s.name] { //a variable of an enclosing block is shadowed until the end of the block
+				c.addError(s.offset, "variable '" + s.name + "' already declared")
+			}
+			c.declared[s.name] = true
+			c.local[s.name] = true
+			c.arrays[s.name] = isArrayDecl(s)
+		case assignOPSTRING:
+			switch {
+			case c.expired[s.name] && !c.declared[s.name]:
+				c.addError(s.offset, "assignment to variable '" + s.name + "' outside the block of its declaration")
+			case !c.declared[s.name]:
+				c.addError(s.offset, "assignment to undeclared variable '" + s.name + "'")
+			case s.index != nil && !c.arrays[s.name]:
+				c.addError(s.offset, "variable '" + s.name + "' is not an array")
+			case s.index == nil && c.arrays[s.name]:
+				c.addError(s.offset, "assignment to array '" + s.name + "' without an index")
+			}
+			if s.index != nil {
+				c.checkValue(s.index)
+			}
+			c.checkValue(s.value)
+		case printSTRING:
+			c.checkValue(s.value)
+		case readSTRING:
+			switch {
+			case c.expired[s.name] && !c.declared[s.name]:
+				c.addError(s.nameOffset, "variable '" + s.name + "' read outside the block of its declaration")
+			case !c.declared[s.name]:
+				c.addError(s.nameOffset, "variable '" + s.name + "' read before its declaration")
+			case c.arrays[s.name]:
+				c.addError(s.nameOffset, "array '" + s.name + "' can't be read")
+			}
+		}
+	}
+}
+
+// checkBlock checks the body of a WHILE, whose declarations are local to the body
+// (they are visible until the end of the body and declared again on every iteration)
+func (c *checker) checkBlock(stmts []stmt) {
+	declared, local, arrays := c.declared, c.local, c.arrays
+	c.declared, c.local, c.arrays = map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for name := range declared {
+		c.declared[name] = true
+		c.arrays[name] = arrays[name]
+	}
+
+	c.checkStmts(stmts)
+	for name := range c.local {
+		c.expired[name] = true
+	}
+	c.declared, c.local, c.arrays = declared, local, arrays
+}
+
+// checkArray checks the declaration of an array: array(n)
+func (c *checker) checkArray(v *valueExpr) {
+	if len(v.params) != 1 {
+		c.addError(v.offset, "function '" + arraySTRING + "' expects 1 parameter(s), found " + strconv.Itoa(len(v.params)))
+	}
+	for _, param := range v.params {
+		c.checkValue(param)
+	}
+}
+
+// checkImports saves the number of parameters of the functions imported by the program, checking that they
+// are not registered on the interpreter too
+func (c *checker) checkImports(libs []*library, offsets []int) {
+	names := map[string]string{} //name of the library imported on every namespace
+	for i, lib := range libs {
+		if name, ok := names[lib.ns]; ok {
+			if !samePath(name, lib.name) {
+				c.addError(offsets[i], "library '" + lib.ns + "' already imported from '" + name + "'")
+			}
+			continue
+		}
+		names[lib.ns] = lib.name
+
+		for _, s := range lib.stmts {
+			if s.op != procSTRING {
+				continue
+			}
+			name := lib.qualify(s.name)
+			if _, ok := c.funcs.registered(name); ok {
+				c.addError(offsets[i], "function '" + name + "' of library '" + lib.name + "' already defined as a registered function")
+				continue
+			}
+			c.procs[name] = len(s.params)
+			c.imported[name] = lib.name
+		}
+	}
+}
+
+// checkProcs saves the number of parameters of the functions defined on a list of statements,
+// checking that their names are not repeated
+func (c *checker) checkProcs(stmts []stmt) {
+	for _, s := range stmts {
+		if s.op != procSTRING {
+			continue
+		}
+		if lib, ok := c.imported[s.name]; ok {
+			c.addError(s.offset, "function '" + s.name + "' already defined by library '" + lib + "'")
+			continue
+		}
+		if _, ok := c.procs[s.name]; ok {
+			c.addError(s.offset, "function '" + s.name + "' already defined")
+			continue
+		}
+		if funcParams(s.name) != -1 || s.name == arraySTRING {
+			c.addError(s.offset, "function '" + s.name + "' already defined as a predefined function")
+			continue
+		}
+		if _, ok := c.funcs.registered(s.name); ok {
+			c.addError(s.offset, "function '" + s.name + "' already defined as a registered function")
+			continue
+		}
+		c.procs[s.name] = len(s.params)
+	}
+}
+
+// checkProc checks the body and the returned value of a function, which only know its parameters
+func (c *checker) checkProc(s stmt) {
+	declared, local, expired, arrays := c.declared, c.local, c.expired, c.arrays
+	c.declared, c.local, c.expired, c.arrays = map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}
+	for _, name := range s.params {
+		if c.declared[name] {
+			c.addError(s.offset, "parameter '" + name + "' of function '" + s.name + "' already declared")
+		}
+		c.declared[name] = true
+		c.local[name] = true
+	}
+
+	c.checkStmts(s.body)
+	c.checkValue(s.value)
+	c.declared, c.local, c.expired, c.arrays = declared, local, expired, arrays
+}
+
+// checkLogicExpr checks the values compared on a logic expression
+func (c *checker) checkLogicExpr(l *logicExpr) {
+	if l.left != nil { //AND, OR, NOT
+		c.checkLogicExpr(l.left)
+		if l.right != nil {
+			c.checkLogicExpr(l.right)
+		}
+		return
+	}
+
+	c.checkValue(l.first)
+	c.checkValue(l.second)
+}
+
+// checkValue checks a number, a variable, an element of an array or a function call (and its parameters)
+func (c *checker) checkValue(v *valueExpr) {
+	switch v.kind {
+	case numberValue:
+		if _, err := strconv.Atoi(v.text); err != nil { //the tokenizer only accepts naturals
+			c.addError(v.offset, "number '" + v.text + "' is too big")
+		}
+	case varValue:
+		if c.expired[v.text] && !c.declared[v.text] {
+			c.addError(v.offset, "variable '" + v.text + "' used outside the block of its declaration")
+		} else if !c.declared[v.text] {
+			c.addError(v.offset, "variable '" + v.text + "' used before its declaration")
+		} else if c.arrays[v.text] {
+			c.addError(v.offset, "array '" + v.text + "' used without an index")
+		}
+	case indexValue:
+		if c.expired[v.text] && !c.declared[v.text] {
+			c.addError(v.offset, "variable '" + v.text + "' used outside the block of its declaration")
+		} else if !c.declared[v.text] {
+			c.addError(v.offset, "variable '" + v.text + "' used before its declaration")
+		} else if !c.arrays[v.text] {
+			c.addError(v.offset, "variable '" + v.text + "' is not an array")
+		}
+		c.checkValue(v.params[0])
+	case callValue:
+		if v.text == arraySTRING {
+			c.addError(v.offset, "function '" + arraySTRING + "' can only be the value of a declaration")
+			for _, param := range v.params {
+				c.checkValue(param)
+			}
+			return
+		}
+		params, ok := c.procs[v.text]
+		if !ok {
+			params = c.funcs.funcParams(v.text)
+		}
+		if params == -1 {
+			c.addError(v.offset, "function '" + v.text + "' not defined")
+		} else if params != len(v.params) {
+			c.addError(v.offset, "function '" + v.text + "' expects " + strconv.Itoa(params) + " parameter(s), found " + strconv.Itoa(len(v.params)))
+		}
+
+		for _, param := range v.params {
+			c.checkValue(param)
+		}
+	}
+}
+
+// funcParams returns the number of parameters of one of the predefined functions
+// return int (-1 if the function is not defined)
+func funcParams(name string) int {
+	if f, ok := predefinedFuncs[name]; ok {
+		return f.arity
+	}
+	return -1
+}
+
+// funcIndex returns the index of one of the predefined functions
+// return int (-1 if the function is not predefined)
+func funcIndex(name string) int {
+	for i, f := range possFunc {
+		if f == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// check checks statically the statements of a program, whose source code is given, without executing them
+// the variables already present on the program are treated as declared
+// return CheckErrors
+func (p *program) check(code string) CheckErrors {
+	c := &checker{name: p.name, code: code, declared: map[string]bool{}, local: map[string]bool{}, expired: map[string]bool{}, arrays: map[string]bool{}, procs: map[string]int{}, imported: map[string]string{}, funcs: p.funcs}
+	for _, v := range p.vars {
+		c.declared[v.name] = true
+		c.local[v.name] = true
+		c.arrays[v.name] = v.array != nil
+	}
+
+	c.checkImports(p.libraries())
+	c.checkProcs(p.stmts) //the functions can be called before their definition
+	c.checkStmts(p.stmts)
+	return c.errs
+}
+
+// CheckCode checks statically the code without executing it and returns every error found
+// return CheckErrors
+func CheckCode(code string) CheckErrors {
+	return defaultInterpreter.CheckCode(code)
+}
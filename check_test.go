@@ -0,0 +1,156 @@
+package whileinterp
+
+import "testing"
+
+/*********************** TESTING ***********************/
+func TestCheckCodeValid(t *testing.T) {
+    if errs := CheckCode(testCode1); len(errs) != 0 {
+        t.Error(errs)
+    }
+    if errs := CheckCode(testCode2); len(errs) != 0 {
+        t.Error(errs)
+    }
+}
+
+func TestCheckCodeUseBeforeDeclare(t *testing.T) {
+    code := "xo := inc(x1); x1 := 2; WHILE(xo != x2) DO xo = inc(xo) OD;"
+    expecErrs := []string{
+        "1:11: variable 'x1' used before its declaration",
+        "1:37: variable 'x2' used before its declaration",
+    }
+
+    doTestCheckCode(code, expecErrs, t)
+}
+
+func TestCheckCodeRedeclare(t *testing.T) {
+    code := "xo := 2; xo := 3; x1 := 4; WHILE(xo < x1) DO x2 := inc(xo); x2 := 0; xo := 1 OD;"
+    expecErrs := []string{
+        "1:10: variable 'xo' already declared",
+        "1:61: variable 'x2' already declared",
+    }
+
+    doTestCheckCode(code, expecErrs, t)
+}
+
+func TestCheckCodeBlockScope(t *testing.T) {
+    code := "xo := 2; WHILE(xo > 0) DO x1 := dec(xo); xo = val(x1) OD; x2 := val(x1); x1 = 3; WHILE(x1 > 0) DO x1 := 0 OD;"
+    expecErrs := []string{
+        "1:69: variable 'x1' used outside the block of its declaration",
+        "1:74: assignment to variable 'x1' outside the block of its declaration",
+        "1:88: variable 'x1' used outside the block of its declaration",
+    }
+
+    doTestCheckCode(code, expecErrs, t)
+}
+
+func TestCheckCodeAssignUndeclared(t *testing.T) {
+    code := "xo := 2; x1 = inc(xo); WHILE(xo > x1) DO x1 = dec(x1) OD;"
+    expecErrs := []string{
+        "1:10: assignment to undeclared variable 'x1'",
+        "1:35: variable 'x1' used before its declaration",
+        "1:42: assignment to undeclared variable 'x1'",
+        "1:51: variable 'x1' used before its declaration",
+    }
+
+    doTestCheckCode(code, expecErrs, t)
+}
+
+func TestCheckCodeFunc(t *testing.T) {
+    code := "xo := foo(2); x1 := inc();"
+    expecErrs := []string{
+        "1:7: function 'foo' not defined",
+        "1:21: function 'inc' expects 1 parameter(s), found 0",
+    }
+
+    doTestCheckCode(code, expecErrs, t)
+}
+
+func TestCheckCodeNatural(t *testing.T) {
+    codes := map[string][]string{
+        "xo := -2; x1 := inc(-1);": {"1:7: number '-2' is not a natural number"},
+        "xo := 2; x1 := inc(-10);": {"1:20: number '-10' is not a natural number"},
+        "xo := 2; WHILE(xo > -1) DO xo = dec(xo) OD": {"1:21: number '-1' is not a natural number"},
+    }
+    for code, expecErrs := range codes {
+        doTestCheckCode(code, expecErrs, t)
+    }
+
+    if _, err := ParseCode("xo := -2"); err == nil || err.Error() != "1:7: number '-2' is not a natural number" {
+        t.Error("expected a parse error with a negative number, returned: ", err)
+    }
+}
+
+func TestCheckCodeLogicExpr(t *testing.T) {
+    code := "xo := 2; WHILE(xo <= x1 OR NOT (x2 >= xo)) DO xo = inc(xo) OD;"
+    expecErrs := []string{
+        "1:22: variable 'x1' used before its declaration",
+        "1:33: variable 'x2' used before its declaration",
+    }
+
+    doTestCheckCode(code, expecErrs, t)
+}
+
+func TestCheckCodeSyntaxError(t *testing.T) {
+    code := "xo := 2; WHILE(xo < ) DO xo = inc(xo) OD;"
+    expecErrs := []string{
+        "1:21: number, variable or function call expected, found ')'",
+    }
+
+    doTestCheckCode(code, expecErrs, t)
+}
+
+func TestCheckCodeProc(t *testing.T) {
+    code := "PROC f(a, a) DO b := inc(xo) RETURN c OD; xo := f(1); PROC f(a) DO RETURN a OD; PROC inc(a) DO RETURN a OD;"
+    expecErrs := []string{
+        "1:55: function 'f' already defined",
+        "1:81: function 'inc' already defined as a predefined function",
+        "1:1: parameter 'a' of function 'f' already declared",
+        "1:26: variable 'xo' used before its declaration",
+        "1:37: variable 'c' used before its declaration",
+        "1:49: function 'f' expects 2 parameter(s), found 1",
+    }
+
+    doTestCheckCode(code, expecErrs, t)
+}
+
+func TestCheckCodeProcInLoop(t *testing.T) {
+    code := "xo := 2; WHILE(xo > 0) DO PROC f(a) DO RETURN a OD OD;"
+    expecErrs := []string{
+        "1:27: functions can only be defined outside of any block",
+    }
+
+    doTestCheckCode(code, expecErrs, t)
+}
+
+func TestCheckCodeArray(t *testing.T) {
+    code := "a := array(2, 3); x := 1; x[0] = 2; b := array(2); b = 1; y := val(b); z := inc(array(1)); b[0] = val(b[x]);"
+    expecErrs := []string{
+        "1:6: function 'array' expects 1 parameter(s), found 2",
+        "1:27: variable 'x' is not an array",
+        "1:52: assignment to array 'b' without an index",
+        "1:68: array 'b' used without an index",
+        "1:81: function 'array' can only be the value of a declaration",
+    }
+
+    doTestCheckCode(code, expecErrs, t)
+}
+
+func TestExecCodeCheck(t *testing.T) {
+    err := ExecCode("xo := 2; WHILE(xo != x1) DO xo = inc(xo) OD;", false)
+    if _, ok := err.(CheckErrors); !ok {
+        t.Error("unexpected returned error:\n returned: ", err, "\n expected: CheckErrors")
+    }
+}
+
+func doTestCheckCode(code string, expecErrs []string, t *testing.T) {
+    errs := CheckCode(code)
+    if len(errs) != len(expecErrs) {
+        t.Error("unexpected returned errors:\n returned: ", errs, "\n expected: ", expecErrs)
+        return
+    }
+    for i, err := range errs {
+        if err.Error() != expecErrs[i] {
+            t.Error("unexpected returned error:\n returned: ", err, "\n expected: ", expecErrs[i])
+        }
+    }
+}
@@ -0,0 +1,791 @@
+/*
+    whileinterp is the command line tool of the while interpreter.
+
+    Usage:
+        whileinterp run [-log] [-noaccel] [-opt pass,...] [-snapshot file [-pause n]] [-show x=shape] [-input file] file
+        whileinterp resume [-log] [-noaccel] [-snapshot file] [-pause n] [-show x=shape] [-input file] snapshot
+        whileinterp check file
+        whileinterp lint [-disable rule,...] file
+        whileinterp optimize [-passes pass,...] file
+        whileinterp ranges file
+        whileinterp termination file
+        whileinterp compute [-steps n] [-noaccel] [-shape shape] [-input file] file n1 ... nk
+        whileinterp batch [-steps n] [-workers n] [-noaccel] file < inputs
+        whileinterp replay [-steps n] [-interval n] [-input file] file [n1 ... nk]
+        whileinterp lsp [-cantor]
+        whileinterp dap [-cantor]
+
+    The code is read from the standard input if the file is "-". Every command reading a code accepts -cantor,
+    which registers the functions pair, fst, snd, nil, cons, head and tail. The values of PRINT are written on
+    the standard output, and READ reads the naturals of the standard input (or of the file given with -input;
+    batch and replay read the standard input for themselves, so their READs only read the -input file).
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/aleics/whileinterp"
+)
+
+// usageSTRING defines the help message of the command line tool
+const usageSTRING = `usage:
+    whileinterp run [-log] [-noaccel] [-opt pass,...] [-snapshot file [-pause n]] [-show x=shape] [-input file] file
+                                                    executes the code (optimized with the given passes), saving
+                                                    its state on the snapshot if interrupted or paused after n steps,
+                                                    and shows the variables decoded as tuples or lists
+    whileinterp resume [-log] [-noaccel] [-snapshot file] [-pause n] [-show x=shape] [-input file] snapshot
+                                                    continues the execution saved on a snapshot
+    whileinterp check file                          checks the code without executing it
+    whileinterp lint [-disable rule,...] file       looks for suspicious statements on the code
+    whileinterp optimize [-passes pass,...] file    shows the code optimized with the given passes (all by default)
+    whileinterp ranges file                         shows the possible range of every variable before every statement
+    whileinterp termination file                    shows whether every loop terminates, may not terminate or is unknown
+    whileinterp compute [-steps n] [-noaccel] [-shape shape] [-input file] file n1 ... nk
+                                                    computes x0 with the inputs saved on x1..xk (decoded with the shape)
+    whileinterp batch [-steps n] [-workers n] [-noaccel] file < inputs
+                                                    computes x0 with every line of inputs "n1 ... nk" in parallel
+    whileinterp replay [-steps n] [-interval n] [-input file] file [n1 ... nk]
+                                                    records the execution and travels over it with the commands
+                                                    of the standard input (back, next, goto, last, start, end)
+    whileinterp lsp [-cantor]                       runs a language server over the standard input and output
+    whileinterp dap [-cantor]                       runs a debug adapter over the standard input and output
+
+    -cantor registers the functions pair, fst, snd, nil, cons, head and tail on any command reading a code, and the
+    shapes decode their values: N (a natural), <S,T> (a pair) and [S] (a list), e.g. -show l=[<N,N>]
+    -input file gives the naturals read by READ (the standard input by default, nothing for batch and replay)
+`
+
+// cantorUsageSTRING defines the description of the -cantor flag
+const cantorUsageSTRING = "register the functions pair, fst, snd, nil, cons, head and tail (Cantor pairing)"
+
+// inputUsageSTRING defines the description of the -input flag
+const inputUsageSTRING = "file with the naturals read by the READ statements, separated by spaces or new lines"
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usageSTRING)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+		case "run":
+			os.Exit(run(os.Args[2:]))
+		case "resume":
+			os.Exit(resume(os.Args[2:]))
+		case "check":
+			os.Exit(check(os.Args[2:]))
+		case "lint":
+			os.Exit(lint(os.Args[2:]))
+		case "optimize":
+			os.Exit(optimize(os.Args[2:]))
+		case "ranges":
+			os.Exit(ranges(os.Args[2:]))
+		case "termination":
+			os.Exit(termination(os.Args[2:]))
+		case "compute":
+			os.Exit(compute(os.Args[2:]))
+		case "batch":
+			os.Exit(batch(os.Args[2:]))
+		case "replay":
+			os.Exit(replay(os.Args[2:]))
+		case "lsp":
+			os.Exit(lsp(os.Args[2:]))
+		case "dap":
+			os.Exit(dap(os.Args[2:]))
+		default:
+			fmt.Fprint(os.Stderr, usageSTRING)
+			os.Exit(2)
+	}
+}
+
+// run executes the code of a file
+// return int (exit code)
+func run(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	log := flags.Bool("log", false, "display the progress of the execution")
+	noAccel := flags.Bool("noaccel", false, "execute every iteration of the counting loops one by one")
+	opt := flags.String("opt", "", "comma separated list of the optimization passes applied before the execution")
+	snapshot := flags.String("snapshot", "", "file where the state is saved if the execution is interrupted (Ctrl-C) or paused")
+	pause := flags.Int("pause", 0, "number of steps after which the execution is paused (with -snapshot)")
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	shows := showFlag{}
+	flags.Var(&shows, "show", "variable shown decoded with a shape, as name=shape (e.g. l=[N]), can be repeated")
+	input := flags.String("input", "", inputUsageSTRING)
+	flags.Parse(args)
+
+	in := newInterpreter(*cantor)
+	in.SetAcceleration(!*noAccel)
+	if err := setInput(in, *input, true); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	prog, err := parseFile(in, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *opt != "" {
+		if prog, err = prog.Optimize(parsePasses(*opt)...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	if *snapshot != "" || len(shows) > 0 {
+		if *log {
+			fmt.Print("Input program: ")
+			fmt.Println(prog)
+		}
+		return execute(prog.NewExecution(0), *snapshot, *pause, *log, shows)
+	}
+	if err := prog.Exec(*log); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// resume continues the execution saved on a snapshot file
+// return int (exit code)
+func resume(args []string) int {
+	flags := flag.NewFlagSet("resume", flag.ExitOnError)
+	log := flags.Bool("log", false, "display the output of the execution")
+	noAccel := flags.Bool("noaccel", false, "execute every iteration of the counting loops one by one")
+	snapshot := flags.String("snapshot", "", "file where the state is saved if the execution is interrupted (Ctrl-C) or paused (the resumed file by default)")
+	pause := flags.Int("pause", 0, "number of steps after which the execution is paused (counted from the start of the execution)")
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	shows := showFlag{}
+	flags.Var(&shows, "show", "variable shown decoded with a shape, as name=shape (e.g. l=[N]), can be repeated")
+	input := flags.String("input", "", inputUsageSTRING)
+	flags.Parse(args)
+
+	path := flags.Arg(0)
+	if path == "" {
+		fmt.Fprintf(os.Stderr, "resume: no snapshot given\n%s", usageSTRING)
+		return 2
+	}
+	if *snapshot == "" {
+		*snapshot = path
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	snap := new(whileinterp.Snapshot)
+	if err := json.Unmarshal(content, snap); err != nil {
+		fmt.Fprintln(os.Stderr, "resume: " + err.Error())
+		return 1
+	}
+	in := newInterpreter(*cantor)
+	in.SetAcceleration(!*noAccel)
+	if err := setInput(in, *input, true); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	e, err := in.Resume(snap)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return execute(e, *snapshot, *pause, *log, shows)
+}
+
+// execute runs an execution until it finishes, and saves its state on the snapshot file if it's interrupted
+// (Ctrl-C) or paused after the given steps (0 for never). Without a snapshot file the execution isn't paused.
+// The variables of shows are printed at the end, decoded with their shapes
+// return int (exit code)
+func execute(e *whileinterp.Execution, snapshot string, pause int, log bool, shows showFlag) int {
+	if snapshot != "" {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+		go func() {
+			if _, ok := <-interrupt; ok {
+				e.Pause()
+			}
+		}()
+		if pause > 0 {
+			e.PauseAfter(pause)
+		}
+	}
+
+	err := e.Run()
+	if err != nil && err != whileinterp.ErrPaused {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	snap, err := e.Snapshot()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !snap.Done {
+		content, err := json.MarshalIndent(snap, "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(snapshot, content, 0644)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Fprintln(os.Stderr, "paused after " + strconv.Itoa(snap.Steps) + " steps, state saved on " + snapshot)
+		return 3
+	}
+
+	if log { //if desired, print the output variables
+		fmt.Println("Output: ")
+		for _, v := range snap.Vars {
+			fmt.Println(v.Name + " => " + strconv.Itoa(v.Value))
+		}
+	}
+	for _, show := range shows {
+		i := strings.Index(show, "=")
+		value, err := e.Var(show[:i])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		formatted, _ := whileinterp.FormatCantor(value, show[i + 1:]) //the shape is checked by the flag
+		fmt.Println(show[:i] + " => " + formatted)
+	}
+	return 0
+}
+
+// showFlag lists the variables shown decoded with a shape, as "name=shape" (the flag can be repeated)
+type showFlag []string
+
+// String returns the variables shown, separated by spaces
+// return string
+func (sf *showFlag) String() string {
+	return strings.Join(*sf, " ")
+}
+
+// Set adds a variable shown, checking its shape
+// return error
+func (sf *showFlag) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i < 1 {
+		return fmt.Errorf("'%s' is not name=shape", value)
+	}
+	if _, err := whileinterp.FormatCantor(0, value[i + 1:]); err != nil {
+		return err
+	}
+	*sf = append(*sf, value)
+	return nil
+}
+
+// newInterpreter returns the interpreter of the codes, with the Cantor pairing functions if requested
+// return *whileinterp.Interpreter
+func newInterpreter(cantor bool) *whileinterp.Interpreter {
+	in := whileinterp.NewInterpreter()
+	if cantor {
+		in.RegisterCantor() //nothing else is registered on a new interpreter
+	}
+	return in
+}
+
+// setInput sets the file read by the READ statements of an interpreter: the standard input if no file is given
+// and stdin is true, or an empty input otherwise
+// return error
+func setInput(in *whileinterp.Interpreter, path string, stdin bool) error {
+	switch {
+		case path != "":
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			in.SetInput(bytes.NewReader(content))
+		case !stdin:
+			in.SetInput(strings.NewReader(""))
+	}
+	return nil
+}
+
+// check checks the code of a file without executing it
+// return int (exit code)
+func check(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	flags.Parse(args)
+
+	if _, err := parseFile(newInterpreter(*cantor), flags.Arg(0)); err != nil { //the code is checked when parsed
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// lint looks for suspicious statements on the code of a file
+// return int (exit code)
+func lint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	disable := flags.String("disable", "", "comma separated list of the rules not reported")
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	flags.Parse(args)
+
+	disabled, err := parseRules(*disable)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	prog, err := parseFile(newInterpreter(*cantor), flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	findings := prog.Lint(disabled...)
+	for _, f := range findings {
+		fmt.Println(f)
+	}
+	if len(findings) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// compute executes the code of a file as a function of the given inputs and prints its result (or "undefined")
+// return int (exit code)
+func compute(args []string) int {
+	flags := flag.NewFlagSet("compute", flag.ExitOnError)
+	steps := flags.Int("steps", 1000000, "maximum number of statements to execute (0 for no limit)")
+	noAccel := flags.Bool("noaccel", false, "execute every iteration of the counting loops one by one")
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	shape := flags.String("shape", "", "shape that decodes the result as a tuple or a list (e.g. [N])")
+	input := flags.String("input", "", inputUsageSTRING)
+	flags.Parse(args)
+
+	if *shape != "" {
+		if _, err := whileinterp.FormatCantor(0, *shape); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	inputs := []int{}
+	for _, arg := range flags.Args()[1:] {
+		in, err := strconv.Atoi(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "compute: input '" + arg + "' is not a number")
+			return 2
+		}
+		inputs = append(inputs, in)
+	}
+
+	in := newInterpreter(*cantor)
+	in.SetAcceleration(!*noAccel)
+	if err := setInput(in, *input, true); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	result := 0
+	f, err := parseFunctionFile(in, flags.Arg(0), len(inputs))
+	if err == nil {
+		result, err = f.Compute(*steps, inputs...)
+	}
+	if err == whileinterp.ErrUndefined {
+		fmt.Println("undefined")
+		return 0
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *shape != "" {
+		formatted, _ := whileinterp.FormatCantor(result, *shape)
+		fmt.Println(formatted)
+		return 0
+	}
+	fmt.Println(result)
+	return 0
+}
+
+// batch computes the function of a file with every line of inputs of the standard input, over a pool of workers
+// return int (exit code)
+func batch(args []string) int {
+	flags := flag.NewFlagSet("batch", flag.ExitOnError)
+	steps := flags.Int("steps", 1000000, "maximum number of statements to execute per input (0 for no limit)")
+	workers := flags.Int("workers", 0, "number of inputs computed at the same time (the number of CPUs by default)")
+	noAccel := flags.Bool("noaccel", false, "execute every iteration of the counting loops one by one")
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	flags.Parse(args)
+
+	if flags.Arg(0) == "-" {
+		fmt.Fprintln(os.Stderr, "batch: the inputs are read from the standard input, the code must be on a file")
+		return 2
+	}
+	if flags.Arg(0) == "" {
+		fmt.Fprintf(os.Stderr, "batch: no file given\n%s", usageSTRING)
+		return 2
+	}
+	if _, err := os.Stat(flags.Arg(0)); err != nil { //the code is parsed once the first line gives the number of inputs
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var err error
+	inputs := [][]int{}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		in := make([]int, len(fields))
+		for i, field := range fields {
+			if in[i], err = strconv.Atoi(field); err != nil {
+				fmt.Fprintln(os.Stderr, "batch: input '" + field + "' is not a number")
+				return 2
+			}
+		}
+		inputs = append(inputs, in)
+	}
+	if len(inputs) == 0 {
+		return 0
+	}
+
+	in := newInterpreter(*cantor)
+	in.SetAcceleration(!*noAccel)
+	setInput(in, "", false) //the standard input has the inputs
+	f, err := parseFunctionFile(in, flags.Arg(0), len(inputs[0])) //every line must have the inputs of the first one
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	status := 0
+	for _, r := range f.Batch(inputs, *workers, *steps) {
+		line := strings.Trim(fmt.Sprint(r.Inputs), "[]") + ": "
+		switch {
+			case r.Err == whileinterp.ErrUndefined:
+				line += "undefined"
+			case r.Err != nil:
+				line += r.Err.Error()
+				status = 1
+			default:
+				line += strconv.Itoa(r.Result)
+		}
+		fmt.Println(line + " (" + strconv.Itoa(r.Steps) + " steps)")
+	}
+	return status
+}
+
+// replayHelpSTRING defines the commands of the replay
+const replayHelpSTRING = `commands:
+    back [n]    goes n statements back (1 by default)
+    next [n]    goes n statements forward (1 by default)
+    goto m      goes to the moment m
+    last x      goes to the last declaration or assignment of the variable x before the current moment
+    start, end  goes to the start or the end of the execution
+    quit        ends the replay
+`
+
+// replay records the execution of the code of a file (as a function if inputs are given) and travels over it
+// with the commands of the standard input
+// return int (exit code)
+func replay(args []string) int {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	steps := flags.Int("steps", 1000000, "maximum number of statements to execute (0 for no limit)")
+	interval := flags.Int("interval", whileinterp.HistoryDefaultInterval, "steps between two checkpoints of the execution")
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	input := flags.String("input", "", inputUsageSTRING)
+	flags.Parse(args)
+
+	if flags.Arg(0) == "-" {
+		fmt.Fprintln(os.Stderr, "replay: the commands are read from the standard input, the code must be on a file")
+		return 2
+	}
+	in := newInterpreter(*cantor)
+	if err := setInput(in, *input, false); err != nil { //the standard input has the commands
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var h *whileinterp.History
+	if flags.NArg() > 1 {
+		inputs := []int{}
+		for _, arg := range flags.Args()[1:] {
+			in, err := strconv.Atoi(arg)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "replay: input '" + arg + "' is not a number")
+				return 2
+			}
+			inputs = append(inputs, in)
+		}
+		f, err := parseFunctionFile(in, flags.Arg(0), len(inputs))
+		if err == nil {
+			h, err = f.Record(*steps, *interval, inputs...)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		prog, err := parseFile(in, flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		h = prog.Record(*steps, *interval)
+	}
+
+	if h.Err() != nil {
+		fmt.Println("execution stopped: " + h.Err().Error())
+	}
+	moment := h.Moments() //the replay starts at the end
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		if state, err := h.At(moment); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		} else {
+			fmt.Println(state)
+		}
+		if !scanner.Scan() {
+			return 0
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		n := 1
+		if len(fields) > 1 {
+			var err error
+			if n, err = strconv.Atoi(fields[1]); err != nil && fields[0] != "last" {
+				fmt.Fprintln(os.Stderr, "replay: '" + fields[1] + "' is not a number")
+				continue
+			}
+		}
+		switch {
+			case fields[0] == "back" || fields[0] == "b":
+				moment -= n
+			case fields[0] == "next" || fields[0] == "n":
+				moment += n
+			case fields[0] == "goto" && len(fields) > 1:
+				moment = n
+			case fields[0] == "last" && len(fields) > 1:
+				last, ok := h.LastAssignment(fields[1], moment)
+				if !ok {
+					fmt.Fprintln(os.Stderr, "replay: variable '" + fields[1] + "' not declared or assigned before")
+					continue
+				}
+				moment = last
+			case fields[0] == "start":
+				moment = 0
+			case fields[0] == "end":
+				moment = h.Moments()
+			case fields[0] == "quit" || fields[0] == "q":
+				return 0
+			default:
+				fmt.Fprint(os.Stderr, replayHelpSTRING)
+				continue
+		}
+		if moment < 0 {
+			moment = 0
+		} else if moment > h.Moments() {
+			moment = h.Moments()
+		}
+	}
+}
+
+// lsp runs a Language Server Protocol server over the standard input and output, for the editors
+// return int (exit code)
+func lsp(args []string) int {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	flags.Parse(args)
+
+	if err := newInterpreter(*cantor).ServeLSP(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// dap runs a Debug Adapter Protocol server over the standard input and output, for the editors
+// return int (exit code)
+func dap(args []string) int {
+	flags := flag.NewFlagSet("dap", flag.ExitOnError)
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	flags.Parse(args)
+
+	if err := newInterpreter(*cantor).ServeDAP(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// optimize shows the code of a file optimized with the given passes
+// return int (exit code)
+func optimize(args []string) int {
+	flags := flag.NewFlagSet("optimize", flag.ExitOnError)
+	passes := flags.String("passes", "", "comma separated list of the optimization passes (all by default)")
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	flags.Parse(args)
+
+	prog, err := parseFile(newInterpreter(*cantor), flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	list := parsePasses(*passes)
+	if len(list) == 0 {
+		list = whileinterp.OptPasses[:]
+	}
+	opt, err := prog.Optimize(list...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Print(opt)
+	return 0
+}
+
+// parsePasses returns the optimization passes of a comma separated list (Optimize reports the passes not defined)
+// return []whileinterp.OptPass
+func parsePasses(list string) []whileinterp.OptPass {
+	passes := []whileinterp.OptPass{}
+	if list == "" {
+		return passes
+	}
+	for _, name := range strings.Split(list, ",") {
+		passes = append(passes, whileinterp.OptPass(strings.TrimSpace(name)))
+	}
+	return passes
+}
+
+// ranges shows the possible range of every variable before every statement of a file, and the conditions
+// of the loops that are always true or always false
+// return int (exit code)
+func ranges(args []string) int {
+	flags := flag.NewFlagSet("ranges", flag.ExitOnError)
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	flags.Parse(args)
+
+	prog, err := parseFile(newInterpreter(*cantor), flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	analysis := prog.Ranges()
+	for _, p := range analysis.Points {
+		fmt.Println(p)
+	}
+	for _, c := range analysis.Conditions {
+		fmt.Println(c)
+	}
+	if len(analysis.Conditions) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// termination shows whether every loop of a file terminates, may not terminate or its termination is unknown
+// return int (exit code)
+func termination(args []string) int {
+	flags := flag.NewFlagSet("termination", flag.ExitOnError)
+	cantor := flags.Bool("cantor", false, cantorUsageSTRING)
+	flags.Parse(args)
+
+	prog, err := parseFile(newInterpreter(*cantor), flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	code := 0
+	for _, lt := range prog.Termination() {
+		fmt.Println(lt)
+		if lt.Verdict != whileinterp.Terminates {
+			code = 1
+		}
+	}
+	return code
+}
+
+// parseRules returns the lint rules of a comma separated list
+// return []whileinterp.LintRule, error
+func parseRules(list string) ([]whileinterp.LintRule, error) {
+	rules := []whileinterp.LintRule{}
+	if list == "" {
+		return rules, nil
+	}
+
+	for _, name := range strings.Split(list, ",") {
+		rule := whileinterp.LintRule(strings.TrimSpace(name))
+		if !isRuleDefined(rule) {
+			return nil, fmt.Errorf("parseRules: lint rule '%s' not defined", rule)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// isRuleDefined checks if a rule is one of the rules of the linter
+// return bool
+func isRuleDefined(rule whileinterp.LintRule) bool {
+	for _, r := range whileinterp.LintRules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFile parses and checks the code of a file (or of the standard input if the path is "-") with an interpreter
+// return *whileinterp.Program, error
+func parseFile(in *whileinterp.Interpreter, path string) (*whileinterp.Program, error) {
+	switch path {
+		case "":
+			return nil, fmt.Errorf("parseFile: no file given\n%s", usageSTRING)
+		case "-":
+			return in.ParseReader(sourceName(path), os.Stdin)
+		default:
+			return in.ParseFile(path)
+	}
+}
+
+// sourceName returns the name of a file shown on the positions ("<stdin>" for the standard input)
+// return string
+func sourceName(path string) string {
+	if path == "-" {
+		return "<stdin>"
+	}
+	return path
+}
+
+// parseFunctionFile parses and checks the code of a file (or of the standard input if the path is "-") as a function
+// of the given number of inputs with an interpreter
+// return *whileinterp.Function, error
+func parseFunctionFile(in *whileinterp.Interpreter, path string, arity int) (*whileinterp.Function, error) {
+	switch path {
+		case "":
+			return nil, fmt.Errorf("parseFunctionFile: no file given\n%s", usageSTRING)
+		case "-":
+			return in.ParseFunctionReader(sourceName(path), os.Stdin, arity)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return in.ParseFunctionReader(path, file, arity)
+}
@@ -0,0 +1,528 @@
+package whileinterp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+/*
+    Debug Adapter Protocol server of the while programs, over a reader and a writer (e.g. stdin and stdout).
+
+    The program is launched with the arguments:
+        - "program": path of the file with the code.
+        - "inputs": inputs of the program, which is executed as a function (see ComputeCode) if they are given.
+        - "maxSteps": maximum number of statements to execute (1000000 by default, 0 for no limit).
+        - "stopOnEntry": the program is paused before its first statement.
+        - "read": values read by the READ statements, in order (the values PRINTed are sent as output events).
+
+    It offers line breakpoints, step in/over/out of the loop bodies and the function calls, pause and
+    a variables pane for every frame (the main program and the functions being executed). When the
+    program exhausts its steps, it is paused before the execution ends, so its variables can be inspected.
+    The program can call the functions registered on the interpreter of the server.
+*/
+
+// dapDefaultMaxSteps defines the maximum number of statements to execute if the launch doesn't give it
+const dapDefaultMaxSteps = 1000000
+
+// dapThreadID is the identifier of the only thread of the programs
+const dapThreadID = 1
+
+// errDAPTerminated is returned by the debugged program when the editor terminates it
+var errDAPTerminated = errors.New("ServeDAP: program terminated")
+
+// dapMode defines how the debugged program is resumed
+type dapMode int
+
+const (
+	dapContinue dapMode = iota //until a breakpoint or the end
+	dapStepIn //until the next statement
+	dapStepOver //until the next statement of the same block or an outer one
+	dapStepOut //until the next statement of an outer block
+	dapEntry //until the first statement (stopOnEntry)
+)
+
+// dapRequest is a request received by the server
+type dapRequest struct {
+	Seq int `json:"seq"`
+	Command string `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// dapResponse is the response of the server to a request
+type dapResponse struct {
+	Seq int `json:"seq"`
+	Type string `json:"type"`
+	RequestSeq int `json:"request_seq"`
+	Success bool `json:"success"`
+	Command string `json:"command"`
+	Message string `json:"message,omitempty"`
+	Body interface{} `json:"body,omitempty"`
+}
+
+// dapEvent is an event sent by the server
+type dapEvent struct {
+	Seq int `json:"seq"`
+	Type string `json:"type"`
+	Event string `json:"event"`
+	Body interface{} `json:"body,omitempty"`
+}
+
+// dapArguments are the arguments of the requests
+type dapArguments struct {
+	Program string `json:"program"`
+	Inputs []int `json:"inputs"`
+	MaxSteps *int `json:"maxSteps"`
+	StopOnEntry bool `json:"stopOnEntry"`
+	Read []int `json:"read"`
+	Source struct {
+		Path string `json:"path"`
+	} `json:"source"`
+	Breakpoints []struct {
+		Line int `json:"line"`
+	} `json:"breakpoints"`
+	FrameID int `json:"frameId"`
+	VariablesReference int `json:"variablesReference"`
+}
+
+// dapResume is the order given to a paused program
+type dapResume struct {
+	mode dapMode //how the program is resumed
+	terminate bool //the program is terminated instead of resumed
+}
+
+// dapSession debugs a program launched by an editor
+type dapSession struct {
+	out io.Writer //writer of the responses and the events
+	mu sync.Mutex //protects the fields below, shared with the program being executed
+	seq int //sequence number of the last message sent
+	name string //path of the program
+	code string //source code of the program
+	p *program //main program (nil until it is launched)
+	funcs *Interpreter //interpreter of the program (its registered functions can be called)
+	function bool //the program is executed as a function of its inputs
+	reads []int //values left to read by the READ statements
+	lineStarts map[int]int //offset of the first statement of every line
+	breakpoints map[int]bool //lines with a breakpoint
+	mode dapMode //how the program was resumed the last time
+	modeLevel int //block level of the program when it was resumed the last time
+	pauseRequested bool //the editor has asked to pause the program
+	terminated bool //the editor has asked to terminate the program
+	paused *program //frame where the program is paused (nil if it is running)
+	started bool //the program has been started
+	resume chan dapResume //orders given to the paused program
+	done chan struct{} //closed when the program ends
+}
+
+// ServeDAP runs a Debug Adapter Protocol server, reading the requests from r and writing the responses on w,
+// until the editor disconnects or r is closed
+// return error
+func ServeDAP(r io.Reader, w io.Writer) error {
+	return defaultInterpreter.ServeDAP(r, w)
+}
+
+// ServeDAP runs a Debug Adapter Protocol server whose programs can call the functions of the interpreter
+// return error
+func (in *Interpreter) ServeDAP(r io.Reader, w io.Writer) error {
+	d := &dapSession{out: w, funcs: in, breakpoints: map[int]bool{}, lineStarts: map[int]int{}, resume: make(chan dapResume), done: make(chan struct{})}
+	defer d.terminate()
+
+	br := bufio.NewReader(r)
+	for {
+		content, err := readMessage(br)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		req := &dapRequest{}
+		if err := json.Unmarshal(content, req); err != nil {
+			return errors.New("ServeDAP: message not valid: " + err.Error())
+		}
+
+		if disconnect, err := d.handle(req); disconnect || err != nil {
+			return err
+		}
+	}
+}
+
+// handle answers a request
+// return bool (true if the editor has disconnected), error
+func (d *dapSession) handle(req *dapRequest) (bool, error) {
+	args := &dapArguments{}
+	if len(req.Arguments) > 0 {
+		if err := json.Unmarshal(req.Arguments, args); err != nil {
+			return false, d.respond(req, nil, errors.New("arguments not valid: " + err.Error()))
+		}
+	}
+
+	var body interface{}
+	var err error
+	switch req.Command {
+	case "initialize":
+		body = map[string]bool{"supportsConfigurationDoneRequest": true, "supportsTerminateRequest": true}
+	case "launch":
+		if err = d.launch(args); err == nil {
+			if err := d.respond(req, nil, nil); err != nil {
+				return false, err
+			}
+			return false, d.send("initialized", nil) //the editor can set the breakpoints now
+		}
+	case "setBreakpoints":
+		body = d.setBreakpoints(args)
+	case "configurationDone":
+		err = d.start()
+	case "threads":
+		body = map[string]interface{}{"threads": []map[string]interface{}{{"id": dapThreadID, "name": "main"}}}
+	case "stackTrace":
+		body = d.stackTrace()
+	case "scopes":
+		body = map[string]interface{}{"scopes": []map[string]interface{}{{"name": "Variables", "variablesReference": args.FrameID, "expensive": false}}}
+	case "variables":
+		body = d.variables(args.VariablesReference)
+	case "continue", "next", "stepIn", "stepOut":
+		modes := map[string]dapMode{"continue": dapContinue, "next": dapStepOver, "stepIn": dapStepIn, "stepOut": dapStepOut}
+		if err = d.checkPaused(); err != nil {
+			break
+		}
+		if err := d.respond(req, map[string]bool{"allThreadsContinued": true}, nil); err != nil {
+			return false, err
+		}
+		d.resume <- dapResume{mode: modes[req.Command]} //the response is sent before the program stops again
+		return false, nil
+	case "pause":
+		d.mu.Lock()
+		d.pauseRequested = true
+		d.mu.Unlock()
+	case "terminate":
+		if err := d.respond(req, nil, nil); err != nil {
+			return false, err
+		}
+		d.terminate() //the terminated event is sent after the response
+		return false, nil
+	case "disconnect":
+		d.terminate()
+		return true, d.respond(req, nil, nil)
+	default:
+		err = errors.New("command '" + req.Command + "' not supported")
+	}
+	return false, d.respond(req, body, err)
+}
+
+// respond sends the response of a request, which has failed if err is not nil
+// return error
+func (d *dapSession) respond(req *dapRequest, body interface{}, err error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seq++
+	resp := &dapResponse{Seq: d.seq, Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+	}
+	return writeMessage(d.out, resp)
+}
+
+// send sends an event
+// return error
+func (d *dapSession) send(event string, body interface{}) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.seq++
+	return writeMessage(d.out, &dapEvent{Seq: d.seq, Type: "event", Event: event, Body: body})
+}
+
+// launch parses and checks the program to debug
+// return error
+func (d *dapSession) launch(args *dapArguments) error {
+	if d.p != nil {
+		return errors.New("program already launched")
+	}
+	content, err := ioutil.ReadFile(args.Program)
+	if err != nil {
+		return err
+	}
+
+	p := d.funcs.newProgram()
+	if args.Inputs != nil { //x0..xk are declared before the code is checked
+		if p, err = initFunction(args.Inputs); err != nil {
+			return err
+		}
+		p.funcs = d.funcs
+		p.accelerate = d.funcs.accelerates()
+	}
+	p.name = args.Program
+	p.code = string(content)
+	if err := p.getStmts(string(content)); err != nil {
+		return err
+	}
+	if errs := p.check(string(content)); len(errs) > 0 {
+		return errs
+	}
+
+	for _, n := range args.Read {
+		if n < 0 {
+			return errors.New("value '" + strconv.Itoa(n) + "' to read is not a natural number")
+		}
+	}
+
+	p.maxSteps = dapDefaultMaxSteps
+	if args.MaxSteps != nil {
+		p.maxSteps = *args.MaxSteps
+	}
+	p.tracer = d
+	p.stdio = d
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.p, d.name, d.code, d.function, d.reads = p, args.Program, string(content), args.Inputs != nil, args.Read
+	d.addLineStarts(p.stmts)
+	if args.StopOnEntry {
+		d.mode = dapEntry
+	}
+	return nil
+}
+
+// addLineStarts saves the offset of the first statement of every line of a list of statements
+func (d *dapSession) addLineStarts(stmts []stmt) {
+	for _, s := range stmts {
+		if s.op == importSTRING { //the imports are not executed
+			continue
+		}
+		d.addLineStarts(s.body)
+		offset := dapOffset(&s)
+		line := newPosition("", d.code, offset).Line
+		if start, ok := d.lineStarts[line]; !ok || offset < start {
+			d.lineStarts[line] = offset
+		}
+	}
+}
+
+// dapOffset returns the offset where a statement is executed: the RETURN for a PROC (the definition is not executed)
+// return int
+func dapOffset(s *stmt) int {
+	if s.op == procSTRING {
+		return s.value.offset
+	}
+	return s.offset
+}
+
+// setBreakpoints replaces the breakpoints of the program
+// return interface{} (body of the response)
+func (d *dapSession) setBreakpoints(args *dapArguments) interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.breakpoints = map[int]bool{}
+	breakpoints := []map[string]interface{}{}
+	for _, b := range args.Breakpoints {
+		_, verified := d.lineStarts[b.Line] //a breakpoint must be on a line with a statement
+		if verified {
+			d.breakpoints[b.Line] = true
+		}
+		breakpoints = append(breakpoints, map[string]interface{}{"verified": verified, "line": b.Line})
+	}
+	return map[string]interface{}{"breakpoints": breakpoints}
+}
+
+// start starts the execution of the program
+// return error
+func (d *dapSession) start() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.p == nil {
+		return errors.New("program not launched")
+	}
+	if d.started {
+		return nil
+	}
+	d.started = true
+
+	go func() {
+		err := d.p.parseProgram()
+		d.finish(err)
+		close(d.done)
+	}()
+	return nil
+}
+
+// finish sends the result of the program and the events of its end
+func (d *dapSession) finish(err error) {
+	exitCode := 0
+	output := ""
+	switch {
+	case err == errDAPTerminated:
+	case err == ErrUndefined:
+		output = "undefined\n"
+	case err != nil:
+		output = err.Error() + "\n"
+		exitCode = 1
+	case d.function:
+		result, _ := d.p.getVar(resultVarSTRING)
+		output = resultVarSTRING + " = " + strconv.Itoa(result.value) + "\n"
+	default:
+		for _, v := range d.p.vars {
+			output += v.name + " = " + v.String() + "\n"
+		}
+	}
+
+	if output != "" {
+		d.send("output", map[string]string{"category": "stdout", "output": output})
+	}
+	d.send("exited", map[string]int{"exitCode": exitCode})
+	d.send("terminated", nil)
+}
+
+// read returns the next value given by the launch to the READ statements
+// return int, error
+func (d *dapSession) read() (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.reads) == 0 {
+		return 0, errInputEnded
+	}
+	n := d.reads[0]
+	d.reads = d.reads[1:]
+	return n, nil
+}
+
+// print sends a value of a PRINT statement as an output event
+// return error
+func (d *dapSession) print(n int) error {
+	return d.send("output", map[string]string{"category": "stdout", "output": strconv.Itoa(n) + "\n"})
+}
+
+// beforeStmt pauses the program before a statement if a breakpoint, a step or a pause requires it
+// return error (errDAPTerminated if the editor terminates the program)
+func (d *dapSession) beforeStmt(p *program, s *stmt) error {
+	d.mu.Lock()
+	reason := ""
+	switch {
+	case d.terminated:
+		d.mu.Unlock()
+		return errDAPTerminated
+	case d.pauseRequested:
+		reason = "pause"
+	case d.mode == dapEntry:
+		reason = "entry"
+	case d.mode == dapStepIn, d.mode == dapStepOver && p.level <= d.modeLevel, d.mode == dapStepOut && p.level < d.modeLevel:
+		reason = "step"
+	default:
+		offset := dapOffset(s)
+		if line := newPosition("", d.code, offset).Line; d.breakpoints[line] && d.lineStarts[line] == offset {
+			reason = "breakpoint"
+		}
+	}
+	d.mu.Unlock()
+
+	if reason == "" {
+		return nil
+	}
+	return d.stop(p, map[string]interface{}{"reason": reason, "threadId": dapThreadID, "allThreadsStopped": true})
+}
+
+// stepLimit pauses the program when it exhausts its steps
+func (d *dapSession) stepLimit(p *program) {
+	d.stop(p, map[string]interface{}{"reason": "exception", "description": "step limit reached", "text": ErrUndefined.Error(), "threadId": dapThreadID, "allThreadsStopped": true})
+}
+
+// stop pauses the program on a frame until the editor resumes it
+// return error (errDAPTerminated if the editor terminates the program)
+func (d *dapSession) stop(p *program, event map[string]interface{}) error {
+	d.mu.Lock()
+	if d.terminated { //the editor can't resume the program anymore
+		d.mu.Unlock()
+		return errDAPTerminated
+	}
+	d.paused = p
+	d.pauseRequested = false
+	d.mu.Unlock()
+
+	d.send("stopped", event)
+	order := <-d.resume
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paused = nil
+	d.mode, d.modeLevel = order.mode, p.level
+	if order.terminate || d.terminated {
+		return errDAPTerminated
+	}
+	return nil
+}
+
+// checkPaused checks that the program is paused
+// return error
+func (d *dapSession) checkPaused() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.paused == nil {
+		return errors.New("program not paused")
+	}
+	return nil
+}
+
+// terminate terminates the program, if it is being executed, and waits for its end
+func (d *dapSession) terminate() {
+	d.mu.Lock()
+	d.terminated = true
+	paused, started := d.paused != nil, d.started
+	d.mu.Unlock()
+
+	if paused {
+		d.resume <- dapResume{terminate: true}
+	}
+	if started {
+		<-d.done
+	}
+}
+
+// frames returns the frames of the paused program, from the innermost function call to the main program
+// return []*program
+func (d *dapSession) frames() []*program {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	frames := []*program{}
+	for p := d.paused; p != nil; p = p.caller {
+		frames = append(frames, p)
+	}
+	return frames
+}
+
+// stackTrace returns the frames of the paused program
+// return interface{} (body of the response)
+func (d *dapSession) stackTrace() interface{} {
+	stackFrames := []map[string]interface{}{}
+	for i, p := range d.frames() {
+		name := "main"
+		if p.caller != nil {
+			name = p.procName
+		}
+		pos := newPosition("", d.code, dapOffset(p.current))
+		source := map[string]string{"name": filepath.Base(d.name), "path": d.name}
+		stackFrames = append(stackFrames, map[string]interface{}{"id": i + 1, "name": name, "line": pos.Line, "column": pos.Column, "source": source})
+	}
+	return map[string]interface{}{"stackFrames": stackFrames, "totalFrames": len(stackFrames)}
+}
+
+// variables returns the variables of a frame of the paused program (the frames are numbered from 1)
+// return interface{} (body of the response)
+func (d *dapSession) variables(frame int) interface{} {
+	variables := []map[string]interface{}{}
+	if frames := d.frames(); frame >= 1 && frame <= len(frames) {
+		for _, v := range frames[frame - 1].vars {
+			variables = append(variables, map[string]interface{}{"name": v.name, "value": v.String(), "variablesReference": 0})
+		}
+	}
+	return map[string]interface{}{"variables": variables}
+}
@@ -0,0 +1,247 @@
+package whileinterp
+
+import (
+    "bufio"
+    "encoding/json"
+    "io"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "testing"
+)
+
+const testDAPCode = `PROC double(a) DO
+    b := val(a)
+    WHILE(b > 0) DO
+        a = inc(a)
+        b = dec(b)
+    OD
+    RETURN a
+OD
+x1 = double(x1)
+WHILE(x1 > 0) DO
+    x0 = inc(x0)
+    x1 = dec(x1)
+OD
+`
+
+// dapClient sends the requests of an editor to a debug server and reads its messages
+type dapClient struct {
+    t *testing.T
+    in io.WriteCloser
+    out *bufio.Reader
+    seq int
+    dir string
+    done chan error
+}
+
+/*********************** TESTING ***********************/
+func TestServeDAPBreakpoints(t *testing.T) {
+    c := newTestDAPClient(t)
+    defer c.close()
+
+    c.launch(map[string]interface{}{"inputs": []int{2}})
+    resp := c.request("setBreakpoints", map[string]interface{}{"breakpoints": []map[string]int{{"line": 11}, {"line": 8}}})
+    c.check(resp["body"], `{"breakpoints":[{"line":11,"verified":true},{"line":8,"verified":false}]}`)
+    c.request("configurationDone", nil)
+
+    c.check(c.expect("event", "stopped")["body"], `{"allThreadsStopped":true,"reason":"breakpoint","threadId":1}`)
+    c.checkStack(`[{"column":5,"id":1,"line":11,"name":"main","source":{"name":"prog.while","path":"` + c.path() + `"}}]`)
+    c.checkVars(1, `{"variables":[{"name":"x0","value":"0","variablesReference":0},{"name":"x1","value":"4","variablesReference":0}]}`)
+
+    c.request("next", nil)
+    c.check(c.expect("event", "stopped")["body"], `{"allThreadsStopped":true,"reason":"step","threadId":1}`)
+    c.checkLine(12)
+
+    c.request("stepOut", nil) //the breakpoint is found before the loop body is left
+    c.check(c.expect("event", "stopped")["body"], `{"allThreadsStopped":true,"reason":"breakpoint","threadId":1}`)
+    c.checkVars(1, `{"variables":[{"name":"x0","value":"1","variablesReference":0},{"name":"x1","value":"3","variablesReference":0}]}`)
+
+    c.request("setBreakpoints", map[string]interface{}{"breakpoints": []map[string]int{}})
+    c.request("continue", nil)
+    c.check(c.expect("event", "output")["body"], `{"category":"stdout","output":"x0 = 4\n"}`)
+    c.expect("event", "terminated")
+}
+
+func TestServeDAPStepInOut(t *testing.T) {
+    c := newTestDAPClient(t)
+    defer c.close()
+
+    c.launch(map[string]interface{}{"inputs": []int{2}, "stopOnEntry": true})
+    c.request("configurationDone", nil)
+    c.check(c.expect("event", "stopped")["body"], `{"allThreadsStopped":true,"reason":"entry","threadId":1}`)
+    c.checkLine(9)
+
+    c.request("stepIn", nil)
+    c.expect("event", "stopped")
+    c.checkStack(`[{"column":5,"id":1,"line":2,"name":"double","source":{"name":"prog.while","path":"` + c.path() + `"}},` +
+        `{"column":1,"id":2,"line":9,"name":"main","source":{"name":"prog.while","path":"` + c.path() + `"}}]`)
+    c.checkVars(1, `{"variables":[{"name":"a","value":"2","variablesReference":0}]}`)
+
+    c.request("next", nil)
+    c.expect("event", "stopped")
+    c.checkLine(3)
+    c.request("next", nil) //the loop is stepped over until the RETURN
+    c.expect("event", "stopped")
+    c.checkLine(7)
+    c.checkVars(1, `{"variables":[{"name":"a","value":"4","variablesReference":0},{"name":"b","value":"0","variablesReference":0}]}`)
+
+    c.request("stepOut", nil)
+    c.expect("event", "stopped")
+    c.checkLine(10)
+
+    c.request("terminate", nil)
+    c.expect("event", "terminated")
+}
+
+func TestServeDAPStepLimit(t *testing.T) {
+    c := newTestDAPClient(t)
+    defer c.close()
+
+    c.launch(map[string]interface{}{"inputs": []int{2}, "maxSteps": 5})
+    c.request("configurationDone", nil)
+    c.check(c.expect("event", "stopped")["body"], `{"allThreadsStopped":true,"description":"step limit reached","reason":"exception","text":"undefined: step budget exhausted","threadId":1}`)
+    c.checkLine(5)
+
+    c.request("continue", nil)
+    c.check(c.expect("event", "output")["body"], `{"category":"stdout","output":"undefined\n"}`)
+    c.expect("event", "terminated")
+}
+
+func TestServeDAPInterpreter(t *testing.T) {
+    interp := NewInterpreter()
+    interp.RegisterCantor()
+    c := newTestDAPClientInterpreter(interp, t)
+    defer c.close()
+    if err := ioutil.WriteFile(c.path(), []byte("x1 = pair(x1, 2)\nx0 = fst(x1)\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    c.launch(map[string]interface{}{"inputs": []int{3}, "stopOnEntry": true})
+    c.request("configurationDone", nil)
+    c.expect("event", "stopped")
+    c.request("next", nil)
+    c.expect("event", "stopped")
+    c.checkVars(1, `{"variables":[{"name":"x0","value":"0","variablesReference":0},{"name":"x1","value":"17","variablesReference":0}]}`)
+
+    c.request("continue", nil)
+    c.check(c.expect("event", "output")["body"], `{"category":"stdout","output":"x0 = 3\n"}`)
+    c.expect("event", "terminated")
+}
+
+func TestServeDAPLaunchErrors(t *testing.T) {
+    c := newTestDAPClient(t)
+    defer c.close()
+
+    c.write(map[string]interface{}{"seq": 1, "type": "request", "command": "launch", "arguments": map[string]interface{}{"program": filepath.Join(c.dir, "missing.while")}})
+    if resp := c.expect("response", "launch"); resp["success"] != false {
+        t.Error("expected error not returned")
+    }
+    c.write(map[string]interface{}{"seq": 2, "type": "request", "command": "continue"})
+    c.check(c.expect("response", "continue")["message"], `"program not paused"`)
+}
+
+func newTestDAPClient(t *testing.T) *dapClient {
+    return newTestDAPClientInterpreter(defaultInterpreter, t)
+}
+
+// newTestDAPClientInterpreter starts a debug server of an interpreter and initializes it
+func newTestDAPClientInterpreter(interp *Interpreter, t *testing.T) *dapClient {
+    reqR, reqW := io.Pipe()
+    respR, respW := io.Pipe()
+    c := &dapClient{t: t, in: reqW, out: bufio.NewReader(respR), done: make(chan error, 1)}
+    go func() {
+        c.done <- interp.ServeDAP(reqR, respW)
+        respW.Close()
+    }()
+
+    dir, err := ioutil.TempDir("", "whileinterp")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := ioutil.WriteFile(filepath.Join(dir, "prog.while"), []byte(testDAPCode), 0644); err != nil {
+        t.Fatal(err)
+    }
+    c.dir = dir
+    c.request("initialize", map[string]string{"adapterID": "while"})
+    return c
+}
+
+func (c *dapClient) path() string {
+    return filepath.Join(c.dir, "prog.while")
+}
+
+func (c *dapClient) launch(args map[string]interface{}) {
+    args["program"] = c.path()
+    c.request("launch", args)
+    c.expect("event", "initialized")
+}
+
+func (c *dapClient) write(msg interface{}) {
+    if err := writeMessage(c.in, msg); err != nil {
+        c.t.Fatal(err)
+    }
+}
+
+func (c *dapClient) request(command string, args interface{}) map[string]interface{} {
+    c.seq++
+    c.write(map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args})
+    resp := c.expect("response", command)
+    if resp["success"] != true {
+        c.t.Error("unexpected failed request: ", command, resp["message"])
+    }
+    return resp
+}
+
+// expect reads the messages until one of the given type ("response" or "event") and command or event is found
+func (c *dapClient) expect(kind string, name string) map[string]interface{} {
+    for {
+        content, err := readMessage(c.out)
+        if err != nil {
+            c.t.Fatal("message '", name, "' expected: ", err)
+        }
+        msg := map[string]interface{}{}
+        json.Unmarshal(content, &msg)
+        if msg["type"] == kind && (msg["command"] == name || msg["event"] == name) {
+            return msg
+        }
+    }
+}
+
+func (c *dapClient) check(value interface{}, expec string) {
+    content, _ := json.Marshal(value)
+    if string(content) != expec {
+        c.t.Error("unexpected returned value:\n returned: ", string(content), "\n expected: ", expec)
+    }
+}
+
+func (c *dapClient) checkStack(expec string) {
+    c.check(c.request("stackTrace", map[string]int{"threadId": dapThreadID})["body"].(map[string]interface{})["stackFrames"], expec)
+}
+
+func (c *dapClient) checkLine(expec int) {
+    frames := c.request("stackTrace", map[string]int{"threadId": dapThreadID})["body"].(map[string]interface{})["stackFrames"].([]interface{})
+    if line := frames[0].(map[string]interface{})["line"]; line != float64(expec) {
+        c.t.Error("unexpected returned line:\n returned: ", line, "\n expected: ", expec)
+    }
+}
+
+func (c *dapClient) checkVars(frame int, expec string) {
+    c.request("scopes", map[string]int{"frameId": frame})
+    c.check(c.request("variables", map[string]int{"variablesReference": frame})["body"], expec)
+}
+
+func (c *dapClient) close() {
+    c.seq++
+    go c.write(map[string]interface{}{"seq": c.seq, "type": "request", "command": "disconnect"}) //the server can be writing yet
+    for { //the messages not read yet are skipped
+        if _, err := readMessage(c.out); err != nil {
+            break
+        }
+    }
+    if err := <-c.done; err != nil {
+        c.t.Error(err)
+    }
+    c.in.Close()
+    os.RemoveAll(c.dir)
+}
@@ -0,0 +1,166 @@
+package whileinterp
+
+import "strings"
+
+// formatStmts returns the code of a list of statements, divided by "; "
+// return string
+func formatStmts(stmts []stmt) string {
+	code := ""
+	for i, s := range stmts {
+		if i > 0 {
+			code += " "
+		}
+		code += formatStmt(s) + ";"
+	}
+	return code
+}
+
+// formatStmt returns the code of a statement
+// return string
+func formatStmt(s stmt) string {
+	if s.op == importSTRING && s.path != "" {
+		return importSTRING + " \"" + s.path + "\""
+	}
+	if s.op == importSTRING {
+		return importSTRING + " " + s.name
+	}
+	if s.op == printSTRING {
+		return printSTRING + " " + formatValue(s.value)
+	}
+	if s.op == readSTRING {
+		return readSTRING + " " + s.name
+	}
+	if s.index != nil {
+		return s.name + "[" + formatValue(s.index) + "] " + s.op + " " + formatValue(s.value)
+	}
+	if s.op != whileFuncSTRING && s.op != procSTRING {
+		return s.name + " " + s.op + " " + formatValue(s.value)
+	}
+
+	body := " "
+	for i, b := range s.body {
+		if i > 0 {
+			body += "; "
+		}
+		body += formatStmt(b)
+	}
+	if len(s.body) > 0 {
+		body += " "
+	}
+	if s.op == procSTRING {
+		return procSTRING + " " + s.name + "(" + strings.Join(s.params, ", ") + ") " + doSTRING + body + returnSTRING + " " + formatValue(s.value) + " " + odSTRING
+	}
+	return whileFuncSTRING + "(" + formatLogicExpr(s.cond) + ") " + doSTRING + body + odSTRING
+}
+
+// formatLogicExpr returns the code of a logic expression, with the parenthesis needed to keep its precedence
+// return string
+func formatLogicExpr(l *logicExpr) string {
+	switch l.op {
+	case andOPSTRING: //AND has precedence over OR, and both are parsed from left to right
+		return formatLogicOperand(l.left, orOPSTRING) + " " + andOPSTRING + " " + formatLogicOperand(l.right, orOPSTRING, andOPSTRING)
+	case orOPSTRING:
+		return formatLogicExpr(l.left) + " " + orOPSTRING + " " + formatLogicOperand(l.right, orOPSTRING)
+	case notOPSTRING:
+		return notOPSTRING + " " + formatLogicOperand(l.left, orOPSTRING, andOPSTRING)
+	default:
+		return formatValue(l.first) + " " + l.op + " " + formatValue(l.second)
+	}
+}
+
+// formatLogicOperand returns the code of a logic expression, between parenthesis if its operation is one of the given ones
+// return string
+func formatLogicOperand(l *logicExpr, parenthesisOps ...string) string {
+	for _, op := range parenthesisOps {
+		if l.op == op {
+			return "(" + formatLogicExpr(l) + ")"
+		}
+	}
+	return formatLogicExpr(l)
+}
+
+// formatValue returns the code of a number, a variable, an element of an array or a function call
+// return string
+func formatValue(v *valueExpr) string {
+	switch v.kind {
+	case indexValue:
+		return v.text + "[" + formatValue(v.params[0]) + "]"
+	case numberValue, varValue:
+		return v.text
+	}
+
+	code := v.text + "("
+	for i, param := range v.params {
+		if i > 0 {
+			code += ", "
+		}
+		code += formatValue(param)
+	}
+	return code + ")"
+}
+
+// indentSTRING defines the indentation of the bodies of the WHILEs and the PROCs on the formatted code
+const indentSTRING = "    "
+
+// codeFormatter formats a code one statement per line, keeping its comments
+type codeFormatter struct {
+	code string //source code of the statements
+	comments []token //comments of the code not written yet
+	lines []string //lines of the formatted code
+}
+
+// formatCode returns the code of a list of statements with one statement per line, the bodies indented
+// and the comments of the source code kept before the statement that follows them (or after, on the same line)
+// return string
+func formatCode(code string, stmts []stmt) string {
+	f := &codeFormatter{code: code, comments: scanComments(code)}
+	f.formatStmts(stmts, "")
+	f.flushComments(len(code) + 1, "")
+
+	return strings.Join(f.lines, "\n") + "\n"
+}
+
+// formatStmts writes the lines of a list of statements with the given indentation
+func (f *codeFormatter) formatStmts(stmts []stmt, indent string) {
+	for _, s := range stmts {
+		f.flushComments(s.offset, indent)
+		end := s.offset + len(s.content)
+
+		switch s.op {
+		case whileFuncSTRING:
+			f.lines = append(f.lines, indent + whileFuncSTRING + "(" + formatLogicExpr(s.cond) + ") " + doSTRING)
+			f.formatStmts(s.body, indent + indentSTRING)
+			f.flushComments(end - len(odSTRING), indent + indentSTRING)
+			f.lines = append(f.lines, indent + odSTRING)
+		case procSTRING:
+			f.lines = append(f.lines, indent + procSTRING + " " + s.name + "(" + strings.Join(s.params, ", ") + ") " + doSTRING)
+			f.formatStmts(s.body, indent + indentSTRING)
+			f.flushComments(s.value.offset, indent + indentSTRING)
+			f.lines = append(f.lines, indent + indentSTRING + returnSTRING + " " + formatValue(s.value))
+			f.flushComments(end - len(odSTRING), indent + indentSTRING)
+			f.lines = append(f.lines, indent + odSTRING)
+		default:
+			f.lines = append(f.lines, indent + formatStmt(s))
+		}
+		f.trailingComment(end)
+	}
+}
+
+// flushComments writes on their own lines the comments found before an offset
+func (f *codeFormatter) flushComments(offset int, indent string) {
+	for len(f.comments) > 0 && f.comments[0].offset < offset {
+		f.lines = append(f.lines, indent + f.comments[0].text)
+		f.comments = f.comments[1:]
+	}
+}
+
+// trailingComment writes at the end of the last line the comment found on the same line as the given offset
+func (f *codeFormatter) trailingComment(offset int) {
+	if len(f.comments) == 0 || f.comments[0].offset < offset {
+		return
+	}
+	if between := f.code[offset:f.comments[0].offset]; strings.TrimLeft(between, " \t;") == "" {
+		f.lines[len(f.lines) - 1] += " " + f.comments[0].text
+		f.comments = f.comments[1:]
+	}
+}
@@ -0,0 +1,184 @@
+package whileinterp
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+)
+
+/*
+    Registry of the functions that the programs can call besides the ones defined with PROC: the predefined
+    functions (zero, inc, dec, val) and the Go functions registered by an embedder on an Interpreter:
+
+        in := NewInterpreter()
+        err := in.RegisterFunc("max", 2, func(args []int) (int, error) { ... })
+        prog, err := in.ParseCode("x := max(3, 4)")
+
+    The registered functions are only known by the programs parsed (or resumed) by their Interpreter: the
+    package functions (e.g. ParseCode) only know the predefined ones.
+*/
+
+// Func is a Go function called by the programs with the values of its parameters (as many as its arity),
+// which returns a natural number or an error that stops the execution
+type Func func(args []int) (int, error)
+
+// funcDef is a function that the programs can call
+type funcDef struct {
+	arity int //number of parameters
+	fn Func //function called with the values of the parameters
+}
+
+// predefinedFuncs defines the functions available on every program (the names listed on possFunc)
+var predefinedFuncs = map[string]funcDef{
+	"zero": {arity: 0, fn: func(args []int) (int, error) { return zero(), nil }},
+	"inc": {arity: 1, fn: func(args []int) (int, error) { return inc(args[0]), nil }},
+	"dec": {arity: 1, fn: func(args []int) (int, error) { return dec(args[0]), nil }},
+	"val": {arity: 1, fn: func(args []int) (int, error) { return val(args[0]), nil }},
+}
+
+// Interpreter parses and executes programs that can call the Go functions registered on it
+type Interpreter struct {
+	mu sync.RWMutex //protects the fields below (the executions may run concurrently)
+	funcs map[string]funcDef //functions registered by their name
+	std bool //the programs import the standard library without IMPORT std
+	input *naturalReader //input of the READ statements (nil for the standard input)
+	output *lineWriter //output of the PRINT statements (nil for the standard output)
+	noAccel bool //the counting loops are executed one by one
+}
+
+// defaultInterpreter is used by the package functions, no function is registered on it
+var defaultInterpreter = NewInterpreter()
+
+// NewInterpreter returns an interpreter that only knows the predefined functions
+// return *Interpreter
+func NewInterpreter() *Interpreter {
+	return &Interpreter{funcs: map[string]funcDef{}}
+}
+
+// RegisterFunc registers a Go function with a fixed number of parameters, which the programs parsed by the
+// interpreter can call by its name (e.g. RegisterFunc("max", 2, fn) for "x := max(a, b)").
+// The name can't be a reserved word, a predefined function or a function already registered
+// return error
+func (in *Interpreter) RegisterFunc(name string, arity int, fn Func) error {
+	if !isValidName(name) {
+		return errors.New("RegisterFunc: name '" + name + "' is not valid")
+	}
+	if arity < 0 {
+		return errors.New("RegisterFunc: function '" + name + "' can't have " + strconv.Itoa(arity) + " parameter(s)")
+	}
+	if fn == nil {
+		return errors.New("RegisterFunc: function '" + name + "' is nil")
+	}
+	if _, ok := predefinedFuncs[name]; ok || name == arraySTRING {
+		return errors.New("RegisterFunc: function '" + name + "' already defined as a predefined function")
+	}
+
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if _, ok := in.funcs[name]; ok {
+		return errors.New("RegisterFunc: function '" + name + "' already registered")
+	}
+	in.funcs[name] = funcDef{arity: arity, fn: fn}
+	return nil
+}
+
+// registered returns a function registered on the interpreter (nil knows no function)
+// return funcDef, bool (false if the function isn't registered)
+func (in *Interpreter) registered(name string) (funcDef, bool) {
+	if in == nil {
+		return funcDef{}, false
+	}
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	f, ok := in.funcs[name]
+	return f, ok
+}
+
+// funcParams returns the number of parameters of a predefined function or a function registered on the interpreter
+// return int (-1 if the function is not defined)
+func (in *Interpreter) funcParams(name string) int {
+	if params := funcParams(name); params != -1 {
+		return params
+	}
+	if f, ok := in.registered(name); ok {
+		return f.arity
+	}
+	return -1
+}
+
+// execFunc executes a predefined function or a function registered on the interpreter with the given parameters
+// return int, error
+func (in *Interpreter) execFunc(name string, params []int) (int, error) {
+	if _, ok := predefinedFuncs[name]; ok {
+		return execFunc(name, params)
+	}
+	f, ok := in.registered(name)
+	if !ok {
+		return 0, errors.New("execFunc: function '" + name + "' not detected")
+	}
+	if f.arity != len(params) {
+		return 0, errors.New("execFunc: function '" + name + "' expects " + strconv.Itoa(f.arity) + " parameter(s)")
+	}
+
+	result, err := f.fn(append([]int{}, params...)) //the function can't modify the values of the program
+	if err != nil {
+		return 0, errors.New("execFunc: function '" + name + "' failed: " + err.Error())
+	}
+	if result < 0 {
+		return 0, errors.New("execFunc: function '" + name + "' returned '" + strconv.Itoa(result) + "', which is not a natural number")
+	}
+	return result, nil
+}
+
+// ParseCode parses the code of a program that can call the functions of the interpreter and checks it statically
+// return *Program, error
+func (in *Interpreter) ParseCode(code string) (*Program, error) {
+	return in.parseProgramCode("", code)
+}
+
+// ParseReader parses the code read from r, like ParseCode, showing the name of the source on the positions
+// return *Program, error
+func (in *Interpreter) ParseReader(name string, r io.Reader) (*Program, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.New("ParseReader: " + err.Error())
+	}
+	return in.parseProgramCode(name, string(content))
+}
+
+// ParseFile parses the code of a file, like ParseCode, showing the path on the positions
+// return *Program, error
+func (in *Interpreter) ParseFile(path string) (*Program, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.New("ParseFile: " + err.Error())
+	}
+	defer f.Close()
+
+	return in.ParseReader(path, f)
+}
+
+// CheckCode checks statically a code that can call the functions of the interpreter, without executing it
+// return CheckErrors
+func (in *Interpreter) CheckCode(code string) CheckErrors {
+	p := in.newProgram()
+	if err := p.getStmts(code); err != nil { //a syntax error is returned as the only error found
+		if ce, ok := err.(*CheckError); ok {
+			return CheckErrors{ce}
+		}
+		return CheckErrors{&CheckError{Pos: newPosition("", code, 0), Msg: err.Error()}}
+	}
+	return p.check(code)
+}
+
+// newProgram initializes a program that can call the functions of the interpreter
+// return *program
+func (in *Interpreter) newProgram() *program {
+	p := initProgram()
+	p.funcs = in
+	p.accelerate = in.accelerates()
+	return p
+}
@@ -0,0 +1,145 @@
+package whileinterp
+
+import (
+    "errors"
+    "testing"
+)
+
+// newTestInterpreter returns an interpreter with the functions max(a, b) and fail(a) registered
+// return *Interpreter
+func newTestInterpreter(t *testing.T) *Interpreter {
+    in := NewInterpreter()
+    err := in.RegisterFunc("max", 2, func(args []int) (int, error) {
+        if args[0] > args[1] {
+            return args[0], nil
+        }
+        return args[1], nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = in.RegisterFunc("fail", 1, func(args []int) (int, error) {
+        if args[0] > 0 {
+            return 0, errors.New("value too big")
+        }
+        return -1, nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    return in
+}
+
+/*********************** TESTING ***********************/
+func TestRegisterFunc(t *testing.T) {
+    in := newTestInterpreter(t)
+    prog, err := in.ParseCode("a := 3; b := max(a, inc(5)); WHILE(max(a, 1) < b) DO a = inc(a) OD")
+    if err != nil {
+        t.Fatal(err)
+    }
+    e := prog.NewExecution(0)
+    if err := e.Run(); err != nil {
+        t.Fatal(err)
+    }
+    if a, _ := e.Var("a"); a != 6 {
+        t.Error("expected a = 6, returned: ", a)
+    }
+
+    f, err := in.ParseFunction("x0 = max(x1, x2)", 2)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if result, err := f.Compute(0, 7, 4); err != nil || result != 7 {
+        t.Error("expected 7, returned: ", result, " ", err)
+    }
+}
+
+func TestRegisterFuncConflicts(t *testing.T) {
+    in := newTestInterpreter(t)
+    fn := func(args []int) (int, error) { return 0, nil }
+    tests := []struct {
+        name string
+        arity int
+        fn Func
+        expected string
+    }{
+        {"inc", 1, fn, "RegisterFunc: function 'inc' already defined as a predefined function"},
+        {"max", 1, fn, "RegisterFunc: function 'max' already registered"},
+        {"WHILE", 1, fn, "RegisterFunc: name 'WHILE' is not valid"},
+        {"2x", 1, fn, "RegisterFunc: name '2x' is not valid"},
+        {"min", -1, fn, "RegisterFunc: function 'min' can't have -1 parameter(s)"},
+        {"min", 2, nil, "RegisterFunc: function 'min' is nil"},
+    }
+    for _, test := range tests {
+        err := in.RegisterFunc(test.name, test.arity, test.fn)
+        if err == nil || err.Error() != test.expected {
+            t.Error("unexpected error registering '", test.name, "'\n returned: ", err, "\n expected: ", test.expected)
+        }
+    }
+
+    errs := in.CheckCode("PROC max(a, b) DO RETURN a OD; x := max(1)")
+    expected := []string{"1:1: function 'max' already defined as a registered function", "1:37: function 'max' expects 2 parameter(s), found 1"}
+    if len(errs) != len(expected) {
+        t.Fatal("unexpected errors: ", errs)
+    }
+    for i, err := range errs {
+        if err.Error() != expected[i] {
+            t.Error("unexpected error\n returned: ", err, "\n expected: ", expected[i])
+        }
+    }
+}
+
+func TestRegisterFuncScoped(t *testing.T) {
+    newTestInterpreter(t)
+    if _, err := ParseCode("x := max(1, 2)"); err == nil {
+        t.Error("expected the registered function unknown by the package functions")
+    }
+    if _, err := NewInterpreter().ParseCode("x := max(1, 2)"); err == nil {
+        t.Error("expected the registered function unknown by another interpreter")
+    }
+}
+
+func TestRegisterFuncErrors(t *testing.T) {
+    in := newTestInterpreter(t)
+    codes := map[string]string{
+        "x := fail(1)": "execFunc: function 'fail' failed: value too big",
+        "x := fail(0)": "execFunc: function 'fail' returned '-1', which is not a natural number",
+    }
+    for code, expected := range codes {
+        prog, err := in.ParseCode(code)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if err := prog.Exec(false); err == nil || err.Error() != expected {
+            t.Error("unexpected error executing '", code, "'\n returned: ", err, "\n expected: ", expected)
+        }
+    }
+}
+
+func TestRegisterFuncResume(t *testing.T) {
+    in := newTestInterpreter(t)
+    prog, _ := in.ParseCode("a := 0; WHILE(a < 3) DO a = inc(a) OD; b := max(a, 1)")
+    e := prog.NewExecution(0)
+    e.Pause()
+    e.Run()
+    snap, _ := e.Snapshot()
+
+    resumed, err := in.Resume(snap)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := resumed.Run(); err != nil {
+        t.Fatal(err)
+    }
+    if b, _ := resumed.Var("b"); b != 3 {
+        t.Error("expected b = 3, returned: ", b)
+    }
+
+    optimized, err := prog.Optimize(OptPasses[:]...)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := optimized.Exec(false); err != nil {
+        t.Error(err)
+    }
+}
@@ -0,0 +1,161 @@
+package whileinterp
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+)
+
+// resultVarSTRING defines the variable that saves the result of a function (x0)
+const resultVarSTRING = "x0"
+
+// inputVarPrefix defines the prefix of the variables that save the inputs of a function (x1..xk)
+const inputVarPrefix = "x"
+
+// initFunction initializes a program that computes a function: x0 is zero and x1..xk save the inputs
+// return *program, error
+func initFunction(inputs []int) (*program, error) {
+	p := initProgram()
+	p.addVar(&variable{name: resultVarSTRING, value: 0})
+
+	for i, in := range inputs {
+		if in < 0 {
+			return nil, errors.New("initFunction: input '" + strconv.Itoa(in) + "' is not a natural number")
+		}
+		p.addVar(&variable{name: inputVarPrefix + strconv.Itoa(i + 1), value: in})
+	}
+	return p, nil
+}
+
+// Function is a program parsed to compute a function f(n1..nk) with the textbook convention,
+// which can be computed many times (also concurrently) with different inputs
+type Function struct {
+	name string //name of the source of the function, shown on the positions (empty if the code has no name)
+	code string //source code of the function
+	stmts []stmt //statements of the function (never modified by the computations)
+	arity int //number of inputs (saved on x1..xk)
+	funcs *Interpreter //interpreter that parsed the function, whose registered functions can be called
+}
+
+// ParseFunction parses and checks the code of a function with the given number of inputs:
+// x1..xk and x0 are declared, any other variable must be declared by the code
+// return *Function, error
+func ParseFunction(code string, arity int) (*Function, error) {
+	return defaultInterpreter.ParseFunction(code, arity)
+}
+
+// ParseFunctionReader parses and checks the code of a function read from r, like ParseFunction.
+// The name of the source (e.g. the file) is shown on the positions, and the files imported are relative to it
+// return *Function, error
+func ParseFunctionReader(name string, r io.Reader, arity int) (*Function, error) {
+	return defaultInterpreter.ParseFunctionReader(name, r, arity)
+}
+
+// ParseFunction parses and checks the code of a function, like ParseFunction, which can call the functions
+// of the interpreter
+// return *Function, error
+func (in *Interpreter) ParseFunction(code string, arity int) (*Function, error) {
+	return in.parseFunctionCode("", code, arity)
+}
+
+// ParseFunctionReader parses and checks the code of a function read from r, like ParseFunctionReader,
+// which can call the functions of the interpreter
+// return *Function, error
+func (in *Interpreter) ParseFunctionReader(name string, r io.Reader, arity int) (*Function, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.New("ParseFunctionReader: " + err.Error())
+	}
+	return in.parseFunctionCode(name, string(content), arity)
+}
+
+// parseFunctionCode parses the code of a named source as a function and checks it statically
+// return *Function, error
+func (in *Interpreter) parseFunctionCode(name string, code string, arity int) (*Function, error) {
+	p, err := initFunction(make([]int, arity))
+	if err != nil {
+		return nil, err
+	}
+	p.funcs = in
+	p.name = name
+
+	if err := p.getStmts(code); err != nil {
+		return nil, err
+	}
+	if errs := p.check(code); len(errs) > 0 { //x0..xk are already declared on the check
+		return nil, errs
+	}
+	return &Function{name: name, code: code, stmts: p.stmts, arity: arity, funcs: in}, nil
+}
+
+// Arity returns the number of inputs of the function
+// return int
+func (f *Function) Arity() int {
+	return f.arity
+}
+
+// Compute computes the function with the given inputs. maxSteps limits the statements to execute (0 for no limit)
+// and, if exhausted, ErrUndefined is returned: the function is undefined (it may diverge) for the inputs.
+// return int, error
+func (f *Function) Compute(maxSteps int, inputs ...int) (int, error) {
+	result, _, err := f.compute(maxSteps, inputs)
+	return result, err
+}
+
+// compute computes the function with the given inputs on a new program
+// return int (value of x0), int (statements executed), error
+func (f *Function) compute(maxSteps int, inputs []int) (int, int, error) {
+	if len(inputs) != f.arity {
+		return 0, 0, errors.New("Compute: function expects " + strconv.Itoa(f.arity) + " input(s), " + strconv.Itoa(len(inputs)) + " given")
+	}
+	p, err := initFunction(inputs)
+	if err != nil {
+		return 0, 0, err
+	}
+	p.maxSteps = maxSteps
+	p.stmts = f.stmts
+	p.name = f.name
+	p.code = f.code
+	p.funcs = f.funcs
+	p.accelerate = f.funcs.accelerates()
+
+	if err := p.parseProgram(); err != nil {
+		return 0, p.steps, err
+	}
+	result, err := p.getVar(resultVarSTRING)
+	return result.value, p.steps, err
+}
+
+// env returns the environment of a computation of the function: x0 is zero and x1..xk save the inputs
+// return map[string]int, error
+func (f *Function) env(inputs []int) (map[string]int, error) {
+	if len(inputs) != f.arity {
+		return nil, errors.New("Compute: function expects " + strconv.Itoa(f.arity) + " input(s), " + strconv.Itoa(len(inputs)) + " given")
+	}
+	if _, err := initFunction(inputs); err != nil {
+		return nil, err
+	}
+	env := map[string]int{resultVarSTRING: 0}
+	for i, in := range inputs {
+		env[inputVarPrefix + strconv.Itoa(i + 1)] = in
+	}
+	return env, nil
+}
+
+// ComputeCode executes the code as a function f(n1..nk), following the textbook convention:
+// x1..xk are declared with the inputs, x0 is declared with 0 and its final value is the result.
+// Any other variable must be declared by the code. maxSteps limits the statements to execute (0 for no limit)
+// and, if exhausted, ErrUndefined is returned: the function is undefined (it may diverge) for the inputs.
+// return int, error
+func ComputeCode(code string, maxSteps int, inputs ...int) (int, error) {
+	if _, err := initFunction(inputs); err != nil { //the inputs are checked before the code
+		return 0, err
+	}
+
+	f, err := ParseFunction(code, len(inputs))
+	if err != nil {
+		return 0, err
+	}
+	return f.Compute(maxSteps, inputs...)
+}
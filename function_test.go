@@ -0,0 +1,74 @@
+package whileinterp
+
+import "testing"
+
+const testFuncIdentity = "WHILE(x0 != x1) DO x0 = inc(x0) OD;"
+const testFuncDiverge = "WHILE(x0 < x1) DO x1 = inc(x1) OD;"
+
+/*********************** TESTING ***********************/
+func TestComputeCodeIdentity(t *testing.T) {
+    for _, in := range []int{0, 1, 5} {
+        retVal, err := ComputeCode(testFuncIdentity, 1000, in)
+        if err != nil {
+            t.Error(err)
+            return
+        }
+        if retVal != in {
+            t.Error("unexpected returned value:\n returned: ", retVal, "\n expected: ", in)
+        }
+    }
+}
+
+func TestComputeCodeConstant(t *testing.T) {
+    code := "x0 = inc(x2);"
+    expecVal := 4
+
+    retVal, err := ComputeCode(code, 0, 7, 3)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    if retVal != expecVal {
+        t.Error("unexpected returned value:\n returned: ", retVal, "\n expected: ", expecVal)
+    }
+}
+
+func TestComputeCodeUndefined(t *testing.T) {
+    if _, err := ComputeCode(testFuncDiverge, 1000, 1); err != ErrUndefined {
+        t.Error("unexpected returned error:\n returned: ", err, "\n expected: ", ErrUndefined)
+    }
+    if retVal, err := ComputeCode(testFuncDiverge, 1000, 0); err != nil || retVal != 0 {
+        t.Error("unexpected returned value:\n returned: ", retVal, err, "\n expected: ", 0)
+    }
+}
+
+func TestComputeCodeNotNatural(t *testing.T) {
+    if _, err := ComputeCode(testFuncIdentity, 1000, -1); err == nil {
+        t.Error("expected error not returned")
+    }
+}
+
+func TestComputeCodeUndeclared(t *testing.T) {
+    if _, err := ComputeCode("x0 = val(x2);", 1000, 1); err == nil {
+        t.Error("expected error not returned")
+    }
+}
+
+func TestParseFunction(t *testing.T) {
+    f, err := ParseFunction(testFuncIdentity, 1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if f.Arity() != 1 {
+        t.Error("unexpected returned arity: ", f.Arity())
+    }
+    for in := 0; in < 5; in++ {
+        if retVal, err := f.Compute(1000, in); err != nil || retVal != in {
+            t.Error("unexpected returned value: ", retVal, " ", err)
+        }
+    }
+
+    if _, err := ParseFunction("x0 = val(x2);", 1); err == nil {
+        t.Error("expected error not returned")
+    }
+}
@@ -0,0 +1,608 @@
+package whileinterp
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+/*
+    Gödel numbering of the while programs: every program is encoded to a natural number, and every natural
+    number is decoded to a program. The encoding is built with the Cantor pairing function <a, b>:
+
+        program   = []                             -> 0
+                    s : rest                       -> <item(s), program(rest)> + 1
+        item      = stmt                           -> 2 * stmt(stmt)
+                    PROC f(params) DO body RETURN o OD
+                                                   -> 2 * <f, <vars(params), <stmts(body), operand(o)>>> + 1
+        stmts     = []                             -> 0
+                    s : rest                       -> <stmt(s), stmts(rest)> + 1
+        stmt      = v := value                     -> 6 * <v, value(value)>
+                    v = value                      -> 6 * <v, value(value)> + 1
+                    WHILE(cond) DO body OD         -> 6 * <cond(cond), stmts(body)> + 2
+                    v[o] = value                   -> 6 * <v, <operand(o), value(value)>> + 3
+                    PRINT o                        -> 6 * operand(o) + 4
+                    READ v                         -> 6 * v + 5
+        cond      = o op p                         -> 4 * (6 * <operand(o), operand(p)> + index of op in comparators)
+                    cond AND cond                  -> 4 * <cond, cond> + 1
+                    cond OR cond                   -> 4 * <cond, cond> + 2
+                    NOT cond                       -> 4 * cond + 3
+        value     = n                              -> 2 * n
+                    call                           -> 2 * call(call) + 1
+        operand   = n                              -> 4 * n
+                    v                              -> 4 * v + 1
+                    call                           -> 4 * call(call) + 2
+                    v[o]                           -> 4 * <v, operand(o)> + 3
+        call      = g(operands)                    -> <callee(g), operands(operands)>
+        callee    = zero, inc, dec, val, array     -> 0, 1, 2, 3, 4
+                    f                              -> 5 + f
+        vars      = []                             -> 0
+                    v : rest                       -> <v, vars(rest)> + 1
+        operands  = []                             -> 0
+                    o : rest                       -> <operand(o), operands(rest)> + 1
+
+    The variables are encoded by their index (x0 -> 0, x1 -> 1, ...), and so are the functions (f0 -> 0,
+    f1 -> 1, ...). Any name not like that is numbered after the highest index, in order of appearance,
+    so it is decoded with a new name. An IMPORT is encoded as the functions of its library, so it is decoded
+    as functions defined on the program.
+*/
+
+// godelCallees lists the functions that are not user functions in the order of their encoding
+var godelCallees = [...]string{"zero", "inc", "dec", "val", arraySTRING}
+
+// godelLogicOps lists the logic operators in the order of their encoding (after the comparisons)
+var godelLogicOps = [...]string{andOPSTRING, orOPSTRING, notOPSTRING}
+
+// varPrefix defines the prefix of the variables' names when they are decoded
+const varPrefix = "x"
+
+// funcPrefix defines the prefix of the functions' names when they are decoded
+const funcPrefix = "f"
+
+// pair returns the Cantor pairing of two natural numbers: (a + b) * (a + b + 1) / 2 + b
+// return *big.Int
+func pair(a, b *big.Int) *big.Int {
+	sum := new(big.Int).Add(a, b)
+	n := new(big.Int).Mul(sum, new(big.Int).Add(sum, big.NewInt(1)))
+	n.Rsh(n, 1)
+
+	return n.Add(n, b)
+}
+
+// unpair returns the two natural numbers whose Cantor pairing is n
+// return *big.Int, *big.Int
+func unpair(n *big.Int) (*big.Int, *big.Int) {
+	w := new(big.Int).Lsh(n, 3) //w = (sqrt(8n + 1) - 1) / 2
+	w.Add(w, big.NewInt(1))
+	w.Sqrt(w)
+	w.Sub(w, big.NewInt(1))
+	w.Rsh(w, 1)
+
+	t := new(big.Int).Mul(w, new(big.Int).Add(w, big.NewInt(1)))
+	t.Rsh(t, 1)
+
+	b := new(big.Int).Sub(n, t)
+	return new(big.Int).Sub(w, b), b
+}
+
+// tag returns k * n + t, used to encode the different alternatives of a syntax element
+// return *big.Int
+func tag(n *big.Int, k, t int64) *big.Int {
+	result := new(big.Int).Mul(n, big.NewInt(k))
+	return result.Add(result, big.NewInt(t))
+}
+
+// untag returns the alternative t and the number n encoded as k * n + t
+// return *big.Int, int64
+func untag(n *big.Int, k int64) (*big.Int, int64) {
+	q, t := new(big.Int).DivMod(n, big.NewInt(k), new(big.Int))
+	return q, t.Int64()
+}
+
+// numbering numbers the names of the variables or the functions of a program
+type numbering struct {
+	prefix string //prefix of the names that keep their index ("x" for "x0", "x1", ...)
+	index map[string]*big.Int //index of every name
+	next *big.Int //next index for the names that don't keep their index
+}
+
+// newNumbering returns a numbering of the given names (without repetitions), where the names
+// like prefix + index keep their index and the others are numbered after them, in the given order
+// return *numbering
+func newNumbering(prefix string, names []string) *numbering {
+	n := &numbering{prefix: prefix, index: map[string]*big.Int{}, next: big.NewInt(0)}
+	for _, name := range names {
+		if i, ok := n.nameIndex(name); ok {
+			n.index[name] = i
+			if i.Cmp(n.next) >= 0 {
+				n.next = new(big.Int).Add(i, big.NewInt(1))
+			}
+		}
+	}
+	for _, name := range names {
+		if _, ok := n.index[name]; !ok {
+			n.index[name] = n.next
+			n.next = new(big.Int).Add(n.next, big.NewInt(1))
+		}
+	}
+	return n
+}
+
+// nameIndex returns the index of a name like prefix + index
+// return *big.Int, bool (false if the name is not like that)
+func (n *numbering) nameIndex(name string) (*big.Int, bool) {
+	if !strings.HasPrefix(name, n.prefix) {
+		return nil, false
+	}
+	i, ok := new(big.Int).SetString(name[len(n.prefix):], 10)
+	if !ok || i.Sign() < 0 || n.prefix + i.String() != name { //e.g. "x01" is not "x1"
+		return nil, false
+	}
+	return i, true
+}
+
+// addName saves on names a name, if it is not saved yet
+func addName(names *[]string, name string) {
+	for _, n := range *names {
+		if n == name {
+			return
+		}
+	}
+	*names = append(*names, name)
+}
+
+// getNames saves on vars and funcs the variables and the user functions of a list of statements, in order of appearance
+func getNames(stmts []stmt, vars, funcs *[]string) {
+	for _, s := range stmts {
+		switch s.op {
+		case whileFuncSTRING:
+			getLogicExprVars(s.cond, vars)
+			getLogicExprFuncs(s.cond, funcs)
+		case procSTRING:
+			addName(funcs, s.name)
+			for _, param := range s.params {
+				addName(vars, param)
+			}
+		case importSTRING:
+			continue
+		case printSTRING:
+		default:
+			addName(vars, s.name)
+		}
+		if s.index != nil {
+			getValueVars(s.index, vars)
+			getValueFuncs(s.index, funcs)
+		}
+		if s.value != nil {
+			getValueVars(s.value, vars)
+			getValueFuncs(s.value, funcs)
+		}
+		getNames(s.body, vars, funcs)
+	}
+}
+
+// getLogicExprFuncs saves on funcs the user functions called on a logic expression
+func getLogicExprFuncs(l *logicExpr, funcs *[]string) {
+	if l.left != nil {
+		getLogicExprFuncs(l.left, funcs)
+		if l.right != nil {
+			getLogicExprFuncs(l.right, funcs)
+		}
+		return
+	}
+	getValueFuncs(l.first, funcs)
+	getValueFuncs(l.second, funcs)
+}
+
+// getValueFuncs saves on funcs the user functions called on a value
+func getValueFuncs(v *valueExpr, funcs *[]string) {
+	if v.kind != callValue && v.kind != indexValue {
+		return
+	}
+	if v.kind == callValue && funcParams(v.text) == -1 && v.text != arraySTRING {
+		addName(funcs, v.text)
+	}
+	for _, param := range v.params {
+		getValueFuncs(param, funcs)
+	}
+}
+
+// encoder encodes the statements of a program, numbering its variables and its functions
+type encoder struct {
+	vars *numbering //index of every variable
+	funcs *numbering //index of every user function
+}
+
+// encodeList encodes a list of numbers already encoded
+// return *big.Int
+func encodeList(items []*big.Int) *big.Int {
+	result := big.NewInt(0)
+	for i := len(items) - 1; i >= 0; i-- {
+		result = pair(items[i], result)
+		result.Add(result, big.NewInt(1))
+	}
+	return result
+}
+
+// encodeProgram encodes the statements and the functions defined on the top level of a program
+// return *big.Int
+func (e *encoder) encodeProgram(stmts []stmt) *big.Int {
+	items := make([]*big.Int, len(stmts))
+	for i, s := range stmts {
+		if s.op != procSTRING {
+			items[i] = tag(e.encodeStmt(s), 2, 0)
+			continue
+		}
+
+		params := make([]*big.Int, len(s.params))
+		for j, param := range s.params {
+			params[j] = e.vars.index[param]
+		}
+		proc := pair(encodeList(params), pair(e.encodeStmts(s.body), e.encodeOperand(s.value)))
+		items[i] = tag(pair(e.funcs.index[s.name], proc), 2, 1)
+	}
+	return encodeList(items)
+}
+
+// encodeStmts encodes a list of statements
+// return *big.Int
+func (e *encoder) encodeStmts(stmts []stmt) *big.Int {
+	items := make([]*big.Int, len(stmts))
+	for i, s := range stmts {
+		items[i] = e.encodeStmt(s)
+	}
+	return encodeList(items)
+}
+
+// encodeStmt encodes a statement
+// return *big.Int
+func (e *encoder) encodeStmt(s stmt) *big.Int {
+	switch s.op {
+	case declareOPSTRING:
+		return tag(pair(e.vars.index[s.name], e.encodeValue(s.value)), 6, 0)
+	case assignOPSTRING:
+		if s.index != nil {
+			return tag(pair(e.vars.index[s.name], pair(e.encodeOperand(s.index), e.encodeValue(s.value))), 6, 3)
+		}
+		return tag(pair(e.vars.index[s.name], e.encodeValue(s.value)), 6, 1)
+	case printSTRING:
+		return tag(e.encodeOperand(s.value), 6, 4)
+	case readSTRING:
+		return tag(e.vars.index[s.name], 6, 5)
+	default:
+		return tag(pair(e.encodeLogicExpr(s.cond), e.encodeStmts(s.body)), 6, 2)
+	}
+}
+
+// encodeLogicExpr encodes a logic expression
+// return *big.Int
+func (e *encoder) encodeLogicExpr(l *logicExpr) *big.Int {
+	switch l.op {
+	case andOPSTRING:
+		return tag(pair(e.encodeLogicExpr(l.left), e.encodeLogicExpr(l.right)), 4, 1)
+	case orOPSTRING:
+		return tag(pair(e.encodeLogicExpr(l.left), e.encodeLogicExpr(l.right)), 4, 2)
+	case notOPSTRING:
+		return tag(e.encodeLogicExpr(l.left), 4, 3)
+	}
+
+	var op int64
+	for i, c := range comparators {
+		if c == l.op {
+			op = int64(i)
+		}
+	}
+	return tag(tag(pair(e.encodeOperand(l.first), e.encodeOperand(l.second)), int64(len(comparators)), op), 4, 0)
+}
+
+// encodeValue encodes the value of a declaration or an assignment
+// return *big.Int
+func (e *encoder) encodeValue(v *valueExpr) *big.Int {
+	if v.kind == numberValue {
+		n, _ := new(big.Int).SetString(v.text, 10)
+		return tag(n, 2, 0)
+	}
+	return tag(e.encodeCall(v), 2, 1)
+}
+
+// encodeOperand encodes a number, a variable, an element of an array or a function call used as a parameter
+// or on a comparison
+// return *big.Int
+func (e *encoder) encodeOperand(v *valueExpr) *big.Int {
+	switch v.kind {
+	case numberValue:
+		n, _ := new(big.Int).SetString(v.text, 10)
+		return tag(n, 4, 0)
+	case varValue:
+		return tag(e.vars.index[v.text], 4, 1)
+	case indexValue:
+		return tag(pair(e.vars.index[v.text], e.encodeOperand(v.params[0])), 4, 3)
+	default:
+		return tag(e.encodeCall(v), 4, 2)
+	}
+}
+
+// encodeCall encodes a function call and its parameters
+// return *big.Int
+func (e *encoder) encodeCall(v *valueExpr) *big.Int {
+	callee := big.NewInt(-1)
+	for i, f := range godelCallees {
+		if f == v.text {
+			callee.SetInt64(int64(i))
+		}
+	}
+	if callee.Sign() < 0 {
+		callee.Add(e.funcs.index[v.text], big.NewInt(int64(len(godelCallees))))
+	}
+
+	params := make([]*big.Int, len(v.params))
+	for i, param := range v.params {
+		params[i] = e.encodeOperand(param)
+	}
+	return pair(callee, encodeList(params))
+}
+
+// Encode returns the Gödel number of a program
+// (the functions of the imported libraries are encoded as functions defined on the program)
+// return *big.Int
+func Encode(prog *Program) *big.Int {
+	stmts := expandImports(&program{stmts: prog.stmts, funcs: prog.funcs})
+	vars, funcs := []string{}, []string{}
+	getNames(stmts, &vars, &funcs)
+
+	e := &encoder{vars: newNumbering(varPrefix, vars), funcs: newNumbering(funcPrefix, funcs)}
+	return e.encodeProgram(stmts)
+}
+
+// expandImports returns the statements of a program with its imports replaced by the functions of the libraries
+// (at the start of the program), named as the program calls them (e.g. math.add)
+// return []stmt
+func expandImports(p *program) []stmt {
+	libs, _ := p.libraries()
+	stmts := expandLibraries(libs, "")
+	for _, s := range p.stmts {
+		if s.op != importSTRING {
+			stmts = append(stmts, s)
+		}
+	}
+	return stmts
+}
+
+// expandLibraries returns the functions of a list of libraries and of the libraries they import, named with
+// a prefix and the namespace of their library (a library imported twice is expanded once)
+// return []stmt
+func expandLibraries(libs []*library, prefix string) []stmt {
+	stmts := []stmt{}
+	expanded := map[string]bool{}
+	for _, lib := range libs {
+		if expanded[lib.ns] {
+			continue
+		}
+		expanded[lib.ns] = true
+
+		imported := []*library{}
+		for _, s := range lib.stmts {
+			if s.op == importSTRING {
+				imported = append(imported, s.lib)
+				continue
+			}
+			s.name = prefix + lib.qualify(s.name)
+			s.body = renameStmts(s.body, lib.procs, prefix + lib.qualify(""))
+			s.value = renameValue(s.value, lib.procs, prefix + lib.qualify(""))
+			stmts = append(stmts, s)
+		}
+		stmts = append(stmts, expandLibraries(imported, prefix + lib.qualify(""))...)
+	}
+	return stmts
+}
+
+// renameStmts returns a copy of a list of statements where the calls of the given functions are named with a prefix
+// return []stmt
+func renameStmts(stmts []stmt, procs map[string]*stmt, prefix string) []stmt {
+	result := make([]stmt, len(stmts))
+	for i, s := range stmts {
+		if s.cond != nil {
+			s.cond = renameLogicExpr(s.cond, procs, prefix)
+		}
+		if s.index != nil {
+			s.index = renameValue(s.index, procs, prefix)
+		}
+		if s.value != nil {
+			s.value = renameValue(s.value, procs, prefix)
+		}
+		s.body = renameStmts(s.body, procs, prefix)
+		result[i] = s
+	}
+	return result
+}
+
+// renameLogicExpr returns a copy of a logic expression where the calls of the given functions are named with a prefix
+// return *logicExpr
+func renameLogicExpr(l *logicExpr, procs map[string]*stmt, prefix string) *logicExpr {
+	result := *l
+	if l.left != nil {
+		result.left = renameLogicExpr(l.left, procs, prefix)
+		if l.right != nil {
+			result.right = renameLogicExpr(l.right, procs, prefix)
+		}
+		return &result
+	}
+	result.first = renameValue(l.first, procs, prefix)
+	result.second = renameValue(l.second, procs, prefix)
+	return &result
+}
+
+// renameValue returns a copy of a value where the calls of the given functions are named with a prefix
+// return *valueExpr
+func renameValue(v *valueExpr, procs map[string]*stmt, prefix string) *valueExpr {
+	result := *v
+	if _, ok := procs[v.text]; ok && v.kind == callValue {
+		result.text = prefix + v.text
+	}
+	result.params = make([]*valueExpr, len(v.params))
+	for i, param := range v.params {
+		result.params[i] = renameValue(param, procs, prefix)
+	}
+	return &result
+}
+
+// decodeList decodes a list of numbers, which are still encoded
+// return []*big.Int
+func decodeList(n *big.Int) []*big.Int {
+	items := []*big.Int{}
+	n = new(big.Int).Set(n)
+	for n.Sign() > 0 {
+		var item *big.Int
+		item, n = unpair(new(big.Int).Sub(n, big.NewInt(1)))
+		items = append(items, item)
+	}
+	return items
+}
+
+// decodeProgram decodes the statements and the functions defined on the top level of a program
+// return []stmt
+func decodeProgram(n *big.Int) []stmt {
+	stmts := []stmt{}
+	for _, item := range decodeList(n) {
+		item, kind := untag(item, 2)
+		if kind == 0 {
+			stmts = append(stmts, decodeStmt(item))
+			continue
+		}
+
+		name, proc := unpair(item)
+		params, rest := unpair(proc)
+		body, value := unpair(rest)
+		s := stmt{op: procSTRING, name: decodeFunc(name), params: []string{}, body: decodeStmts(body), value: decodeOperand(value)}
+		for _, param := range decodeList(params) {
+			s.params = append(s.params, decodeVar(param))
+		}
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+// decodeStmts decodes a list of statements
+// return []stmt
+func decodeStmts(n *big.Int) []stmt {
+	stmts := []stmt{}
+	for _, item := range decodeList(n) {
+		stmts = append(stmts, decodeStmt(item))
+	}
+	return stmts
+}
+
+// decodeStmt decodes a statement
+// return stmt
+func decodeStmt(n *big.Int) stmt {
+	n, kind := untag(n, 6)
+	switch kind {
+	case 4:
+		return stmt{op: printSTRING, value: decodeOperand(n)}
+	case 5:
+		return stmt{op: readSTRING, name: decodeVar(n)}
+	}
+
+	a, b := unpair(n)
+	switch kind {
+	case 0:
+		return stmt{op: declareOPSTRING, name: decodeVar(a), value: decodeValue(b)}
+	case 1:
+		return stmt{op: assignOPSTRING, name: decodeVar(a), value: decodeValue(b)}
+	case 3:
+		index, value := unpair(b)
+		return stmt{op: assignOPSTRING, name: decodeVar(a), index: decodeOperand(index), value: decodeValue(value)}
+	default:
+		return stmt{op: whileFuncSTRING, cond: decodeLogicExpr(a), body: decodeStmts(b)}
+	}
+}
+
+// decodeLogicExpr decodes a logic expression
+// return *logicExpr
+func decodeLogicExpr(n *big.Int) *logicExpr {
+	n, kind := untag(n, 4)
+	if kind == 3 {
+		return &logicExpr{op: notOPSTRING, left: decodeLogicExpr(n)}
+	}
+	if kind > 0 {
+		left, right := unpair(n)
+		return &logicExpr{op: godelLogicOps[kind - 1], left: decodeLogicExpr(left), right: decodeLogicExpr(right)}
+	}
+
+	n, op := untag(n, int64(len(comparators)))
+	first, second := unpair(n)
+	return &logicExpr{op: comparators[op], first: decodeOperand(first), second: decodeOperand(second)}
+}
+
+// decodeValue decodes the value of a declaration or an assignment
+// return *valueExpr
+func decodeValue(n *big.Int) *valueExpr {
+	n, kind := untag(n, 2)
+	if kind == 0 {
+		return &valueExpr{kind: numberValue, text: n.String()}
+	}
+	return decodeCall(n)
+}
+
+// decodeOperand decodes a number, a variable, an element of an array or a function call used as a parameter
+// or on a comparison
+// return *valueExpr
+func decodeOperand(n *big.Int) *valueExpr {
+	n, kind := untag(n, 4)
+	switch kind {
+	case 0:
+		return &valueExpr{kind: numberValue, text: n.String()}
+	case 1:
+		return &valueExpr{kind: varValue, text: decodeVar(n)}
+	case 3:
+		array, index := unpair(n)
+		return &valueExpr{kind: indexValue, text: decodeVar(array), params: []*valueExpr{decodeOperand(index)}}
+	default:
+		return decodeCall(n)
+	}
+}
+
+// decodeCall decodes a function call and its parameters
+// return *valueExpr
+func decodeCall(n *big.Int) *valueExpr {
+	callee, params := unpair(n)
+
+	call := &valueExpr{kind: callValue, params: []*valueExpr{}}
+	if builtins := big.NewInt(int64(len(godelCallees))); callee.Cmp(builtins) < 0 {
+		call.text = godelCallees[callee.Int64()]
+	} else {
+		call.text = decodeFunc(callee.Sub(callee, builtins))
+	}
+	for _, param := range decodeList(params) {
+		call.params = append(call.params, decodeOperand(param))
+	}
+	return call
+}
+
+// decodeVar decodes the name of a variable
+// return string
+func decodeVar(n *big.Int) string {
+	return varPrefix + n.String()
+}
+
+// decodeFunc decodes the name of a user function
+// return string
+func decodeFunc(n *big.Int) string {
+	return funcPrefix + n.String()
+}
+
+// Decode returns the program whose Gödel number is n
+// the program is not checked statically, as not every number encodes a valid program
+// return *Program, error
+func Decode(n *big.Int) (*Program, error) {
+	if n.Sign() < 0 {
+		return nil, errors.New("Decode: number '" + n.String() + "' is not a natural number")
+	}
+
+	code := formatStmts(decodeProgram(n))
+	p := initProgram()
+	if err := p.getStmts(code); err != nil { //the code is parsed to know the positions of the statements
+		return nil, err
+	}
+	return &Program{code: code, stmts: p.stmts}, nil
+}
@@ -0,0 +1,122 @@
+package whileinterp
+
+import (
+    "math/big"
+    "testing"
+    "testing/quick"
+)
+
+/*********************** TESTING ***********************/
+func TestPairUnpair(t *testing.T) {
+    f := func(a, b uint64) bool {
+        x, y := unpair(pair(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b)))
+        return x.Uint64() == a && y.Uint64() == b
+    }
+    if err := quick.Check(f, nil); err != nil {
+        t.Error(err)
+    }
+}
+
+func TestDecodeEncodeSmall(t *testing.T) {
+    for i := int64(0); i < 5000; i++ {
+        doTestDecodeEncode(big.NewInt(i), t)
+    }
+}
+
+func TestDecodeEncodeRandom(t *testing.T) {
+    f := func(a, b, c uint64) bool {
+        n := new(big.Int).SetUint64(a)
+        n.Lsh(n, 64).Add(n, new(big.Int).SetUint64(b))
+        n.Lsh(n, 64).Add(n, new(big.Int).SetUint64(c))
+        return doTestDecodeEncode(n, t)
+    }
+    if err := quick.Check(f, nil); err != nil {
+        t.Error(err)
+    }
+}
+
+func TestEncodeDecode(t *testing.T) {
+    codes := []string{
+        "",
+        "x0 := 2; x1 := inc(3); x2 := dec(2); WHILE(x0 != x1) DO x0 = inc(x0) OD;",
+        "x0 := zero(); x1 := 2; x2 := inc(x1); WHILE(x1 < x2) DO x2 = dec(x2) OD;",
+        "x3 := val(7); x1 := 1; x5 := 0; WHILE(x3 == x1) DO OD; WHILE(x1 > x3) DO x5 = val(x1) OD;",
+        "x0 := 1; x1 := 2; WHILE(x0 < x1 AND NOT (x1 == x0 OR x0 >= x1)) DO x0 = inc(x0); WHILE(x1 <= x0) DO x1 = inc(x1) OD OD;",
+        "x0 := 1; x1 := 2; WHILE((x0 < x1 OR x1 < x0) AND (x1 != x0 AND x0 > x1) OR NOT NOT x0 == x1) DO x0 = inc(x0) OD;",
+        "x0 := inc(dec(val(4))); WHILE(inc(x0) < 10 AND 3 != x0) DO x0 = inc(inc(x0)) OD;",
+        "PROC f0(x0, x1) DO WHILE(x1 > 0) DO x0 = inc(x0); x1 = dec(x1) OD RETURN x0 OD; x2 := f0(2, f1(3)); PROC f1(x0) DO RETURN 7 OD;",
+        "PROC f2() DO RETURN inc(f2()) OD; x0 := f2();",
+        "x0 := array(3); x1 := 2; x0[x1] = inc(x0[dec(x1)]); WHILE(x0[0] < x1) DO x0[0] = inc(x0[0]) OD;",
+    }
+
+    for _, code := range codes {
+        p, err := ParseCode(code)
+        if err != nil {
+            t.Error(err)
+            continue
+        }
+        retProg, err := Decode(Encode(p))
+        if err != nil {
+            t.Error(err)
+            continue
+        }
+        if retProg.String() != code {
+            t.Error("unexpected returned program:\n returned: ", retProg, "\n expected: ", code)
+        }
+    }
+}
+
+func TestEncodeRenamesFuncs(t *testing.T) {
+    p, err := ParseCode("PROC add(a, b) DO RETURN a OD; PROC f0(a) DO RETURN add(a, 1) OD; xo := f0(2);")
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    expecCode := "PROC f1(x0, x1) DO RETURN x0 OD; PROC f0(x0) DO RETURN f1(x0, 1) OD; x2 := f0(2);"
+
+    retProg, err := Decode(Encode(p))
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    if retProg.String() != expecCode {
+        t.Error("unexpected returned program:\n returned: ", retProg, "\n expected: ", expecCode)
+    }
+}
+
+func TestEncodeRenamesVars(t *testing.T) {
+    p, err := ParseCode(testCode1)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    expecCode := "x3 := 2; x1 := inc(3); x2 := dec(2); WHILE(x3 != x1) DO x3 = inc(x3) OD;"
+
+    retProg, err := Decode(Encode(p))
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    if retProg.String() != expecCode {
+        t.Error("unexpected returned program:\n returned: ", retProg, "\n expected: ", expecCode)
+    }
+}
+
+func TestDecodeNegative(t *testing.T) {
+    if _, err := Decode(big.NewInt(-1)); err == nil {
+        t.Error("expected error not returned")
+    }
+}
+
+func doTestDecodeEncode(n *big.Int, t *testing.T) bool {
+    p, err := Decode(n)
+    if err != nil {
+        t.Error(err)
+        return false
+    }
+    if retNum := Encode(p); retNum.Cmp(n) != 0 {
+        t.Error("unexpected returned number:\n returned: ", retNum, "\n expected: ", n, "\n program: ", p)
+        return false
+    }
+    return true
+}
@@ -0,0 +1,292 @@
+package whileinterp
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/*
+    Execution histories for reverse debugging: an execution is recorded once, counting its moments (every
+    statement reached, also inside the functions, and every RETURN), and a checkpoint (a Snapshot) is saved
+    every given number of steps between the statements of the main program. Any earlier moment is inspected
+    by resuming the closest checkpoint before it and executing it again until the moment, so the memory
+    used is bounded by the checkpoints instead of the statements executed. The values read by the recorded
+    execution are kept, and read again by the replays, which don't write the PRINTs again.
+*/
+
+// HistoryDefaultInterval defines the steps between two checkpoints if the recording doesn't give them
+const HistoryDefaultInterval = 1000
+
+// errReplayStopped is returned by a replayed execution when it reaches the moment looked for
+var errReplayStopped = errors.New("replay: execution stopped")
+
+// HistoryState is the state of a recorded execution at a moment: the statement about to be executed and
+// the variables of its frame (the main program or the function being executed)
+type HistoryState struct {
+	Moment int //number of statements reached before
+	Steps int //statements executed before
+	Pos Position //position of the next statement (zero if the execution has ended)
+	Stmt string //code of the next statement (empty if the execution has ended)
+	Proc string //function being executed (empty for the main program)
+	Depth int //nested function calls being executed (0 on the main program)
+	Vars []SnapshotVar //variables of the frame, in their order of declaration
+	End bool //the execution has ended (finished or stopped by an error)
+}
+
+// String returns the state as "moment 3 (step 4) 1:20: x = inc(x) {x: 1, y: 2, a: [0, 1]}"
+// return string
+func (hs *HistoryState) String() string {
+	vars := make([]string, len(hs.Vars))
+	for i, v := range hs.Vars {
+		vars[i] = v.Name + ": " + v.String()
+	}
+	head := "moment " + strconv.Itoa(hs.Moment) + " (step " + strconv.Itoa(hs.Steps) + ") "
+	switch {
+		case hs.End:
+			head += "end"
+		case hs.Proc != "":
+			head += hs.Pos.String() + ": " + hs.Proc + ": " + hs.Stmt
+		default:
+			head += hs.Pos.String() + ": " + hs.Stmt
+	}
+	return head + " {" + strings.Join(vars, ", ") + "}"
+}
+
+// historyCheckpoint is a snapshot of a recorded execution and the moment where it was taken
+type historyCheckpoint struct {
+	snap *Snapshot //state of the execution
+	moment int //moments before the snapshot
+	reads int //values read before the snapshot
+}
+
+// History is a recorded execution, whose earlier moments can be inspected (e.g. stepping backwards)
+type History struct {
+	name string //name of the source of the code
+	code string //source code of the program
+	checkpoints []historyCheckpoint //checkpoints of the execution, by moment
+	moments int //moments of the whole execution (the last one is its end)
+	end *HistoryState //state when the execution has ended
+	err error //error that stopped the execution (nil if it has finished)
+	funcs *Interpreter //interpreter whose registered functions are called when the execution is replayed
+	reads []int //values read by the execution, in order
+}
+
+// historyIO records the values read by an execution, or reads them again when it is replayed
+type historyIO struct {
+	recorded programIO //input and output of the recorded execution (nil when it is replayed)
+	values []int //values read until now when recorded, or values left to read when replayed
+}
+
+// read returns the next natural of the recorded input, which is saved
+// return int, error
+func (hio *historyIO) read() (int, error) {
+	if hio.recorded != nil {
+		n, err := hio.recorded.read()
+		if err == nil {
+			hio.values = append(hio.values, n)
+		}
+		return n, err
+	}
+	if len(hio.values) == 0 { //the recorded execution failed reading
+		return 0, errInputEnded
+	}
+	n := hio.values[0]
+	hio.values = hio.values[1:]
+	return n, nil
+}
+
+// print writes a value on the output of the recorded execution (nothing when it is replayed)
+// return error
+func (hio *historyIO) print(n int) error {
+	if hio.recorded != nil {
+		return hio.recorded.print(n)
+	}
+	return nil
+}
+
+// historyTracer counts the moments of an execution and visits them
+type historyTracer struct {
+	moment int //moments before the next statement
+	visit func(moment int, p *program, s *stmt) bool //called on every moment, the execution is stopped if it returns false (nil to only count them)
+}
+
+// beforeStmt visits the moment of a statement
+// return error (errReplayStopped if the visit stops the execution)
+func (t *historyTracer) beforeStmt(p *program, s *stmt) error {
+	if t.visit != nil && !t.visit(t.moment, p, s) {
+		return errReplayStopped
+	}
+	t.moment++
+	return nil
+}
+
+// stepLimit does nothing: the error is saved on the history
+func (t *historyTracer) stepLimit(p *program) {}
+
+// Record executes the program and records its history, with a checkpoint every given steps (if interval
+// is lower than 1, HistoryDefaultInterval). maxSteps limits the statements to execute (0 for no limit),
+// the error that stops the execution (e.g. ErrUndefined) is saved on the history
+// return *History
+func (prog *Program) Record(maxSteps int, interval int) *History {
+	return recordHistory(prog.NewExecution(maxSteps), interval)
+}
+
+// Record computes the function with the given inputs and records its history, like Program.Record
+// return *History, error
+func (f *Function) Record(maxSteps int, interval int, inputs ...int) (*History, error) {
+	e, err := f.NewExecution(maxSteps, inputs...)
+	if err != nil {
+		return nil, err
+	}
+	return recordHistory(e, interval), nil
+}
+
+// recordHistory executes an execution until it ends, saving a checkpoint every interval steps
+// return *History
+func recordHistory(e *Execution, interval int) *History {
+	if interval < 1 {
+		interval = HistoryDefaultInterval
+	}
+	h := &History{name: e.p.name, code: e.code, funcs: e.p.funcs}
+	t := &historyTracer{}
+	e.p.tracer = t //the traced programs execute every iteration of the counting loops
+	hio := &historyIO{recorded: e.p.streams()}
+	e.p.stdio = hio
+
+	for {
+		snap, _ := e.Snapshot()
+		h.checkpoints = append(h.checkpoints, historyCheckpoint{snap: snap, moment: t.moment, reads: len(hio.values)})
+		e.PauseAfter(e.Steps() + interval)
+		if err := e.Run(); err != ErrPaused {
+			h.err = err
+			break
+		}
+	}
+
+	h.moments = t.moment
+	h.reads = hio.values
+	h.end = &HistoryState{Moment: t.moment, Steps: e.p.steps, Vars: frameVars(e.p), End: true}
+	return h
+}
+
+// Moments returns the number of statements reached by the execution, which is the moment of its end
+// (the moments go from 0 to Moments())
+// return int
+func (h *History) Moments() int {
+	return h.moments
+}
+
+// Err returns the error that stopped the execution (nil if it has finished)
+// return error
+func (h *History) Err() error {
+	return h.err
+}
+
+// At returns the state of the execution at a moment
+// return *HistoryState, error
+func (h *History) At(moment int) (*HistoryState, error) {
+	if moment < 0 || moment > h.moments {
+		return nil, errors.New("At: moment " + strconv.Itoa(moment) + " out of the history (0.." + strconv.Itoa(h.moments) + ")")
+	}
+	if moment == h.moments {
+		end := *h.end
+		return &end, nil
+	}
+
+	var state *HistoryState
+	h.replay(h.checkpointAt(moment), func(m int, p *program, s *stmt) bool {
+		if m < moment {
+			return true
+		}
+		state = h.newState(m, p, s)
+		return false
+	})
+	if state == nil {
+		return nil, errors.New("At: moment " + strconv.Itoa(moment) + " not reached again")
+	}
+	return state, nil
+}
+
+// LastAssignment returns the last moment before the given one where the variable is declared or assigned,
+// on the frame of the given moment (the main program or the call of a function being executed, not an earlier
+// or a recursive call of the same function)
+// return int, bool (false if the variable isn't declared or assigned before)
+func (h *History) LastAssignment(name string, before int) (int, bool) {
+	state, err := h.At(before)
+	if err != nil {
+		return 0, false
+	}
+
+	for i := h.checkpointAt(before - 1); i >= 0 && before > 0; i-- { //the segments between checkpoints, backwards
+		limit := before
+		if i + 1 < len(h.checkpoints) && h.checkpoints[i + 1].moment < limit {
+			limit = h.checkpoints[i + 1].moment
+		}
+
+		last, called := -1, false
+		h.replay(i, func(m int, p *program, s *stmt) bool {
+			if m >= limit {
+				return false
+			}
+			if p.depth < state.Depth || (p.depth == state.Depth && s.op == procSTRING) { //the frame is called after this moment (or after an earlier call returns)
+				last, called = -1, true
+			} else if (s.op == declareOPSTRING || s.op == assignOPSTRING || s.op == readSTRING) && s.name == name && p.depth == state.Depth {
+				last = m
+			}
+			return true
+		})
+		if last >= 0 {
+			return last, true
+		}
+		if called {
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// checkpointAt returns the index of the last checkpoint taken at a moment or before
+// return int
+func (h *History) checkpointAt(moment int) int {
+	i := 0
+	for i + 1 < len(h.checkpoints) && h.checkpoints[i + 1].moment <= moment {
+		i++
+	}
+	return i
+}
+
+// replay executes the recording again from a checkpoint, visiting every moment until the visit stops it
+// return error (errReplayStopped if stopped by the visit)
+func (h *History) replay(checkpoint int, visit func(moment int, p *program, s *stmt) bool) error {
+	cp := h.checkpoints[checkpoint]
+	e, err := h.funcs.Resume(cp.snap)
+	if err != nil {
+		return err
+	}
+	e.p.tracer = &historyTracer{moment: cp.moment, visit: visit}
+	e.p.stdio = &historyIO{values: h.reads[cp.reads:]}
+	return e.Run()
+}
+
+// newState returns the state of a frame of the execution before a statement
+// return *HistoryState
+func (h *History) newState(moment int, p *program, s *stmt) *HistoryState {
+	state := &HistoryState{Moment: moment, Steps: p.steps, Pos: newPosition(h.name, h.code, s.offset), Proc: p.procName, Depth: p.depth, Vars: frameVars(p)}
+	if s.op == procSTRING { //the RETURN is a moment of the function
+		state.Stmt = returnSTRING + " " + formatValue(s.value)
+	} else {
+		state.Stmt = stmtHead(*s)
+	}
+	return state
+}
+
+// frameVars returns the variables of a frame
+// return []SnapshotVar
+func frameVars(p *program) []SnapshotVar {
+	vars := make([]SnapshotVar, len(p.vars))
+	for i, v := range p.vars {
+		vars[i] = SnapshotVar{Name: v.name, Value: v.value, Array: copyArray(v.array)}
+	}
+	return vars
+}
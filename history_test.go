@@ -0,0 +1,128 @@
+package whileinterp
+
+import (
+    "reflect"
+    "strings"
+    "testing"
+)
+
+var testHistoryCodes = []string{
+    "a := 3; b := 0; WHILE(a > 0) DO b = inc(b); a = dec(a) OD",
+    "PROC add(a, b) DO WHILE(b > 0) DO a = inc(a); b = dec(b) OD RETURN a OD; x := 0; i := 0; WHILE(i < 4) DO x = add(x, i); i = inc(i) OD",
+    "a := 0; WHILE(a == 0) DO a = 0 OD",
+    "PROC f(a) DO b := 0; WHILE(a > b) DO b = f(dec(a)); b = inc(a) OD RETURN b OD; x := f(2); a := 1; x = f(a)",
+    "PROC f(n) DO a := 7 RETURN a OD; PROC g(n) DO b := 0; a := 1 RETURN n OD; y := g(f(2)); x := g(f(g(1)))",
+}
+
+// testAssignment is a declaration or assignment reached by an execution
+type testAssignment struct {
+    moment int
+    depth int
+    name string
+}
+
+// doTestHistoryReference replays a whole recorded execution from its start, saving the state of every moment
+// and the assignments
+// return []*HistoryState, []testAssignment
+func doTestHistoryReference(h *History) ([]*HistoryState, []testAssignment) {
+    states, assignments := []*HistoryState{}, []testAssignment{}
+    h.replay(0, func(m int, p *program, s *stmt) bool {
+        states = append(states, h.newState(m, p, s))
+        if s.op == declareOPSTRING || s.op == assignOPSTRING {
+            assignments = append(assignments, testAssignment{m, p.depth, s.name})
+        }
+        return true
+    })
+    return states, assignments
+}
+
+/*********************** TESTING ***********************/
+func TestHistoryAt(t *testing.T) {
+    for _, code := range testHistoryCodes {
+        prog, err := ParseCode(code)
+        if err != nil {
+            t.Fatal(err)
+        }
+        expected, _ := doTestHistoryReference(prog.Record(60, 1 << 30))
+
+        for _, interval := range []int{1, 2, 3, 7} {
+            h := prog.Record(60, interval)
+            if h.Moments() != len(expected) {
+                t.Fatal("unexpected moments of '", code, "': ", h.Moments(), ", expected: ", len(expected))
+            }
+            for m := len(expected) - 1; m >= 0; m-- { //stepping backwards
+                returned, err := h.At(m)
+                if err != nil || !reflect.DeepEqual(returned, expected[m]) {
+                    t.Error("unexpected state of '", code, "' with interval ", interval, ":\n returned: ", returned, " ", err, "\n expected: ", expected[m])
+                }
+            }
+        }
+    }
+}
+
+func TestHistoryLastAssignment(t *testing.T) {
+    for _, code := range testHistoryCodes {
+        prog, _ := ParseCode(code)
+        h := prog.Record(60, 4)
+        states, assignments := doTestHistoryReference(h)
+
+        for before := 0; before < len(states); before++ {
+            for _, name := range []string{"a", "b", "x", "i"} {
+                expected, found := 0, false
+                depth, start := states[before].Depth, 0 //the frame starts after the last moment with less depth or RETURN
+                for m := 0; m < before; m++ {
+                    if states[m].Depth < depth || (states[m].Depth == depth && strings.HasPrefix(states[m].Stmt, returnSTRING + " ")) {
+                        start = m + 1
+                    }
+                }
+                for _, as := range assignments {
+                    if as.moment >= start && as.moment < before && as.name == name && as.depth == depth {
+                        expected, found = as.moment, true
+                    }
+                }
+                returned, ok := h.LastAssignment(name, before)
+                if ok != found || returned != expected {
+                    t.Error("unexpected last assignment of '", name, "' before ", before, " on '", code, "': ", returned, " ", ok, ", expected: ", expected, " ", found)
+                }
+            }
+        }
+    }
+}
+
+func TestHistoryEnd(t *testing.T) {
+    prog, _ := ParseCode(testHistoryCodes[1])
+    h := prog.Record(0, 5)
+    end, err := h.At(h.Moments())
+    if err != nil || h.Err() != nil || !end.End {
+        t.Fatal("unexpected end: ", end, " ", err, " ", h.Err())
+    }
+    if !reflect.DeepEqual(end.Vars, []SnapshotVar{{Name: "x", Value: 6}, {Name: "i", Value: 4}}) {
+        t.Error("unexpected variables at the end: ", end.Vars)
+    }
+
+    state, _ := h.At(9)
+    if state.String() != "moment 9 (step 9) 1:35: add: a = inc(a) {a: 0, b: 1}" {
+        t.Error("unexpected state: ", state)
+    }
+    if _, err := h.At(h.Moments() + 1); err == nil {
+        t.Error("expected an error out of the history")
+    }
+
+    prog, _ = ParseCode(testHistoryCodes[2])
+    if h := prog.Record(20, 5); h.Err() != ErrUndefined {
+        t.Error("expected ErrUndefined, returned: ", h.Err())
+    }
+}
+
+func TestHistoryFunction(t *testing.T) {
+    f, _ := ParseFunction("c := val(x2); WHILE(c > 0) DO x0 = inc(x0); c = dec(c) OD", 2)
+    h, err := f.Record(0, 2, 3, 2)
+    if err != nil {
+        t.Fatal(err)
+    }
+    m, ok := h.LastAssignment("x0", h.Moments())
+    state, _ := h.At(m)
+    if !ok || state.Stmt != "x0 = inc(x0)" || state.Vars[0].Value != 1 {
+        t.Error("unexpected last assignment of x0: ", state)
+    }
+}
@@ -0,0 +1,180 @@
+package whileinterp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"unicode"
+)
+
+/*
+    Input and output of the programs: PRINT writes a value on a line of the output, and READ reads the next
+    natural of the input (separated by spaces or new lines) on a variable already declared:
+
+        n := 0; READ n
+        WHILE(n > 0) DO PRINT n; n = dec(n) OD
+
+    The programs of an Interpreter use the standard input and output, unless an embedder gives others with
+    SetInput and SetOutput (e.g. a strings.Reader and a bytes.Buffer to test a program). A READ stops the
+    execution with its position if the input has ended or the value read is not a natural number.
+    The snapshots don't save the input: a resumed execution reads the values that follow on its own input.
+*/
+
+// printSTRING defines the syntax of the output of a value in a string
+const printSTRING = "PRINT"
+
+// readSTRING defines the syntax of the input of a variable in a string
+const readSTRING = "READ"
+
+// errInputEnded is returned by a READ when the input has no more values
+var errInputEnded = errors.New("the input has ended")
+
+// programIO reads the values of the READ statements and writes the values of the PRINT statements of an execution
+type programIO interface {
+	// read returns the next natural of the input
+	read() (int, error)
+	// print writes a value on a line of the output
+	print(n int) error
+}
+
+// naturalReader reads the naturals of an input, separated by spaces or new lines
+type naturalReader struct {
+	mu sync.Mutex //protects r (the executions may run concurrently)
+	r *bufio.Reader //input, read until the end of every value
+}
+
+// lineWriter writes the values of an output, one per line
+type lineWriter struct {
+	mu sync.Mutex //protects w (the executions may run concurrently)
+	w io.Writer //output
+}
+
+var (
+	stdinReader = &naturalReader{r: bufio.NewReader(os.Stdin)} //input of the interpreters without SetInput
+	stdoutWriter = &lineWriter{w: os.Stdout} //output of the interpreters without SetOutput
+)
+
+// readNatural reads the next natural of the input
+// return int, error (errInputEnded if there are no more values)
+func (nr *naturalReader) readNatural() (int, error) {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	word := []rune{}
+	for {
+		c, _, err := nr.r.ReadRune()
+		if err == io.EOF && len(word) > 0 {
+			break
+		}
+		if err == io.EOF {
+			return 0, errInputEnded
+		}
+		if err != nil {
+			return 0, err
+		}
+		if unicode.IsSpace(c) && len(word) > 0 {
+			break
+		}
+		if !unicode.IsSpace(c) {
+			word = append(word, c)
+		}
+	}
+
+	n, err := strconv.Atoi(string(word))
+	if err != nil || n < 0 {
+		return 0, errors.New("value '" + string(word) + "' is not a natural number")
+	}
+	return n, nil
+}
+
+// writeLine writes a value on a line of the output
+// return error
+func (lw *lineWriter) writeLine(n int) error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	_, err := io.WriteString(lw.w, strconv.Itoa(n) + "\n")
+	return err
+}
+
+// SetInput sets the input read by the READ statements of the programs of the interpreter (os.Stdin by default),
+// shared by all of them: every value is read once
+func (in *Interpreter) SetInput(r io.Reader) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.input = &naturalReader{r: bufio.NewReader(r)}
+}
+
+// SetOutput sets the output written by the PRINT statements of the programs of the interpreter (os.Stdout by default)
+func (in *Interpreter) SetOutput(w io.Writer) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.output = &lineWriter{w: w}
+}
+
+// read returns the next natural of the input of the interpreter (nil reads the standard input)
+// return int, error
+func (in *Interpreter) read() (int, error) {
+	nr := stdinReader
+	if in != nil {
+		in.mu.RLock()
+		if in.input != nil {
+			nr = in.input
+		}
+		in.mu.RUnlock()
+	}
+	return nr.readNatural()
+}
+
+// print writes a value on a line of the output of the interpreter (nil writes on the standard output)
+// return error
+func (in *Interpreter) print(n int) error {
+	lw := stdoutWriter
+	if in != nil {
+		in.mu.RLock()
+		if in.output != nil {
+			lw = in.output
+		}
+		in.mu.RUnlock()
+	}
+	return lw.writeLine(n)
+}
+
+// streams returns the input and the output of a program: its own ones, or the ones of its interpreter
+// return programIO
+func (p *program) streams() programIO {
+	if p.stdio != nil {
+		return p.stdio
+	}
+	return p.funcs
+}
+
+// execPrint writes the value of a PRINT statement on the output of the program
+// return error
+func (p *program) execPrint(s stmt) error {
+	val, err := p.evalValue(s.value)
+	if err != nil {
+		return err
+	}
+	if err := p.streams().print(val); err != nil {
+		return p.runtimeError(s.offset, "value of " + printSTRING + " can't be written: " + err.Error())
+	}
+	return nil
+}
+
+// execRead reads the next natural of the input of the program on the variable of a READ statement
+// return error
+func (p *program) execRead(s stmt) error {
+	if !p.isVarPresent(s.name) {
+		return errors.New("parseProgram: error using " + readSTRING + ". variable '" + s.name + "' is not present.")
+	}
+	val, err := p.streams().read()
+	if err != nil {
+		return p.runtimeError(s.offset, "variable '" + s.name + "' can't be read: " + err.Error())
+	}
+	p.setVar(&variable{name: s.name, value: val})
+	return nil
+}
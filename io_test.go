@@ -0,0 +1,171 @@
+package whileinterp
+
+import (
+    "bytes"
+    "encoding/json"
+    "io/ioutil"
+    "strings"
+    "testing"
+)
+
+const testIOCode = `PROC show(a) DO PRINT a; RETURN a OD
+n := 0; READ n
+s := 0
+WHILE(n > 0) DO
+    READ s
+    x := show(s)
+    n = dec(n)
+OD
+PRINT inc(s)`
+
+// doTestIOExec executes a code with the given input and returns what it writes and the error that stops it
+// return string, error
+func doTestIOExec(code string, input string, t *testing.T) (string, error) {
+    in := NewInterpreter()
+    out := &bytes.Buffer{}
+    in.SetInput(strings.NewReader(input))
+    in.SetOutput(out)
+    prog, err := in.ParseCode(code)
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = prog.NewExecution(0).Run()
+    return out.String(), err
+}
+
+/*********************** TESTING ***********************/
+func TestPrintRead(t *testing.T) {
+    tests := map[string]string{
+        "3\n7 8\n\t9  ": "7\n8\n9\n10\n",
+        "0": "1\n",
+        "1 5 6": "5\n6\n",
+    }
+    for input, expected := range tests {
+        output, err := doTestIOExec(testIOCode, input, t)
+        if err != nil || output != expected {
+            t.Error("unexpected output with the input '", input, "'\n returned: ", output, " ", err, "\n expected: ", expected)
+        }
+    }
+}
+
+func TestReadErrors(t *testing.T) {
+    tests := map[string]string{
+        "2 4": "5:5: variable 's' can't be read: the input has ended",
+        "1 x": "5:5: variable 's' can't be read: value 'x' is not a natural number",
+        "-1": "2:9: variable 'n' can't be read: value '-1' is not a natural number",
+        "": "2:9: variable 'n' can't be read: the input has ended",
+    }
+    for input, expected := range tests {
+        if _, err := doTestIOExec(testIOCode, input, t); err == nil || err.Error() != expected {
+            t.Error("unexpected error with the input '", input, "'\n returned: ", err, "\n expected: ", expected)
+        }
+    }
+}
+
+func TestPrintReadCheck(t *testing.T) {
+    codes := map[string][]string{
+        "READ x; PRINT y": {"1:6: variable 'x' read before its declaration", "1:15: variable 'y' used before its declaration"},
+        "a := array(2); READ a; PRINT a[1]": {"1:21: array 'a' can't be read"},
+        "x := 0; WHILE(x < 1) DO t := 0; READ t OD; READ t": {"1:49: variable 't' read outside the block of its declaration"},
+        "x := 0; READ 3": {"1:14: name of the variable expected, found '3'"},
+    }
+    for code, expecErrs := range codes {
+        doTestCheckCode(code, expecErrs, t)
+    }
+}
+
+func TestPrintReadFormat(t *testing.T) {
+    prog, err := ParseCode(testIOCode)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := "PROC show(a) DO PRINT a RETURN a OD; n := 0; READ n; s := 0; WHILE(n > 0) DO READ s; x := show(s); n = dec(n) OD; PRINT inc(s);"
+    if formatted := formatStmts(prog.stmts); formatted != expected {
+        t.Error("unexpected formatted code\n returned: ", formatted, "\n expected: ", expected)
+    }
+
+    data, err := json.Marshal(prog)
+    if err != nil {
+        t.Fatal(err)
+    }
+    imported := &Program{}
+    if err := json.Unmarshal(data, imported); err != nil {
+        t.Fatal(err)
+    }
+    if formatted := formatStmts(imported.stmts); formatted != expected {
+        t.Error("unexpected code imported from JSON\n returned: ", formatted, "\n expected: ", expected)
+    }
+
+    decoded, err := Decode(Encode(prog))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if Encode(decoded).Cmp(Encode(prog)) != 0 || !strings.Contains(decoded.String(), "READ x") || !strings.Contains(decoded.String(), "PRINT") {
+        t.Error("unexpected decoded program: ", decoded)
+    }
+}
+
+func TestPrintReadAnalyses(t *testing.T) {
+    prog, err := ParseCode("x := 0; y := 5; READ x; WHILE(x > 0) DO x = dec(x) OD; PRINT y")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    optimized, err := prog.Optimize(OptPasses[:]...)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if expected := "x := 0\nREAD x\nWHILE(x > 0) DO\n    x = dec(x)\nOD\nPRINT 5\n"; optimized.String() != expected {
+        t.Error("unexpected optimized code\n returned: ", optimized, "\n expected: ", expected)
+    }
+
+    for _, point := range prog.Ranges().Points {
+        if point.Stmt == "WHILE(x > 0)" && point.Ranges["x"].String() != "[0, +inf]" {
+            t.Error("expected any natural read, returned: ", point.Ranges["x"])
+        }
+    }
+    if loops := prog.Termination(); len(loops) != 1 || loops[0].Verdict != Terminates {
+        t.Error("unexpected termination: ", loops)
+    }
+}
+
+func TestPrintReadHistory(t *testing.T) {
+    in := NewInterpreter()
+    out := &bytes.Buffer{}
+    in.SetInput(strings.NewReader("2 4 6"))
+    in.SetOutput(out)
+    prog, err := in.ParseCode(testIOCode)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    h := prog.Record(0, 2)
+    if h.Err() != nil || out.String() != "4\n6\n7\n" {
+        t.Fatal("unexpected recorded execution: ", out.String(), " ", h.Err())
+    }
+    moment, ok := h.LastAssignment("s", h.Moments())
+    if !ok {
+        t.Fatal("expected the READ of 's' found")
+    }
+    state, err := h.At(moment + 1)
+    if err != nil || state.Vars[1].Value != 6 {
+        t.Error("expected the value read again, returned: ", state, " ", err)
+    }
+    if out.String() != "4\n6\n7\n" {
+        t.Error("expected nothing printed by the replays, returned: ", out.String())
+    }
+}
+
+func TestServeDAPPrintRead(t *testing.T) {
+    c := newTestDAPClient(t)
+    defer c.close()
+
+    if err := ioutil.WriteFile(c.path(), []byte("x := 0\nREAD x\nPRINT inc(x)\n"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    c.launch(map[string]interface{}{"read": []int{4}})
+    c.request("configurationDone", nil)
+    c.check(c.expect("event", "output")["body"], `{"category":"stdout","output":"5\n"}`)
+    c.check(c.expect("event", "output")["body"], `{"category":"stdout","output":"x = 4\n"}`)
+    c.expect("event", "terminated")
+}
@@ -0,0 +1,371 @@
+package whileinterp
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/*
+    JSON schema of the programs (version 1):
+
+        program   = {"version": 1, "name": string, "stmts": [stmt]}
+        stmt      = {"kind": "declare" | "assign", "pos": pos, "name": string, "value": value}
+                    {"kind": "assign", "pos": pos, "name": string, "index": value, "value": value}
+                    {"kind": "while", "pos": pos, "cond": cond, "body": [stmt]}
+                    {"kind": "proc", "pos": pos, "name": string, "params": [string], "body": [stmt], "value": value}
+                    {"kind": "import", "pos": pos, "name": string}
+                    {"kind": "import", "pos": pos, "name": string, "path": string}
+                    {"kind": "print", "pos": pos, "value": value}
+                    {"kind": "read", "pos": pos, "name": string}
+        cond      = {"kind": "compare", "pos": pos, "op": "<" | "<=" | ">" | ">=" | "==" | "!=", "first": value, "second": value}
+                    {"kind": "and" | "or", "pos": pos, "left": cond, "right": cond}
+                    {"kind": "not", "pos": pos, "left": cond}
+        value     = {"kind": "number", "pos": pos, "number": string}
+                    {"kind": "var", "pos": pos, "name": string}
+                    {"kind": "call", "pos": pos, "name": string, "params": [value]}
+                    {"kind": "index", "pos": pos, "name": string, "index": value}
+        pos       = {"line": int, "column": int}
+
+    The positions are written on the export and ignored on the import, since the code of an imported
+    program is built again from its statements.
+*/
+
+// jsonVersion defines the version of the JSON schema of the programs
+const jsonVersion = 1
+
+// jsonProgram is a program on the JSON schema
+type jsonProgram struct {
+	Version int `json:"version"`
+	Name string `json:"name,omitempty"`
+	Stmts []*jsonStmt `json:"stmts"`
+}
+
+// jsonStmt is a statement on the JSON schema
+type jsonStmt struct {
+	Kind string `json:"kind"`
+	Pos *jsonPos `json:"pos,omitempty"`
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"` //path of the file imported (empty for the standard library)
+	Params []string `json:"params,omitempty"`
+	Cond *jsonCond `json:"cond,omitempty"`
+	Body []*jsonStmt `json:"body,omitempty"`
+	Index *jsonValue `json:"index,omitempty"` //index of the element of an array assigned
+	Value *jsonValue `json:"value,omitempty"`
+}
+
+// jsonCond is a logic expression on the JSON schema
+type jsonCond struct {
+	Kind string `json:"kind"`
+	Pos *jsonPos `json:"pos,omitempty"`
+	Op string `json:"op,omitempty"`
+	First *jsonValue `json:"first,omitempty"`
+	Second *jsonValue `json:"second,omitempty"`
+	Left *jsonCond `json:"left,omitempty"`
+	Right *jsonCond `json:"right,omitempty"`
+}
+
+// jsonValue is a number, a variable, a function call or an element of an array on the JSON schema
+type jsonValue struct {
+	Kind string `json:"kind"`
+	Pos *jsonPos `json:"pos,omitempty"`
+	Number string `json:"number,omitempty"`
+	Name string `json:"name,omitempty"`
+	Params []*jsonValue `json:"params,omitempty"`
+	Index *jsonValue `json:"index,omitempty"`
+}
+
+// jsonPos is a position of the code on the JSON schema
+type jsonPos struct {
+	Line int `json:"line"`
+	Column int `json:"column"`
+}
+
+// jsonStmtKinds relates the operations of the statements with their kind on the JSON schema
+var jsonStmtKinds = map[string]string{declareOPSTRING: "declare", assignOPSTRING: "assign", whileFuncSTRING: "while", procSTRING: "proc", importSTRING: "import",
+	printSTRING: "print", readSTRING: "read"}
+
+// jsonLogicKinds relates the logic operators with their kind on the JSON schema
+var jsonLogicKinds = map[string]string{andOPSTRING: "and", orOPSTRING: "or", notOPSTRING: "not"}
+
+// jsonValueKinds lists the kinds of the values on the JSON schema (in the same order as valueKind)
+var jsonValueKinds = [...]string{"number", "var", "call", "index"}
+
+// jsonExporter exports the statements of a program to the JSON schema
+type jsonExporter struct {
+	code string //source code of the program (used to compute the positions)
+}
+
+// pos returns the position of an offset of the code
+// return *jsonPos
+func (e *jsonExporter) pos(offset int) *jsonPos {
+	pos := newPosition("", e.code, offset)
+	return &jsonPos{Line: pos.Line, Column: pos.Column}
+}
+
+// exportStmts exports a list of statements
+// return []*jsonStmt
+func (e *jsonExporter) exportStmts(stmts []stmt) []*jsonStmt {
+	result := make([]*jsonStmt, len(stmts))
+	for i, s := range stmts {
+		js := &jsonStmt{Kind: jsonStmtKinds[s.op], Pos: e.pos(s.offset), Name: s.name, Path: s.path, Params: s.params}
+		if s.op == whileFuncSTRING {
+			js.Cond = e.exportLogicExpr(s.cond)
+		}
+		if s.op == whileFuncSTRING || s.op == procSTRING {
+			js.Body = e.exportStmts(s.body)
+		}
+		if s.index != nil {
+			js.Index = e.exportValue(s.index)
+		}
+		if s.value != nil {
+			js.Value = e.exportValue(s.value)
+		}
+		result[i] = js
+	}
+	return result
+}
+
+// exportLogicExpr exports a logic expression
+// return *jsonCond
+func (e *jsonExporter) exportLogicExpr(l *logicExpr) *jsonCond {
+	kind, ok := jsonLogicKinds[l.op]
+	if !ok {
+		return &jsonCond{Kind: "compare", Pos: e.pos(l.offset), Op: l.op, First: e.exportValue(l.first), Second: e.exportValue(l.second)}
+	}
+
+	jc := &jsonCond{Kind: kind, Pos: e.pos(l.offset), Left: e.exportLogicExpr(l.left)}
+	if l.right != nil {
+		jc.Right = e.exportLogicExpr(l.right)
+	}
+	return jc
+}
+
+// exportValue exports a number, a variable, a function call or an element of an array
+// return *jsonValue
+func (e *jsonExporter) exportValue(v *valueExpr) *jsonValue {
+	jv := &jsonValue{Kind: jsonValueKinds[v.kind], Pos: e.pos(v.offset)}
+	switch v.kind {
+	case numberValue:
+		jv.Number = v.text
+	case varValue:
+		jv.Name = v.text
+	case indexValue:
+		jv.Name = v.text
+		jv.Index = e.exportValue(v.params[0])
+	case callValue:
+		jv.Name = v.text
+		jv.Params = make([]*jsonValue, len(v.params))
+		for i, param := range v.params {
+			jv.Params[i] = e.exportValue(param)
+		}
+	}
+	return jv
+}
+
+// MarshalJSON returns the program on the JSON schema
+// return []byte, error
+func (prog *Program) MarshalJSON() ([]byte, error) {
+	e := &jsonExporter{code: prog.code}
+	return json.Marshal(&jsonProgram{Version: jsonVersion, Name: prog.name, Stmts: e.exportStmts(prog.stmts)})
+}
+
+// importStmts imports a list of statements (topLevel is true if the functions can be defined on the list)
+// return []stmt, error
+func importStmts(stmts []*jsonStmt, topLevel bool) ([]stmt, error) {
+	result := []stmt{}
+	for _, js := range stmts {
+		if js == nil {
+			return nil, errors.New("UnmarshalJSON: statement expected, found null")
+		}
+
+		s := stmt{name: js.Name}
+		for op, kind := range jsonStmtKinds {
+			if kind == js.Kind {
+				s.op = op
+			}
+		}
+
+		var err error
+		switch s.op {
+		case declareOPSTRING, assignOPSTRING:
+			err = checkJSONName(js.Name)
+			if err == nil && js.Index != nil {
+				if s.op == declareOPSTRING {
+					return nil, errors.New("UnmarshalJSON: an element of array '" + js.Name + "' can't be declared")
+				}
+				s.index, err = importValue(js.Index)
+			}
+			if err == nil {
+				s.value, err = importValue(js.Value)
+			}
+		case printSTRING:
+			s.value, err = importValue(js.Value)
+		case readSTRING:
+			err = checkJSONName(js.Name)
+		case whileFuncSTRING:
+			s.cond, err = importLogicExpr(js.Cond)
+			if err == nil {
+				s.body, err = importStmts(js.Body, false)
+			}
+		case procSTRING:
+			if !topLevel {
+				return nil, errors.New("UnmarshalJSON: function '" + js.Name + "' can only be defined on the top level")
+			}
+			err = checkJSONName(js.Name)
+			s.params = []string{}
+			for _, param := range js.Params {
+				if err == nil {
+					err = checkJSONName(param)
+				}
+				s.params = append(s.params, param)
+			}
+			if err == nil {
+				s.body, err = importStmts(js.Body, false)
+			}
+			if err == nil {
+				s.value, err = importValue(js.Value)
+			}
+		case importSTRING: //the library is loaded when the code is parsed
+			if !topLevel {
+				return nil, errors.New("UnmarshalJSON: library '" + js.Name + "' can only be imported on the top level")
+			}
+			if js.Path == "" {
+				err = checkJSONName(js.Name)
+			} else if strings.ContainsAny(js.Path, "\"\n") {
+				err = errors.New("UnmarshalJSON: path '" + js.Path + "' is not valid")
+			}
+			s.path = js.Path
+		default:
+			return nil, errors.New("UnmarshalJSON: statement kind '" + js.Kind + "' not defined")
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// importLogicExpr imports a logic expression
+// return *logicExpr, error
+func importLogicExpr(jc *jsonCond) (*logicExpr, error) {
+	if jc == nil {
+		return nil, errors.New("UnmarshalJSON: condition expected, found null")
+	}
+
+	if jc.Kind == "compare" {
+		for _, c := range comparators {
+			if c == jc.Op {
+				first, err := importValue(jc.First)
+				if err != nil {
+					return nil, err
+				}
+				second, err := importValue(jc.Second)
+				if err != nil {
+					return nil, err
+				}
+				return &logicExpr{op: c, first: first, second: second}, nil
+			}
+		}
+		return nil, errors.New("UnmarshalJSON: comparator '" + jc.Op + "' not defined")
+	}
+
+	for op, kind := range jsonLogicKinds {
+		if kind != jc.Kind {
+			continue
+		}
+		left, err := importLogicExpr(jc.Left)
+		if err != nil {
+			return nil, err
+		}
+		l := &logicExpr{op: op, left: left}
+		if op != notOPSTRING {
+			if l.right, err = importLogicExpr(jc.Right); err != nil {
+				return nil, err
+			}
+		}
+		return l, nil
+	}
+	return nil, errors.New("UnmarshalJSON: condition kind '" + jc.Kind + "' not defined")
+}
+
+// importValue imports a number, a variable, a function call or an element of an array
+// return *valueExpr, error
+func importValue(jv *jsonValue) (*valueExpr, error) {
+	if jv == nil {
+		return nil, errors.New("UnmarshalJSON: value expected, found null")
+	}
+
+	switch jv.Kind {
+	case jsonValueKinds[numberValue]:
+		if _, err := strconv.ParseUint(jv.Number, 10, 64); err != nil {
+			return nil, errors.New("UnmarshalJSON: number '" + jv.Number + "' is not a natural number")
+		}
+		return &valueExpr{kind: numberValue, text: jv.Number}, nil
+	case jsonValueKinds[varValue]:
+		return &valueExpr{kind: varValue, text: jv.Name}, checkJSONName(jv.Name)
+	case jsonValueKinds[indexValue]:
+		index, err := importValue(jv.Index)
+		if err != nil {
+			return nil, err
+		}
+		return &valueExpr{kind: indexValue, text: jv.Name, params: []*valueExpr{index}}, checkJSONName(jv.Name)
+	case jsonValueKinds[callValue]:
+		call := &valueExpr{kind: callValue, text: jv.Name, params: []*valueExpr{}}
+		for _, param := range jv.Params {
+			p, err := importValue(param)
+			if err != nil {
+				return nil, err
+			}
+			call.params = append(call.params, p)
+		}
+		return call, checkJSONCallName(jv.Name)
+	}
+	return nil, errors.New("UnmarshalJSON: value kind '" + jv.Kind + "' not defined")
+}
+
+// checkJSONName checks that a name of a variable or a function can be written on the code
+// return error
+func checkJSONName(name string) error {
+	if !isValidName(name) {
+		return errors.New("UnmarshalJSON: name '" + name + "' is not valid")
+	}
+	return nil
+}
+
+// checkJSONCallName checks that a name of a called function can be written on the code, qualified with
+// the namespace of a library or not (e.g. math.add)
+// return error
+func checkJSONCallName(name string) error {
+	if i := strings.Index(name, "."); i != -1 && isValidName(name[:i]) && isValidName(name[i + 1:]) {
+		return nil
+	}
+	return checkJSONName(name)
+}
+
+// UnmarshalJSON reads a program on the JSON schema. The code of the program is built again
+// from its statements and checked statically, so the program can be executed
+// return error
+func (prog *Program) UnmarshalJSON(data []byte) error {
+	jp := &jsonProgram{}
+	if err := json.Unmarshal(data, jp); err != nil {
+		return err
+	}
+	if jp.Version != jsonVersion {
+		return errors.New("UnmarshalJSON: version " + strconv.Itoa(jp.Version) + " not supported")
+	}
+
+	stmts, err := importStmts(jp.Stmts, true)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := prog.funcs.parseProgramCode(jp.Name, formatStmts(stmts)) //the code is parsed to know the positions of the statements
+	if err != nil {
+		return err
+	}
+	*prog = *parsed
+	return nil
+}
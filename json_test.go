@@ -0,0 +1,99 @@
+package whileinterp
+
+import (
+    "encoding/json"
+    "strings"
+    "testing"
+)
+
+/*********************** TESTING ***********************/
+func TestMarshalJSON(t *testing.T) {
+    p, err := ParseCode("xo := 2\nWHILE(NOT xo < 5) DO xo = dec(xo) OD")
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    expecJSON := `{"version":1,"stmts":[` +
+        `{"kind":"declare","pos":{"line":1,"column":1},"name":"xo","value":{"kind":"number","pos":{"line":1,"column":7},"number":"2"}},` +
+        `{"kind":"while","pos":{"line":2,"column":1},"cond":{"kind":"not","pos":{"line":2,"column":7},"left":{"kind":"compare","pos":{"line":2,"column":11},"op":"\u003c",` +
+        `"first":{"kind":"var","pos":{"line":2,"column":11},"name":"xo"},"second":{"kind":"number","pos":{"line":2,"column":16},"number":"5"}}},` +
+        `"body":[{"kind":"assign","pos":{"line":2,"column":22},"name":"xo","value":{"kind":"call","pos":{"line":2,"column":27},"name":"dec",` +
+        `"params":[{"kind":"var","pos":{"line":2,"column":31},"name":"xo"}]}}]}]}`
+
+    data, err := json.Marshal(p)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    if string(data) != expecJSON {
+        t.Error("unexpected returned JSON:\n returned: ", string(data), "\n expected: ", expecJSON)
+    }
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+    codes := []string{
+        testCode1,
+        "PROC add(a, b) DO WHILE(b > 0) DO a = inc(a); b = dec(b) OD RETURN a OD; xo := add(2, inc(3)); WHILE(add(xo, 1) <= 10 OR xo == 0 AND NOT xo != 1) DO xo = inc(xo) OD;",
+        "IMPORT std; xo := mult(add(2, 3), 4); x1 := 0; WHILE(mod(xo, 3) != 0) DO xo = inc(xo); x1 = sub(x1, 1) OD;",
+        "a := array(3); i := 2; a[i] = inc(a[dec(i)]); WHILE(a[0] < i) DO a[0] = inc(a[0]) OD; xo := val(a[0]);",
+    }
+
+    for _, code := range codes {
+        p, err := ParseCode(code)
+        if err != nil {
+            t.Error(err)
+            continue
+        }
+        data, err := json.Marshal(p)
+        if err != nil {
+            t.Error(err)
+            continue
+        }
+
+        retProg := &Program{}
+        if err := json.Unmarshal(data, retProg); err != nil {
+            t.Error(err)
+            continue
+        }
+        if retProg.String() != code {
+            t.Error("unexpected returned program:\n returned: ", retProg, "\n expected: ", code)
+        }
+        if err := retProg.Exec(false); err != nil {
+            t.Error(err)
+        }
+    }
+}
+
+func TestUnmarshalJSONEdited(t *testing.T) {
+    data := `{"version":1,"name":"edited.json","stmts":[{"kind":"declare","name":"xo","value":{"kind":"number","number":"7"}},` +
+        `{"kind":"assign","name":"xo","value":{"kind":"call","name":"inc","params":[{"kind":"var","name":"xo"}]}}]}`
+    expecCode := "xo := 7; xo = inc(xo);"
+
+    p := &Program{}
+    if err := json.Unmarshal([]byte(data), p); err != nil {
+        t.Error(err)
+        return
+    }
+    if p.String() != expecCode || p.Name() != "edited.json" {
+        t.Error("unexpected returned program:\n returned: ", p.Name(), p, "\n expected: ", "edited.json", expecCode)
+    }
+}
+
+func TestUnmarshalJSONErrors(t *testing.T) {
+    datas := map[string]string{
+        `{"version":2,"stmts":[]}`: "UnmarshalJSON: version 2 not supported",
+        `{"version":1,"stmts":[{"kind":"loop"}]}`: "UnmarshalJSON: statement kind 'loop' not defined",
+        `{"version":1,"stmts":[{"kind":"declare","name":"x; y","value":{"kind":"number","number":"1"}}]}`: "UnmarshalJSON: name 'x; y' is not valid",
+        `{"version":1,"stmts":[{"kind":"declare","name":"xo","value":{"kind":"number","number":"-1"}}]}`: "UnmarshalJSON: number '-1' is not a natural number",
+        `{"version":1,"stmts":[{"kind":"while","cond":{"kind":"compare","op":"=<"}}]}`: "UnmarshalJSON: comparator '=<' not defined",
+        `{"version":1,"stmts":[{"kind":"declare","name":"xo"}]}`: "UnmarshalJSON: value expected, found null",
+        `{"version":1,"stmts":[{"kind":"assign","name":"xo","value":{"kind":"number","number":"1"}}]}`: "1:1: assignment to undeclared variable 'xo'",
+    }
+
+    for data, expecErr := range datas {
+        err := json.Unmarshal([]byte(data), &Program{})
+        if err == nil || !strings.HasSuffix(err.Error(), expecErr) {
+            t.Error("unexpected returned error:\n returned: ", err, "\n expected: ", expecErr)
+        }
+    }
+}
@@ -0,0 +1,129 @@
+package whileinterp
+
+import (
+	_ "embed"
+	"sync"
+)
+
+/*
+    Libraries of functions written in the while language, which the programs import with IMPORT:
+
+        IMPORT std
+        IMPORT "lib/geometry.while"
+        x := mult(add(2, 3), geometry.area(4, 5))
+
+    The standard library (std/std.while, embedded on the package) defines add(a, b), sub(a, b) (truncated
+    subtraction), mult(a, b), div(a, b), mod(a, b) and exp(a, b); an Interpreter imports it on every program
+    with ImportStd. A file is imported with its path, relative to the file that imports it, and its functions
+    are called qualified with the name of the file (geometry.area), so the names of different files don't clash.
+    A library can only define functions and import other libraries (without cycles), whose functions are only
+    known by the library. The functions of a library are executed like the predefined ones: their steps count
+    on the program, but they are not traced (a debugger steps over them) and the programs can't redefine them.
+*/
+
+// stdCode is the source code of the standard library
+//go:embed std/std.while
+var stdCode string
+
+// stdSTRING defines the name of the standard library in a string
+const stdSTRING = "std"
+
+// library is a source of functions imported by the programs
+type library struct {
+	name string //name of the library (the path of a file), shown on the positions of its statements
+	ns string //namespace of the functions of the library on the importing code (empty if they are not qualified)
+	code string //source code of the library
+	stmts []stmt //functions defined and libraries imported on the library
+	procs map[string]*stmt //functions known by the library (defined and imported) by their name on the library
+}
+
+// newLibrary parses and checks the code of a library imported through a chain of files (the last one is the
+// library), which can only define functions and import other libraries
+// return *library, error
+func newLibrary(name string, ns string, code string, chain []string) (*library, error) {
+	stmts, err := parseImported(name, code, chain)
+	if err != nil {
+		return nil, err
+	}
+	p := initProgram()
+	p.name = name
+	p.stmts = stmts
+	if errs := p.check(code); len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	lib := &library{name: name, ns: ns, code: code, stmts: p.stmts}
+	for i := range lib.stmts {
+		s := &lib.stmts[i]
+		if s.op != procSTRING && s.op != importSTRING {
+			return nil, &CheckError{Pos: newPosition(name, code, s.offset), Msg: "only functions can be defined on a library"}
+		}
+		if s.op == procSTRING {
+			s.lib = lib
+		}
+	}
+	p.defineProcs()
+	lib.procs = p.procs
+	return lib, nil
+}
+
+// qualify returns the name of a function of the library on the code that imports it (e.g. math.add)
+// return string
+func (lib *library) qualify(name string) string {
+	if lib.ns == "" {
+		return name
+	}
+	return lib.ns + "." + name
+}
+
+var (
+	stdOnce sync.Once //parses the standard library the first time it is imported
+	stdLib *library //standard library, once parsed
+	stdErr error //error found parsing the standard library
+)
+
+// stdLibrary returns the standard library
+// return *library, error
+func stdLibrary() (*library, error) {
+	stdOnce.Do(func() {
+		stdLib, stdErr = newLibrary(stdSTRING, "", stdCode, nil)
+	})
+	return stdLib, stdErr
+}
+
+// ImportStd imports the standard library on every program parsed (or resumed) by the interpreter,
+// as if they started with IMPORT std
+func (in *Interpreter) ImportStd() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.std = true
+}
+
+// importsStd checks if the interpreter imports the standard library on every program (nil doesn't)
+// return bool
+func (in *Interpreter) importsStd() bool {
+	if in == nil {
+		return false
+	}
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return in.std
+}
+
+// libraries returns the libraries imported by a program: by its interpreter, and with IMPORT
+// (together with the offset of their IMPORT, 0 for the interpreter's)
+// return []*library, []int
+func (p *program) libraries() ([]*library, []int) {
+	libs, offsets := []*library{}, []int{}
+	if p.funcs.importsStd() {
+		if lib, err := stdLibrary(); err == nil {
+			libs, offsets = append(libs, lib), append(offsets, 0)
+		}
+	}
+	for _, s := range p.stmts {
+		if s.op == importSTRING {
+			libs, offsets = append(libs, s.lib), append(offsets, s.offset)
+		}
+	}
+	return libs, offsets
+}
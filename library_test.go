@@ -0,0 +1,303 @@
+package whileinterp
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+// stdTests relates every function of the standard library with its result on Go
+var stdTests = map[string]func(a, b int) int{
+    "add": func(a, b int) int { return a + b },
+    "sub": func(a, b int) int {
+        if b > a {
+            return 0
+        }
+        return a - b
+    },
+    "mult": func(a, b int) int { return a * b },
+    "div": func(a, b int) int {
+        if b == 0 {
+            return 0
+        }
+        return a / b
+    },
+    "mod": func(a, b int) int {
+        if b == 0 {
+            return a
+        }
+        return a % b
+    },
+    "exp": func(a, b int) int {
+        r := 1
+        for i := 0; i < b; i++ {
+            r *= a
+        }
+        return r
+    },
+}
+
+// stdTestValues lists the parameters of the functions of the standard library on the tests
+var stdTestValues = []int{0, 1, 2, 3, 5, 7, 10, 12}
+
+// doTestLibraryFiles writes the files of a test on a temporary directory and returns its path
+// return string
+func doTestLibraryFiles(files map[string]string, t *testing.T) string {
+    dir, err := ioutil.TempDir("", "whileinterp")
+    if err != nil {
+        t.Fatal(err)
+    }
+    for name, code := range files {
+        path := filepath.Join(dir, name)
+        if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := ioutil.WriteFile(path, []byte(code), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    return dir
+}
+
+/*********************** TESTING ***********************/
+func TestStdImport(t *testing.T) {
+    for name, expected := range stdTests {
+        for _, a := range stdTestValues {
+            for _, b := range stdTestValues {
+                code := "IMPORT std; x := " + name + "(" + strconv.Itoa(a) + ", " + strconv.Itoa(b) + ")"
+                prog, err := ParseCode(code)
+                if err != nil {
+                    t.Fatal(err)
+                }
+                e := prog.NewExecution(0)
+                if err := e.Run(); err != nil {
+                    t.Fatal(err)
+                }
+                if x, _ := e.Var("x"); x != expected(a, b) {
+                    t.Error("unexpected result of '", code, "'\n returned: ", x, "\n expected: ", expected(a, b))
+                }
+            }
+        }
+    }
+}
+
+func TestStdInterpreter(t *testing.T) {
+    in := NewInterpreter()
+    in.ImportStd()
+    f, err := in.ParseFunction("x0 = add(mult(x1, x1), exp(2, x2))", 2)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if result, err := f.Compute(0, 3, 4); err != nil || result != 25 {
+        t.Error("expected 25, returned: ", result, " ", err)
+    }
+
+    if _, err := ParseCode("x := add(1, 2)"); err == nil {
+        t.Error("expected the standard library unknown without IMPORT std")
+    }
+    if _, err := in.ParseCode("IMPORT std; x := add(1, 2)"); err != nil {
+        t.Error(err)
+    }
+}
+
+func TestStdErrors(t *testing.T) {
+    codes := map[string][]string{
+        "IMPORT std; PROC add(a, b) DO RETURN a OD; x := mult(1)": {
+            "1:13: function 'add' already defined by library 'std'",
+            "1:49: function 'mult' expects 2 parameter(s), found 1",
+        },
+        "IMPORT lib": {"1:8: library 'lib' not found"},
+        "x := 0; WHILE(x < 1) DO IMPORT std OD": {"1:25: libraries can only be imported outside of any block"},
+    }
+    for code, expecErrs := range codes {
+        doTestCheckCode(code, expecErrs, t)
+    }
+
+    in := newTestInterpreter(t)
+    in.RegisterFunc("exp", 2, func(args []int) (int, error) { return 0, nil })
+    errs := in.CheckCode("x := 1\nIMPORT std")
+    if len(errs) != 1 || errs[0].Error() != "2:1: function 'exp' of library 'std' already defined as a registered function" {
+        t.Error("unexpected errors: ", errs)
+    }
+}
+
+func TestStdSteps(t *testing.T) {
+    prog, _ := ParseCode("IMPORT std; x := mult(3, 4)")
+    e := prog.NewExecution(5)
+    if err := e.Run(); err != ErrUndefined {
+        t.Error("expected the steps of the library counted, returned: ", err)
+    }
+}
+
+func TestStdEncode(t *testing.T) {
+    prog, _ := ParseCode("IMPORT std; x0 := div(exp(3, 3), 4)")
+    decoded, err := Decode(Encode(prog))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if Encode(decoded).Cmp(Encode(prog)) != 0 {
+        t.Error("unexpected decoded program: ", decoded)
+    }
+    e := decoded.NewExecution(0)
+    if err := e.Run(); err != nil {
+        t.Fatal(err)
+    }
+    if x0, _ := e.Var("x0"); x0 != 6 {
+        t.Error("expected x0 = 6, returned: ", x0)
+    }
+}
+
+func TestImportFile(t *testing.T) {
+    dir := doTestLibraryFiles(map[string]string{
+        "main.while": "IMPORT \"lib/math.while\"; IMPORT std; x := math.triple(add(1, 1)); y := math.add(x, 1)",
+        "encode.while": "IMPORT \"lib/math.while\"; x0 := math.triple(2)",
+        "lib/math.while": "IMPORT \"util/twice.while\"\nPROC add(a, b) DO r := val(a); i := 0; WHILE(i < b) DO r = inc(r); i = inc(i) OD; RETURN r OD\n" +
+            "PROC triple(a) DO RETURN add(a, twice.double(a)) OD",
+        "lib/util/twice.while": "PROC double(a) DO r := val(a); i := 0; WHILE(i < a) DO r = inc(r); i = inc(i) OD; RETURN r OD",
+    }, t)
+    defer os.RemoveAll(dir)
+
+    prog, err := ParseFile(filepath.Join(dir, "main.while"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    e := prog.NewExecution(0)
+    if err := e.Run(); err != nil {
+        t.Fatal(err)
+    }
+    for name, expected := range map[string]int{"x": 6, "y": 7} {
+        if value, _ := e.Var(name); value != expected {
+            t.Error("unexpected value of '", name, "'\n returned: ", value, "\n expected: ", expected)
+        }
+    }
+
+    if formatted := formatStmts(prog.stmts); !strings.HasPrefix(formatted, "IMPORT \"lib/math.while\";") {
+        t.Error("unexpected formatted program: ", formatted)
+    }
+    if data, err := json.Marshal(prog); err != nil || !strings.Contains(string(data), `"path":"lib/math.while"`) {
+        t.Error("unexpected JSON of the program: ", string(data), " ", err)
+    }
+
+    prog, err = ParseFile(filepath.Join(dir, "encode.while"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    decoded, err := Decode(Encode(prog))
+    if err != nil {
+        t.Fatal(err)
+    }
+    e = decoded.NewExecution(0)
+    if err := e.Run(); err != nil {
+        t.Fatal(err)
+    }
+    if x0, _ := e.Var("x0"); x0 != 6 {
+        t.Error("expected x0 = 6 on the decoded program, returned: ", x0)
+    }
+}
+
+func TestImportFileFormat(t *testing.T) {
+    dir := doTestLibraryFiles(map[string]string{
+        "lib#1/c.while": "PROC f(a) DO RETURN inc(a) OD",
+        "a/b.while": "PROC g(a) DO RETURN dec(a) OD",
+    }, t)
+    defer os.RemoveAll(dir)
+
+    code := "IMPORT \"lib#1/c.while\" # first\nIMPORT \"a//b.while\"; x := c.f(b.g(1)) // last\n"
+    expected := "IMPORT \"lib#1/c.while\" # first\nIMPORT \"a//b.while\"\nx := c.f(b.g(1)) // last\n"
+    stmts, err := parseSource(filepath.Join(dir, "main.while"), code)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if formatted := formatCode(code, stmts); formatted != expected {
+        t.Error("unexpected formatted code\n returned: ", formatted, "\n expected: ", expected)
+    }
+}
+
+func TestImportFileFunction(t *testing.T) {
+    dir := doTestLibraryFiles(map[string]string{
+        "sub/lib.while": "PROC twice(a) DO r := val(a); i := 0; WHILE(i < a) DO r = inc(r); i = inc(i) OD; RETURN r OD",
+    }, t)
+    defer os.RemoveAll(dir)
+    wd, err := os.Getwd()
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.Chdir(wd)
+    if err := os.Chdir(os.TempDir()); err != nil { //the library is not on the working directory
+        t.Fatal(err)
+    }
+
+    name := filepath.Join(dir, "sub", "main.while")
+    f, err := ParseFunctionReader(name, strings.NewReader("IMPORT \"lib.while\"\nx0 = lib.twice(x1)\na := array(1); a[x1] = 1"), 1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if result, err := f.Compute(0, 0); err != nil || result != 0 {
+        t.Error("unexpected result: ", result, " ", err)
+    }
+    if results := f.Batch([][]int{{0}, {3}}, 2, 0); results[0].Result != 0 || results[1].Err == nil ||
+        results[1].Err.Error() != name + ":3:16: index 3 out of the bounds of array 'a' (1 elements)" {
+        t.Error("unexpected results: ", results[0], " ", results[1])
+    }
+
+    if _, err := ParseFunction("IMPORT \"lib.while\"\nx0 = lib.twice(x1)", 1); err == nil {
+        t.Error("expected the library not found without the name of the source")
+    }
+    _, err = ParseFunctionReader(name, strings.NewReader("x0 = y"), 1)
+    if err == nil || !strings.HasPrefix(err.Error(), name + ":1:6: ") {
+        t.Error("expected an error with the name of the source, returned: ", err)
+    }
+}
+
+func TestImportFileErrors(t *testing.T) {
+    dir := doTestLibraryFiles(map[string]string{
+        "cycle.while": "IMPORT \"a.while\"",
+        "a.while": "IMPORT \"b.while\"",
+        "b.while": "IMPORT \"a.while\"",
+        "missing.while": "IMPORT \"none.while\"",
+        "clash.while": "IMPORT \"c.while\"; IMPORT \"lib/c.while\"",
+        "c.while": "PROC f(a) DO RETURN a OD",
+        "lib/c.while": "PROC f(a) DO RETURN a OD",
+        "target.while": "IMPORT \"c.while\"; c.f := 1",
+        "code.while": "IMPORT \"stmt.while\"",
+        "stmt.while": "x := 1",
+    }, t)
+    defer os.RemoveAll(dir)
+
+    tests := map[string]string{
+        "cycle.while": "1:8: library 'a.while' not valid: a.while:1:8: library 'b.while' not valid: b.while:1:8: import cycle: " +
+            "cycle.while -> a.while -> b.while -> a.while",
+        "missing.while": "1:8: library 'none.while' not found",
+        "clash.while": "1:19: library 'c' already imported from 'c.while'",
+        "target.while": "1:19: name 'c.f' is not valid, only the calls of the functions of a library are qualified",
+        "code.while": "1:8: library 'stmt.while' not valid: stmt.while:1:1: only functions can be defined on a library",
+    }
+    for name, expected := range tests {
+        path := filepath.Join(dir, name)
+        _, err := ParseFile(path)
+        if err == nil || strings.Replace(err.Error(), dir + string(filepath.Separator), "", -1) != name + ":" + expected {
+            t.Error("unexpected error of '", name, "'\n returned: ", err, "\n expected: ", expected)
+        }
+    }
+}
+
+func TestImportFileRuntime(t *testing.T) {
+    dir := doTestLibraryFiles(map[string]string{
+        "main.while": "IMPORT \"arr.while\"\nx := arr.get(3)",
+        "arr.while": "PROC get(i) DO\n  a := array(2)\n  r := val(a[i])\n  RETURN r\nOD",
+    }, t)
+    defer os.RemoveAll(dir)
+
+    prog, err := ParseFile(filepath.Join(dir, "main.while"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = prog.NewExecution(0).Run()
+    if err == nil || !strings.Contains(err.Error(), "arr.while:3:") {
+        t.Error("expected an error with the position on the library, returned: ", err)
+    }
+}
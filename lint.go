@@ -0,0 +1,311 @@
+package whileinterp
+
+import "sort"
+
+// LintRule is the name of a rule checked by the linter
+type LintRule string
+
+// LintUnmodifiedLoopCondition reports loops whose condition variables are never modified in the body
+const LintUnmodifiedLoopCondition LintRule = "unmodified-loop-condition"
+
+// LintUnusedVariable reports variables that are declared but never read
+const LintUnusedVariable LintRule = "unused-variable"
+
+// LintSelfAssignment reports assignments of a variable to itself (e.g. x = val(x))
+const LintSelfAssignment LintRule = "self-assignment"
+
+// LintDecOfZero reports the decrement of a variable known to be zero
+const LintDecOfZero LintRule = "dec-of-zero"
+
+// LintShadowedVariable reports variables declared on a loop body that shadow a variable of an enclosing block
+const LintShadowedVariable LintRule = "shadowed-variable"
+
+// LintRules lists every rule checked by the linter
+var LintRules = [...]LintRule{LintUnmodifiedLoopCondition, LintUnusedVariable, LintSelfAssignment, LintDecOfZero, LintShadowedVariable}
+
+// LintFinding is a suspicious piece of code found by the linter
+type LintFinding struct {
+	Rule LintRule //rule that reported the finding
+	Pos Position //position of the finding on the code
+	Msg string //description of the finding
+}
+
+// String returns the finding with the format "line:column: message (rule)"
+// return string
+func (f *LintFinding) String() string {
+	return f.Pos.String() + ": " + f.Msg + " (" + string(f.Rule) + ")"
+}
+
+// linter looks for suspicious statements on a program
+type linter struct {
+	name string //name of the source of the code, shown on the positions
+	code string //source code of the program (used to compute the positions)
+	disabled map[LintRule]bool //rules that are not reported
+	findings []*LintFinding //findings until the current statement
+	offsets []int //offsets of the findings (used to sort them)
+	declared map[string]int //offset of the declaration of every variable visible on the current statement
+	local map[string]bool //variables declared on the current block
+	vars map[int]string //name of every declaration of the current function (or the main program) by its offset
+	read map[int]bool //offsets of the declarations read until the current statement
+	zero map[string]bool //variables known to be zero on the current statement
+}
+
+// addFinding saves a finding of a rule on an offset of the source code, if the rule is not disabled
+func (l *linter) addFinding(rule LintRule, offset int, msg string) {
+	if l.disabled[rule] {
+		return
+	}
+	l.findings = append(l.findings, &LintFinding{Rule: rule, Pos: newPosition(l.name, l.code, offset), Msg: msg})
+	l.offsets = append(l.offsets, offset)
+}
+
+// lintStmts looks for suspicious statements on a list of statements
+func (l *linter) lintStmts(stmts []stmt) {
+	for _, s := range stmts {
+		switch s.op {
+		case whileFuncSTRING:
+			l.lintWhile(s)
+		case procSTRING:
+			l.lintProc(s)
+		case declareOPSTRING:
+			l.lintValue(s)
+			if offset, ok := l.declared[s.name]; ok && !l.local[s.name] {
+				l.addFinding(LintShadowedVariable, s.offset, "variable '" + s.name + "' shadows the variable declared at " + newPosition(l.name, l.code, offset).String())
+			}
+			l.declared[s.name] = s.offset
+			l.local[s.name] = true
+			l.vars[s.offset] = s.name
+		case assignOPSTRING:
+			l.lintValue(s)
+		case printSTRING:
+			vars := []string{}
+			getValueVars(s.value, &vars)
+			for _, name := range vars {
+				l.markRead(name)
+			}
+		case readSTRING: //the value read is unknown
+			delete(l.zero, s.name)
+		}
+	}
+}
+
+// lintWhile looks for suspicious statements on a WHILE statement and its body
+func (l *linter) lintWhile(s stmt) {
+	condVars := []string{}
+	getLogicExprVars(s.cond, &condVars)
+
+	modified := map[string]bool{}
+	getModifiedVars(s.body, modified)
+
+	isModified := false
+	for _, name := range condVars {
+		l.markRead(name)
+		isModified = isModified || modified[name]
+	}
+	if !isModified && len(condVars) > 0 {
+		l.addFinding(LintUnmodifiedLoopCondition, s.offset, "variables " + quoteNames(condVars) + " of the loop condition are not modified in the body")
+	}
+
+	for name := range modified { //the body can be executed any number of times
+		delete(l.zero, name)
+	}
+	declared, local, zero := l.declared, l.local, map[string]bool{}
+	l.declared, l.local = map[string]int{}, map[string]bool{}
+	for name, offset := range declared {
+		l.declared[name] = offset
+	}
+	for name := range l.zero {
+		zero[name] = true
+	}
+
+	l.lintStmts(s.body)
+	for name := range l.local { //the variables shadowed by the body are not modified by it
+		if zero[name] {
+			l.zero[name] = true
+		} else {
+			delete(l.zero, name)
+		}
+	}
+	for name := range modified {
+		delete(l.zero, name)
+	}
+	l.declared, l.local = declared, local
+}
+
+// markRead saves that the visible declaration of a variable is read
+func (l *linter) markRead(name string) {
+	if offset, ok := l.declared[name]; ok {
+		l.read[offset] = true
+	}
+}
+
+// lintProc looks for suspicious statements on the body of a function, which has its own variables
+func (l *linter) lintProc(s stmt) {
+	declared, local, decls, read, zero := l.declared, l.local, l.vars, l.read, l.zero
+	l.declared, l.local, l.vars, l.read, l.zero = map[string]int{}, map[string]bool{}, map[int]string{}, map[int]bool{}, map[string]bool{}
+
+	l.lintStmts(s.body)
+	vars := []string{}
+	getValueVars(s.value, &vars)
+	for _, name := range vars {
+		l.markRead(name)
+	}
+	l.lintUnused()
+
+	l.declared, l.local, l.vars, l.read, l.zero = declared, local, decls, read, zero
+}
+
+// lintValue looks for suspicious values assigned to the variable of a declaration or an assignment
+func (l *linter) lintValue(s stmt) {
+	isZero := s.value.kind == numberValue && s.value.text == "0"
+
+	vars := []string{}
+	getValueVars(s.value, &vars)
+	if s.index != nil { //the array is used to assign its element (which can be out of its bounds)
+		addName(&vars, s.name)
+		getValueVars(s.index, &vars)
+	}
+	for _, name := range vars {
+		l.markRead(name)
+	}
+
+	if s.value.kind == callValue {
+		paramZero := false
+		if len(s.value.params) == 1 {
+			param := s.value.params[0]
+			paramZero = (param.kind == numberValue && param.text == "0") || (param.kind == varValue && l.zero[param.text])
+		}
+
+		switch s.value.text {
+		case "zero":
+			isZero = true
+		case "val":
+			isZero = paramZero
+			if s.op == assignOPSTRING && s.value.params[0].kind == varValue && s.value.params[0].text == s.name {
+				l.addFinding(LintSelfAssignment, s.offset, "variable '" + s.name + "' is assigned to itself")
+			}
+		case "dec":
+			if paramZero {
+				l.addFinding(LintDecOfZero, s.offset, "'" + s.value.params[0].text + "' is zero and can't be decremented")
+			}
+		}
+	}
+
+	if isZero && s.index == nil { //an array is never zero
+		l.zero[s.name] = true
+	} else {
+		delete(l.zero, s.name)
+	}
+}
+
+// quoteNames returns a list of names with the format "'a', 'b' and 'c'"
+// return string
+func quoteNames(names []string) string {
+	result := "'" + names[0] + "'"
+	for i, name := range names[1:] {
+		if i == len(names) - 2 {
+			result += " and '" + name + "'"
+		} else {
+			result += ", '" + name + "'"
+		}
+	}
+	return result
+}
+
+// getLogicExprVars saves on vars the variables used on a logic expression, in order of appearance and without repetitions
+func getLogicExprVars(expr *logicExpr, vars *[]string) {
+	if expr.left != nil {
+		getLogicExprVars(expr.left, vars)
+		if expr.right != nil {
+			getLogicExprVars(expr.right, vars)
+		}
+		return
+	}
+	getValueVars(expr.first, vars)
+	getValueVars(expr.second, vars)
+}
+
+// getValueVars saves on vars the variables used on a value, in order of appearance and without repetitions
+func getValueVars(v *valueExpr, vars *[]string) {
+	switch v.kind {
+	case varValue:
+		addName(vars, v.text)
+	case indexValue: //the array, and the variables of the index
+		addName(vars, v.text)
+		getValueVars(v.params[0], vars)
+	case callValue:
+		for _, param := range v.params {
+			getValueVars(param, vars)
+		}
+	}
+}
+
+// getModifiedVars saves on modified the variables of the enclosing blocks assigned on a loop body, with the arrays
+// whose elements are assigned (the variables declared on the body or a nested one are local to it, and not saved)
+func getModifiedVars(stmts []stmt, modified map[string]bool) {
+	local := map[string]bool{}
+	for _, s := range stmts {
+		switch s.op {
+		case whileFuncSTRING:
+			nested := map[string]bool{}
+			getModifiedVars(s.body, nested)
+			for name := range nested {
+				if !local[name] {
+					modified[name] = true
+				}
+			}
+		case declareOPSTRING:
+			local[s.name] = true
+		case assignOPSTRING, readSTRING: //the name of an assigned element (e.g. a[i] = 1) is its array
+			if !local[s.name] {
+				modified[s.name] = true
+			}
+		}
+	}
+}
+
+// lint looks for suspicious statements on a program, whose source code is given
+// return []*LintFinding
+func (p *program) lint(code string, disabled []LintRule) []*LintFinding {
+	l := &linter{name: p.name, code: code, disabled: map[LintRule]bool{}, declared: map[string]int{}, local: map[string]bool{}, vars: map[int]string{}, read: map[int]bool{}, zero: map[string]bool{}}
+	for _, rule := range disabled {
+		l.disabled[rule] = true
+	}
+
+	l.lintStmts(p.stmts)
+	l.lintUnused()
+
+	sort.Stable(l)
+	return l.findings
+}
+
+// lintUnused reports the declared variables that are never read
+func (l *linter) lintUnused() {
+	for offset, name := range l.vars {
+		if !l.read[offset] {
+			l.addFinding(LintUnusedVariable, offset, "variable '" + name + "' is declared but never read")
+		}
+	}
+}
+
+// Len, Less and Swap sort the findings of the linter by their offset
+func (l *linter) Len() int { return len(l.findings) }
+func (l *linter) Less(i, j int) bool { return l.offsets[i] < l.offsets[j] }
+func (l *linter) Swap(i, j int) {
+	l.findings[i], l.findings[j] = l.findings[j], l.findings[i]
+	l.offsets[i], l.offsets[j] = l.offsets[j], l.offsets[i]
+}
+
+// LintCode looks for suspicious statements on the code and returns every finding, except the ones of the disabled rules
+// the code must pass the static checker first, otherwise its errors are returned
+// return []*LintFinding, error
+func LintCode(code string, disabled ...LintRule) ([]*LintFinding, error) {
+	p := initProgram()
+	if err := p.getStmts(code); err != nil {
+		return nil, err
+	}
+	if errs := p.check(code); len(errs) > 0 {
+		return nil, errs
+	}
+	return p.lint(code, disabled), nil
+}
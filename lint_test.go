@@ -0,0 +1,137 @@
+package whileinterp
+
+import "testing"
+
+/*********************** TESTING ***********************/
+func TestLintCodeClean(t *testing.T) {
+    code := "xo := 2; x1 := inc(3); WHILE(xo != x1) DO xo = inc(xo) OD;"
+
+    doTestLintCode(code, nil, []string{}, t)
+}
+
+func TestLintCodeUnmodifiedLoopCondition(t *testing.T) {
+    code := "xo := 2; x1 := 3; x2 := 0; WHILE(xo < x1) DO x2 = inc(x2) OD;"
+    expecFindings := []string{
+        "1:28: variables 'xo' and 'x1' of the loop condition are not modified in the body (unmodified-loop-condition)",
+    }
+
+    doTestLintCode(code, nil, expecFindings, t)
+}
+
+func TestLintCodeUnmodifiedLogicExpr(t *testing.T) {
+    code := "xo := 2; x1 := 3; x2 := 0; x3 := 0; WHILE(xo < x1 OR NOT x2 == xo) DO x3 = inc(x3) OD; WHILE(x1 > x3) DO x1 = dec(x1) OD;"
+    expecFindings := []string{
+        "1:37: variables 'xo', 'x1' and 'x2' of the loop condition are not modified in the body (unmodified-loop-condition)",
+    }
+
+    doTestLintCode(code, nil, expecFindings, t)
+}
+
+func TestLintCodeUnusedVariable(t *testing.T) {
+    code := "xo := 2; x1 := inc(xo);"
+    expecFindings := []string{
+        "1:10: variable 'x1' is declared but never read (unused-variable)",
+    }
+
+    doTestLintCode(code, nil, expecFindings, t)
+}
+
+func TestLintCodeShadowedVariable(t *testing.T) {
+    code := "xo := 0; x1 := 0; WHILE(x1 < 2) DO xo := 1; x1 = inc(x1) OD; x2 := dec(xo); x1 = val(x2);"
+    expecFindings := []string{
+        "1:36: variable 'xo' shadows the variable declared at 1:1 (shadowed-variable)",
+        "1:36: variable 'xo' is declared but never read (unused-variable)",
+        "1:62: 'xo' is zero and can't be decremented (dec-of-zero)",
+    }
+
+    doTestLintCode(code, nil, expecFindings, t)
+}
+
+func TestLintCodeSelfAssignment(t *testing.T) {
+    code := "xo := 2; xo = val(xo);"
+    expecFindings := []string{
+        "1:10: variable 'xo' is assigned to itself (self-assignment)",
+    }
+
+    doTestLintCode(code, nil, expecFindings, t)
+}
+
+func TestLintCodeDecOfZero(t *testing.T) {
+    code := "xo := zero(); x1 := val(xo); x1 = dec(x1); xo = inc(x1); xo = dec(xo);"
+    expecFindings := []string{
+        "1:30: 'x1' is zero and can't be decremented (dec-of-zero)",
+    }
+
+    doTestLintCode(code, nil, expecFindings, t)
+}
+
+func TestLintCodeDecOfZeroLoop(t *testing.T) {
+    code := "xo := zero(); x1 := 3; WHILE(x1 > xo) DO x1 = dec(x1) OD; xo = dec(xo);"
+    expecFindings := []string{
+        "1:59: 'xo' is zero and can't be decremented (dec-of-zero)",
+    }
+
+    doTestLintCode(code, nil, expecFindings, t)
+}
+
+func TestLintCodeProc(t *testing.T) {
+    code := "PROC f(a) DO b := 0; d := val(a); b = dec(b) RETURN b OD; xo := f(2); x1 := val(xo);"
+    expecFindings := []string{
+        "1:22: variable 'd' is declared but never read (unused-variable)",
+        "1:35: 'b' is zero and can't be decremented (dec-of-zero)",
+        "1:71: variable 'x1' is declared but never read (unused-variable)",
+    }
+
+    doTestLintCode(code, nil, expecFindings, t)
+}
+
+func TestLintCodeArray(t *testing.T) {
+    codes := map[string][]string{
+        "a := array(1); a[0] = 5; WHILE(a[0] > 0) DO a[0] = dec(a[0]) OD": {},
+        "a := array(2); i := 0; WHILE(i < 2) DO a[i] = 1; i = inc(i) OD": {},
+        "a := array(2); i := 0; WHILE(a[i] < 2) DO WHILE(i < 1) DO a[i] = 3; i = inc(i) OD OD": {},
+        "a := array(2); i := 0; WHILE(a[1] > 0) DO a := array(1); a[0] = inc(i) OD": {
+            "1:24: variables 'a' of the loop condition are not modified in the body (unmodified-loop-condition)",
+            "1:43: variable 'a' shadows the variable declared at 1:1 (shadowed-variable)",
+        },
+        "a := array(2); b := array(1); x := val(b[0])": {
+            "1:1: variable 'a' is declared but never read (unused-variable)",
+            "1:31: variable 'x' is declared but never read (unused-variable)",
+        },
+    }
+    for code, expecFindings := range codes {
+        doTestLintCode(code, nil, expecFindings, t)
+    }
+}
+
+func TestLintCodeDisabled(t *testing.T) {
+    code := "xo := 2; xo = val(xo); x1 := 3;"
+    expecFindings := []string{
+        "1:10: variable 'xo' is assigned to itself (self-assignment)",
+    }
+
+    doTestLintCode(code, []LintRule{LintUnusedVariable}, expecFindings, t)
+}
+
+func TestLintCodeCheckErrors(t *testing.T) {
+    if _, err := LintCode("xo := inc(x1);"); err == nil {
+        t.Error("expected error not returned")
+    }
+}
+
+func doTestLintCode(code string, disabled []LintRule, expecFindings []string, t *testing.T) {
+    findings, err := LintCode(code, disabled...)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    if len(findings) != len(expecFindings) {
+        t.Error("unexpected returned findings:\n returned: ", findings, "\n expected: ", expecFindings)
+        return
+    }
+    for i, f := range findings {
+        if f.String() != expecFindings[i] {
+            t.Error("unexpected returned finding:\n returned: ", f, "\n expected: ", expecFindings[i])
+        }
+    }
+}
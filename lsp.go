@@ -0,0 +1,628 @@
+package whileinterp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+    Language Server Protocol server of the while programs, over a reader and a writer (e.g. stdin and stdout).
+
+    It offers:
+        - diagnostics of the parser and the static checker, every time a document is opened or changed (the
+          documents can call the functions registered on the interpreter of the server).
+        - hover of the variables (declaration site), the parameters, the functions and the libraries imported.
+        - go-to-definition of the variables, the parameters and the functions defined with PROC.
+        - formatting of the whole document, one statement per line (the comments are kept).
+        - completion of the reserved words and the predefined functions.
+
+    The documents are synchronized as a whole on every change.
+*/
+
+// lspMethodNotFound is the JSON-RPC error code of a request whose method is not implemented
+const lspMethodNotFound = -32601
+
+// lspSeverityError is the severity of the diagnostics of the errors found on the code
+const lspSeverityError = 1
+
+// lspKindFunction and lspKindKeyword are the kinds of the completion items
+const (
+	lspKindFunction = 3
+	lspKindKeyword = 14
+)
+
+// lspRequest is a request or a notification received by the server
+type lspRequest struct {
+	ID *json.RawMessage `json:"id"`
+	Method string `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// lspResponse is the response of the server to a request
+type lspResponse struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID *json.RawMessage `json:"id"`
+	Result interface{} `json:"result"`
+}
+
+// lspErrorResponse is the response of the server to a request that can't be answered
+type lspErrorResponse struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID *json.RawMessage `json:"id"`
+	Error lspError `json:"error"`
+}
+
+// lspError is the error of a request that can't be answered
+type lspError struct {
+	Code int `json:"code"`
+	Message string `json:"message"`
+}
+
+// lspNotification is a notification sent by the server
+type lspNotification struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method string `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+// lspPosition is a position of a document (line and character start at 0, the characters are counted in UTF-16)
+type lspPosition struct {
+	Line int `json:"line"`
+	Character int `json:"character"`
+}
+
+// lspRange is a range of a document
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End lspPosition `json:"end"`
+}
+
+// lspLocation is a range of a document given by its URI
+type lspLocation struct {
+	URI string `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+// lspDiagnostic is an error found on a document
+type lspDiagnostic struct {
+	Range lspRange `json:"range"`
+	Severity int `json:"severity"`
+	Source string `json:"source"`
+	Message string `json:"message"`
+}
+
+// lspTextEdit is a change of a document
+type lspTextEdit struct {
+	Range lspRange `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// lspCompletionItem is a proposal of the completion
+type lspCompletionItem struct {
+	Label string `json:"label"`
+	Kind int `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// lspHover is the information shown when the mouse is over a symbol
+type lspHover struct {
+	Contents string `json:"contents"`
+	Range lspRange `json:"range"`
+}
+
+// lspDocumentParams are the parameters of the requests about a document
+type lspDocumentParams struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+// lspSymbol is a use of a variable or a function on the code
+type lspSymbol struct {
+	offset int //offset of the symbol on the code
+	name string //name of the variable or the function
+	def int //offset of the definition of the symbol (-1 if it is not defined)
+	hover string //description of the symbol
+}
+
+// lspDecl is the declaration of a variable or a parameter
+type lspDecl struct {
+	offset int //offset of the declaration on the code
+	hover string //description of the declaration
+}
+
+// lspServer serves the requests of an editor about the documents of while programs
+type lspServer struct {
+	in *bufio.Reader //reader of the requests
+	out io.Writer //writer of the responses and the notifications
+	docs map[string]string //code of every opened document by its URI
+	funcs *Interpreter //interpreter of the documents (its registered functions can be called)
+	shutdown bool //the editor has asked the server to shut down
+}
+
+// ServeLSP runs a Language Server Protocol server, reading the requests from r and writing the responses on w,
+// until the editor sends the exit notification or r is closed
+// return error
+func ServeLSP(r io.Reader, w io.Writer) error {
+	return defaultInterpreter.ServeLSP(r, w)
+}
+
+// ServeLSP runs a Language Server Protocol server whose documents can call the functions of the interpreter
+// return error
+func (in *Interpreter) ServeLSP(r io.Reader, w io.Writer) error {
+	s := &lspServer{in: bufio.NewReader(r), out: w, docs: map[string]string{}, funcs: in}
+	for {
+		req, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("ServeLSP: exit before shutdown")
+			}
+			return nil
+		}
+		if err := s.handle(req); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads the next request
+// return *lspRequest, error
+func (s *lspServer) read() (*lspRequest, error) {
+	content, err := readMessage(s.in)
+	if err != nil {
+		return nil, err
+	}
+	req := &lspRequest{}
+	if err := json.Unmarshal(content, req); err != nil {
+		return nil, errors.New("ServeLSP: message not valid: " + err.Error())
+	}
+	return req, nil
+}
+
+// write writes a response or a notification
+// return error
+func (s *lspServer) write(msg interface{}) error {
+	return writeMessage(s.out, msg)
+}
+
+// readMessage reads the content of the next message, with the format "Content-Length: n\r\n\r\n{...}"
+// (the format of the messages of the language and the debug servers)
+// return []byte, error
+func readMessage(in *bufio.Reader) ([]byte, error) {
+	length := -1
+	for {
+		line, err := in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "Content-Length:") {
+			if length, err = strconv.Atoi(strings.TrimSpace(line[len("Content-Length:"):])); err != nil {
+				return nil, errors.New("readMessage: header '" + line + "' not valid")
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("readMessage: Content-Length header expected")
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(in, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// writeMessage writes a message as JSON with its Content-Length header
+// return error
+func writeMessage(out io.Writer, msg interface{}) error {
+	content := &bytes.Buffer{}
+	enc := json.NewEncoder(content)
+	enc.SetEscapeHTML(false) //the code has comparators like "<"
+	if err := enc.Encode(msg); err != nil {
+		return err
+	}
+
+	body := bytes.TrimSuffix(content.Bytes(), []byte("\n"))
+	_, err := io.WriteString(out, "Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + string(body))
+	return err
+}
+
+// handle answers a request or processes a notification
+// return error
+func (s *lspServer) handle(req *lspRequest) error {
+	params := &lspDocumentParams{}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, params); err != nil {
+			return errors.New("ServeLSP: parameters of '" + req.Method + "' not valid: " + err.Error())
+		}
+	}
+	uri := params.TextDocument.URI
+
+	var result interface{}
+	switch req.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": 1, //the whole document is sent on every change
+				"hoverProvider": true,
+				"definitionProvider": true,
+				"documentFormattingProvider": true,
+				"completionProvider": map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "whileinterp"},
+		}
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		s.docs[uri] = params.TextDocument.Text
+		return s.publishDiagnostics(uri)
+	case "textDocument/didChange":
+		if n := len(params.ContentChanges); n > 0 {
+			s.docs[uri] = params.ContentChanges[n - 1].Text
+		}
+		return s.publishDiagnostics(uri)
+	case "textDocument/didClose":
+		delete(s.docs, uri)
+		return s.write(&lspNotification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics", Params: map[string]interface{}{"uri": uri, "diagnostics": []lspDiagnostic{}}})
+	case "textDocument/hover":
+		if sym, code := s.symbolAt(uri, params.Position); sym != nil {
+			result = &lspHover{Contents: sym.hover, Range: lspSymbolRange(code, sym.offset)}
+		}
+	case "textDocument/definition":
+		if sym, code := s.symbolAt(uri, params.Position); sym != nil && sym.def != -1 {
+			result = &lspLocation{URI: uri, Range: lspSymbolRange(code, sym.def)}
+		}
+	case "textDocument/formatting":
+		result = s.format(uri)
+	case "textDocument/completion":
+		result = lspCompletion()
+	default:
+		if req.ID != nil { //the notifications not implemented are ignored
+			return s.write(&lspErrorResponse{JSONRPC: "2.0", ID: req.ID, Error: lspError{Code: lspMethodNotFound, Message: "method '" + req.Method + "' not implemented"}})
+		}
+		return nil
+	}
+
+	if req.ID == nil {
+		return nil
+	}
+	return s.write(&lspResponse{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+// publishDiagnostics sends the errors found by the parser and the static checker on a document
+// return error
+func (s *lspServer) publishDiagnostics(uri string) error {
+	code := s.docs[uri]
+	diagnostics := []lspDiagnostic{}
+
+	p := s.funcs.newProgram()
+	p.name = lspPath(uri) //the libraries are imported relative to the document
+	errs := CheckErrors{}
+	if err := p.getStmts(code); err != nil {
+		if ce, ok := err.(*CheckError); ok {
+			errs = append(errs, ce)
+		} else {
+			errs = append(errs, &CheckError{Pos: newPosition("", code, 0), Msg: err.Error()})
+		}
+	} else {
+		errs = p.check(code)
+	}
+
+	for _, e := range errs {
+		offset := lspErrorOffset(code, e.Pos)
+		diagnostics = append(diagnostics, lspDiagnostic{Range: lspSymbolRange(code, offset), Severity: lspSeverityError, Source: "whileinterp", Message: e.Msg})
+	}
+	return s.write(&lspNotification{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics", Params: map[string]interface{}{"uri": uri, "diagnostics": diagnostics}})
+}
+
+// lspPath returns the path of the file of a document (empty if the document is not a file)
+// return string
+func lspPath(uri string) string {
+	u, err := url.Parse(uri) //the path is percent-encoded (e.g. "my%20file.while")
+	if err != nil || u.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// symbolAt returns the symbol on a position of a document, and the code of the document
+// return *lspSymbol (nil if there is no symbol on the position), string
+func (s *lspServer) symbolAt(uri string, pos lspPosition) (*lspSymbol, string) {
+	code := s.docs[uri]
+	stmts, err := parseSource(lspPath(uri), code)
+	if err != nil {
+		return nil, code
+	}
+
+	offset := lspOffset(code, pos)
+	for _, sym := range lspSymbols(code, stmts) {
+		if sym.offset <= offset && offset <= sym.offset + len(sym.name) {
+			return sym, code
+		}
+	}
+	return nil, code
+}
+
+// format returns the change that formats a document
+// return []lspTextEdit (nil if the document has syntax errors)
+func (s *lspServer) format(uri string) []lspTextEdit {
+	code := s.docs[uri]
+	stmts, err := parseSource(lspPath(uri), code)
+	if err != nil {
+		return nil
+	}
+
+	whole := lspRange{Start: lspPosition{}, End: lspPositionOf(code, len(code))}
+	return []lspTextEdit{{Range: whole, NewText: formatCode(code, stmts)}}
+}
+
+// lspCompletion returns the reserved words and the predefined functions (with array)
+// return []lspCompletionItem
+func lspCompletion() []lspCompletionItem {
+	items := []lspCompletionItem{}
+	for _, k := range keywords {
+		items = append(items, lspCompletionItem{Label: k, Kind: lspKindKeyword})
+	}
+	for _, f := range possFunc {
+		items = append(items, lspCompletionItem{Label: f, Kind: lspKindFunction, Detail: "predefined function with " + strconv.Itoa(funcParams(f)) + " parameter(s)"})
+	}
+	items = append(items, lspCompletionItem{Label: arraySTRING, Kind: lspKindFunction, Detail: "declaration of an array with 1 parameter(s)"})
+	return items
+}
+
+// lspSymbols returns every use of a variable or a function on a list of statements, sorted by offset
+// return []*lspSymbol
+func lspSymbols(code string, stmts []stmt) []*lspSymbol {
+	r := &lspResolver{code: code, procs: map[string]stmt{}, imported: map[string]*stmt{}}
+	for _, s := range stmts {
+		if _, ok := r.procs[s.name]; s.op == procSTRING && !ok {
+			r.procs[s.name] = s
+		}
+		if s.op != importSTRING {
+			continue
+		}
+		for i, proc := range s.lib.stmts {
+			if proc.op == procSTRING {
+				r.imported[s.lib.qualify(proc.name)] = &s.lib.stmts[i]
+			}
+		}
+	}
+
+	decls := map[string]lspDecl{}
+	r.collectDecls(stmts, decls)
+	r.resolveStmts(stmts, decls)
+
+	sort.Slice(r.symbols, func(i, j int) bool { return r.symbols[i].offset < r.symbols[j].offset })
+	return r.symbols
+}
+
+// lspResolver relates the uses of the variables and the functions of a program with their definitions
+type lspResolver struct {
+	code string //source code of the program
+	procs map[string]stmt //functions defined on the program
+	imported map[string]*stmt //functions imported from the libraries
+	symbols []*lspSymbol //symbols found until the current statement
+}
+
+// collectDecls saves on decls the first declaration of every variable of a block that isn't visible yet, so the uses
+// before the declaration are related with it (the bodies of the WHILEs and the PROCs have their own variables)
+func (r *lspResolver) collectDecls(stmts []stmt, decls map[string]lspDecl) {
+	for _, s := range stmts {
+		if _, ok := decls[s.name]; s.op == declareOPSTRING && !ok {
+			decls[s.name] = r.newDecl(s)
+		}
+	}
+}
+
+// newDecl returns the declaration of a variable
+// return lspDecl
+func (r *lspResolver) newDecl(s stmt) lspDecl {
+	kind := "variable"
+	if isArrayDecl(s) {
+		kind = "array"
+	}
+	return lspDecl{offset: s.offset, hover: kind + " '" + s.name + "' declared at " + newPosition("", r.code, s.offset).String()}
+}
+
+// addVar saves the use of a variable, whose declaration is searched on decls
+func (r *lspResolver) addVar(offset int, name string, decls map[string]lspDecl) {
+	sym := &lspSymbol{offset: offset, name: name, def: -1, hover: "variable '" + name + "' not declared"}
+	if decl, ok := decls[name]; ok {
+		sym.def, sym.hover = decl.offset, decl.hover
+	}
+	r.symbols = append(r.symbols, sym)
+}
+
+// resolveStmts saves the symbols of a list of statements, whose variables are declared on decls
+func (r *lspResolver) resolveStmts(stmts []stmt, decls map[string]lspDecl) {
+	for _, s := range stmts {
+		switch s.op {
+		case whileFuncSTRING:
+			r.resolveLogicExpr(s.cond, decls)
+			body := map[string]lspDecl{} //the variables declared on the body are local to it
+			for name, decl := range decls {
+				body[name] = decl
+			}
+			r.collectDecls(s.body, body)
+			r.resolveStmts(s.body, body)
+		case printSTRING:
+			r.resolveValue(s.value, decls)
+		case readSTRING:
+			r.addVar(s.nameOffset, s.name, decls)
+		case declareOPSTRING:
+			r.resolveValue(s.value, decls) //the value is resolved before the variable is declared
+			decls[s.name] = r.newDecl(s) //a variable of an enclosing block is shadowed from here
+			r.addVar(s.offset, s.name, decls)
+		case importSTRING:
+			procs := []string{}
+			for _, proc := range s.lib.stmts {
+				if proc.op == procSTRING {
+					procs = append(procs, s.lib.qualify(proc.name))
+				}
+			}
+			name := s.name
+			if s.path != "" {
+				name = "\"" + s.path + "\""
+			}
+			hover := "library '" + s.lib.name + "' with the functions " + strings.Join(procs, ", ")
+			r.symbols = append(r.symbols, &lspSymbol{offset: s.nameOffset, name: name, def: -1, hover: hover})
+		case procSTRING:
+			hover := procSTRING + " " + s.name + "(" + strings.Join(s.params, ", ") + ") defined at " + newPosition("", r.code, s.nameOffset).String()
+			r.symbols = append(r.symbols, &lspSymbol{offset: s.nameOffset, name: s.name, def: s.nameOffset, hover: hover})
+
+			procDecls := map[string]lspDecl{} //the function only knows its parameters and its variables
+			for i, param := range s.params {
+				if _, ok := procDecls[param]; !ok {
+					pos := newPosition("", r.code, s.paramOffsets[i]).String()
+					procDecls[param] = lspDecl{offset: s.paramOffsets[i], hover: "parameter '" + param + "' of " + procSTRING + " " + s.name + " declared at " + pos}
+				}
+			}
+			r.collectDecls(s.body, procDecls)
+			for i, param := range s.params {
+				r.addVar(s.paramOffsets[i], param, procDecls)
+			}
+			r.resolveStmts(s.body, procDecls)
+			r.resolveValue(s.value, procDecls)
+		default:
+			r.addVar(s.offset, s.name, decls)
+			if s.index != nil {
+				r.resolveValue(s.index, decls)
+			}
+			r.resolveValue(s.value, decls)
+		}
+	}
+}
+
+// resolveLogicExpr saves the symbols of a logic expression
+func (r *lspResolver) resolveLogicExpr(l *logicExpr, decls map[string]lspDecl) {
+	if l.left != nil {
+		r.resolveLogicExpr(l.left, decls)
+		if l.right != nil {
+			r.resolveLogicExpr(l.right, decls)
+		}
+		return
+	}
+	r.resolveValue(l.first, decls)
+	r.resolveValue(l.second, decls)
+}
+
+// resolveValue saves the symbols of a value
+func (r *lspResolver) resolveValue(v *valueExpr, decls map[string]lspDecl) {
+	switch v.kind {
+	case varValue:
+		r.addVar(v.offset, v.text, decls)
+	case indexValue:
+		r.addVar(v.offset, v.text, decls)
+		r.resolveValue(v.params[0], decls)
+	case callValue:
+		sym := &lspSymbol{offset: v.offset, name: v.text, def: -1, hover: "function '" + v.text + "' not defined"}
+		if proc, ok := r.procs[v.text]; ok {
+			sym.def = proc.nameOffset
+			sym.hover = procSTRING + " " + proc.name + "(" + strings.Join(proc.params, ", ") + ") defined at " + newPosition("", r.code, proc.nameOffset).String()
+		} else if proc, ok := r.imported[v.text]; ok {
+			sym.hover = procSTRING + " " + proc.name + "(" + strings.Join(proc.params, ", ") + ") of library '" + proc.lib.name + "'"
+		} else if params := funcParams(v.text); params != -1 {
+			sym.hover = "predefined function '" + v.text + "' with " + strconv.Itoa(params) + " parameter(s)"
+		} else if v.text == arraySTRING {
+			sym.hover = "predefined function '" + arraySTRING + "' with 1 parameter(s), which declares an array"
+		}
+		r.symbols = append(r.symbols, sym)
+
+		for _, param := range v.params {
+			r.resolveValue(param, decls)
+		}
+	}
+}
+
+// lspPositionOf returns the position of an offset of a code
+// return lspPosition
+func lspPositionOf(code string, offset int) lspPosition {
+	lineStart := strings.LastIndex(code[:offset], "\n") + 1
+	character := 0
+	for _, c := range code[lineStart:offset] {
+		character++
+		if c >= 0x10000 { //the character takes two UTF-16 units
+			character++
+		}
+	}
+	return lspPosition{Line: strings.Count(code[:offset], "\n"), Character: character}
+}
+
+// lspErrorOffset returns the offset of the position of an error on a code, whose column counts bytes (see newPosition)
+// return int
+func lspErrorOffset(code string, pos Position) int {
+	offset := 0
+	for line := 1; line < pos.Line; line++ {
+		i := strings.IndexByte(code[offset:], '\n')
+		if i == -1 {
+			return len(code)
+		}
+		offset += i + 1
+	}
+
+	if offset += pos.Column - 1; offset > len(code) {
+		return len(code)
+	}
+	return offset
+}
+
+// lspOffset returns the offset of a position of a code (the end of the line or the code if the position is beyond)
+// return int
+func lspOffset(code string, pos lspPosition) int {
+	offset := 0
+	for line := 0; line < pos.Line; line++ {
+		i := strings.IndexByte(code[offset:], '\n')
+		if i == -1 {
+			return len(code)
+		}
+		offset += i + 1
+	}
+
+	character := 0
+	for i, c := range code[offset:] {
+		if c == '\n' || character >= pos.Character {
+			return offset + i
+		}
+		character++
+		if c >= 0x10000 {
+			character++
+		}
+	}
+	return len(code)
+}
+
+// lspSymbolRange returns the range of the word that starts on an offset of a code
+// return lspRange
+func lspSymbolRange(code string, offset int) lspRange {
+	end := offset
+	for end < len(code) && isIdentChar(rune(code[end])) {
+		end++
+	}
+	if end == offset && end < len(code) { //e.g. an operator
+		end++
+	}
+	return lspRange{Start: lspPositionOf(code, offset), End: lspPositionOf(code, end)}
+}
@@ -0,0 +1,196 @@
+package whileinterp
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "io"
+    "net/url"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "testing"
+)
+
+const testLSPCode = "PROC add(a, b) DO\n  WHILE(b > 0) DO a = inc(a); b = dec(b) OD RETURN a OD\n# sum\nxo := add(2, 3); xo = inc(xo)\n"
+
+/*********************** TESTING ***********************/
+func TestServeLSPDiagnostics(t *testing.T) {
+    responses := doTestServeLSP([]string{
+        `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"capabilities":{}}}`,
+        `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.while","text":"xo := 2\nx1 = inc(y)"}}}`,
+        `{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///a.while"},"contentChanges":[{"text":"xo := 2 x1"}]}}`,
+    }, t)
+    expecResponses := []string{
+        `"hoverProvider":true`,
+        `{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"diagnostics":[` +
+            `{"range":{"start":{"line":1,"character":0},"end":{"line":1,"character":2}},"severity":1,"source":"whileinterp","message":"assignment to undeclared variable 'x1'"},` +
+            `{"range":{"start":{"line":1,"character":9},"end":{"line":1,"character":10}},"severity":1,"source":"whileinterp","message":"variable 'y' used before its declaration"}],` +
+            `"uri":"file:///a.while"}}`,
+        `"message":"';' expected, found 'x1'"`,
+    }
+
+    doTestLSPResponses(responses, expecResponses, t)
+}
+
+func TestServeLSPInterpreter(t *testing.T) {
+    dir := doTestLibraryFiles(map[string]string{"my dir/lib.while": "PROC f(a) DO RETURN inc(a) OD"}, t)
+    defer os.RemoveAll(dir)
+    uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "my dir", "main.while"))}).String()
+    if !strings.Contains(uri, "my%20dir") {
+        t.Fatal("expected an encoded URI, returned: ", uri)
+    }
+
+    interp := NewInterpreter()
+    interp.RegisterCantor()
+    responses := doTestServeLSPInterpreter(interp, []string{
+        `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"` + uri + `","text":"IMPORT \"lib.while\"; x := pair(lib.f(1), 2)"}}}`,
+        `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.while","text":"x := pair(1, 2)"}}}`,
+    }, t)
+    expecResponses := []string{
+        `"diagnostics":[]`,
+        `"diagnostics":[]`,
+    }
+
+    doTestLSPResponses(responses, expecResponses, t)
+}
+
+func TestServeLSPDiagnosticsUnicode(t *testing.T) {
+    dir := doTestLibraryFiles(map[string]string{"é.while": "PROC f(a) DO RETURN a OD"}, t)
+    defer os.RemoveAll(dir)
+    uri := (&url.URL{Scheme: "file", Path: filepath.ToSlash(filepath.Join(dir, "main.while"))}).String()
+
+    responses := doTestServeLSP([]string{
+        `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"` + uri + `","text":"IMPORT \"é.while\"; x := y"}}}`,
+    }, t)
+    expecResponses := []string{
+        `"diagnostics":[{"range":{"start":{"line":0,"character":23},"end":{"line":0,"character":24}},"severity":1,"source":"whileinterp","message":"number or function call expected, found 'y' (use val(y))"}]`,
+    }
+
+    doTestLSPResponses(responses, expecResponses, t)
+}
+
+func TestServeLSPHoverDefinition(t *testing.T) {
+    responses := doTestServeLSP([]string{
+        `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.while","text":` + strconv.Quote(testLSPCode) + `}}}`,
+        `{"jsonrpc":"2.0","id":2,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.while"},"position":{"line":3,"character":7}}}`,
+        `{"jsonrpc":"2.0","id":3,"method":"textDocument/definition","params":{"textDocument":{"uri":"file:///a.while"},"position":{"line":3,"character":26}}}`,
+        `{"jsonrpc":"2.0","id":4,"method":"textDocument/definition","params":{"textDocument":{"uri":"file:///a.while"},"position":{"line":1,"character":27}}}`,
+        `{"jsonrpc":"2.0","id":5,"method":"textDocument/hover","params":{"textDocument":{"uri":"file:///a.while"},"position":{"line":1,"character":22}}}`,
+        `{"jsonrpc":"2.0","id":6,"method":"textDocument/definition","params":{"textDocument":{"uri":"file:///a.while"},"position":{"line":2,"character":2}}}`,
+    }, t)
+    expecResponses := []string{
+        `"diagnostics":[]`,
+        `{"jsonrpc":"2.0","id":2,"result":{"contents":"PROC add(a, b) defined at 1:6","range":{"start":{"line":3,"character":6},"end":{"line":3,"character":9}}}}`,
+        `{"jsonrpc":"2.0","id":3,"result":{"uri":"file:///a.while","range":{"start":{"line":3,"character":0},"end":{"line":3,"character":2}}}}`,
+        `{"jsonrpc":"2.0","id":4,"result":{"uri":"file:///a.while","range":{"start":{"line":0,"character":9},"end":{"line":0,"character":10}}}}`,
+        `{"jsonrpc":"2.0","id":5,"result":{"contents":"predefined function 'inc' with 1 parameter(s)"`,
+        `{"jsonrpc":"2.0","id":6,"result":null}`,
+    }
+
+    doTestLSPResponses(responses, expecResponses, t)
+}
+
+func TestServeLSPFormattingCompletion(t *testing.T) {
+    responses := doTestServeLSP([]string{
+        `{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.while","text":` + strconv.Quote(testLSPCode) + `}}}`,
+        `{"jsonrpc":"2.0","id":2,"method":"textDocument/formatting","params":{"textDocument":{"uri":"file:///a.while"},"options":{}}}`,
+        `{"jsonrpc":"2.0","id":3,"method":"textDocument/completion","params":{"textDocument":{"uri":"file:///a.while"},"position":{"line":0,"character":0}}}`,
+        `{"jsonrpc":"2.0","id":4,"method":"textDocument/rename","params":{}}`,
+        `{"jsonrpc":"2.0","id":5,"method":"shutdown"}`,
+        `{"jsonrpc":"2.0","method":"exit"}`,
+    }, t)
+    expecText := "PROC add(a, b) DO\n    WHILE(b > 0) DO\n        a = inc(a)\n        b = dec(b)\n    OD\n    RETURN a\nOD\n# sum\nxo := add(2, 3)\nxo = inc(xo)\n"
+    expecResponses := []string{
+        `"diagnostics":[]`,
+        `{"jsonrpc":"2.0","id":2,"result":[{"range":{"start":{"line":0,"character":0},"end":{"line":4,"character":0}},"newText":` + strconv.Quote(expecText) + `}]}`,
+        `{"label":"WHILE","kind":14},`,
+        `"code":-32601`,
+        `{"jsonrpc":"2.0","id":5,"result":null}`,
+    }
+
+    doTestLSPResponses(responses, expecResponses, t)
+}
+
+func TestFormatCodeComments(t *testing.T) {
+    code := "# start\nxo := 2 // two\nWHILE(xo > 0) DO # loop\n  xo = dec(xo); # next\n  # end of the body\nOD; x1 := 1\n# end\n"
+    expecCode := "# start\nxo := 2 // two\nWHILE(xo > 0) DO\n    # loop\n    xo = dec(xo) # next\n    # end of the body\nOD\nx1 := 1\n# end\n"
+
+    stmts, err := parseCode(code)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    if retCode := formatCode(code, stmts); retCode != expecCode {
+        t.Error("unexpected returned code:\n returned: ", retCode, "\n expected: ", expecCode)
+    }
+}
+
+func doTestServeLSP(requests []string, t *testing.T) []string {
+    return doTestServeLSPInterpreter(defaultInterpreter, requests, t)
+}
+
+// doTestServeLSPInterpreter serves the requests with an interpreter and returns the responses
+// return []string
+func doTestServeLSPInterpreter(interp *Interpreter, requests []string, t *testing.T) []string {
+    in := &bytes.Buffer{}
+    for _, req := range requests {
+        in.WriteString("Content-Length: " + strconv.Itoa(len(req)) + "\r\n\r\n" + req)
+    }
+    out := &bytes.Buffer{}
+    if err := interp.ServeLSP(in, out); err != nil {
+        t.Error(err)
+    }
+
+    responses := []string{}
+    r := bufio.NewReader(out)
+    for {
+        header, err := r.ReadString('\n')
+        if err != nil {
+            return responses
+        }
+        length, _ := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "Content-Length:")))
+        r.ReadString('\n')
+        content := make([]byte, length)
+        io.ReadFull(r, content)
+        if !json.Valid(content) {
+            t.Error("response not valid: ", string(content))
+        }
+        responses = append(responses, string(content))
+    }
+}
+
+func doTestLSPResponses(responses []string, expecResponses []string, t *testing.T) {
+    if len(responses) != len(expecResponses) {
+        t.Error("unexpected returned responses:\n returned: ", responses, "\n expected: ", expecResponses)
+        return
+    }
+    for i, resp := range responses {
+        if !strings.Contains(resp, expecResponses[i]) {
+            t.Error("unexpected returned response:\n returned: ", resp, "\n expected: ", expecResponses[i])
+        }
+    }
+}
+
+func TestLSPSymbolsShadowed(t *testing.T) {
+    code := "x := 1; WHILE(x < 2) DO x := inc(x); x = val(x) OD; x = 2"
+    p := initProgram()
+    if err := p.getStmts(code); err != nil {
+        t.Fatal(err)
+    }
+    inner := strings.Index(code, "x := inc")
+    expecDefs := map[int]int{
+        strings.Index(code, "x < 2"): 0,
+        strings.Index(code, "inc(x)") + 4: 0, //the value is resolved before the declaration
+        strings.Index(code, "x = val"): inner,
+        strings.Index(code, "val(x)") + 4: inner,
+        strings.LastIndex(code, "x = 2"): 0,
+    }
+
+    for _, sym := range lspSymbols(code, p.stmts) {
+        if def, ok := expecDefs[sym.offset]; ok && sym.def != def {
+            t.Error("unexpected definition of '", sym.name, "' at ", sym.offset, ": ", sym.def, ", expected: ", def)
+        }
+    }
+}
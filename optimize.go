@@ -0,0 +1,341 @@
+package whileinterp
+
+import (
+	"errors"
+	"strconv"
+)
+
+// OptPass is the name of a pass of the optimization pipeline
+type OptPass string
+
+// OptConstProp replaces the variables whose value is a known constant by their value (e.g. x := 2; y := inc(x) -> y := inc(2))
+const OptConstProp OptPass = "const-prop"
+
+// OptFold replaces the calls of inc, dec, val and zero on constants by their result (e.g. inc(2) -> 3)
+const OptFold OptPass = "fold"
+
+// OptDeadLoops removes the loops whose condition is always false
+const OptDeadLoops OptPass = "dead-loops"
+
+// OptDeadStores removes the declarations and assignments of the variables that are never read (except x0, the result)
+const OptDeadStores OptPass = "dead-stores"
+
+// OptPasses lists every pass of the optimization pipeline, in the order they are applied
+var OptPasses = [...]OptPass{OptConstProp, OptFold, OptDeadLoops, OptDeadStores}
+
+// optimizer applies the passes of the optimization pipeline to the statements of a program
+type optimizer struct {
+	name string //name of the source of the code
+	code string //source code of the program (used by the interval analysis)
+}
+
+// propagateStmts replaces the variables whose value is known by their value on a list of statements
+// return []stmt (the optimized statements)
+func (o *optimizer) propagateStmts(stmts []stmt, known map[string]int) []stmt {
+	result := make([]stmt, len(stmts))
+	for i, s := range stmts {
+		switch s.op {
+		case procSTRING: //the functions only know their parameters
+			body := map[string]int{}
+			s.body = o.propagateStmts(s.body, body)
+			s.value = propagateValue(s.value, body)
+		case importSTRING:
+		case printSTRING:
+			s.value = propagateValue(s.value, known)
+		case readSTRING: //the value read is unknown
+			delete(known, s.name)
+		case whileFuncSTRING: //the variables modified by the body are unknown on every iteration
+			modified := map[string]bool{}
+			getModifiedVars(s.body, modified)
+			for name := range modified {
+				delete(known, name)
+			}
+			body := map[string]int{}
+			for name, n := range known {
+				body[name] = n
+			}
+			s.cond = propagateLogicExpr(s.cond, known)
+			s.body = o.propagateStmts(s.body, body)
+		default:
+			s.value = propagateValue(s.value, known)
+			if s.index != nil {
+				s.index = propagateValue(s.index, known)
+			}
+			if n, ok := constValue(s.value); ok && n >= 0 && s.index == nil { //the elements of an array aren't known
+				known[s.name] = n
+			} else {
+				delete(known, s.name)
+			}
+		}
+		result[i] = s
+	}
+	return result
+}
+
+// propagateLogicExpr replaces the variables whose value is known by their value on a logic expression
+// return *logicExpr (a copy of the logic expression)
+func propagateLogicExpr(l *logicExpr, known map[string]int) *logicExpr {
+	result := *l
+	if l.left != nil {
+		result.left = propagateLogicExpr(l.left, known)
+		if l.right != nil {
+			result.right = propagateLogicExpr(l.right, known)
+		}
+		return &result
+	}
+	result.first = propagateValue(l.first, known)
+	result.second = propagateValue(l.second, known)
+	return &result
+}
+
+// propagateValue replaces the variables whose value is known by their value on a value
+// return *valueExpr (a copy of the value)
+func propagateValue(v *valueExpr, known map[string]int) *valueExpr {
+	result := *v
+	switch v.kind {
+	case varValue:
+		if n, ok := known[v.text]; ok {
+			return &valueExpr{kind: numberValue, text: strconv.Itoa(n), offset: v.offset}
+		}
+	case callValue, indexValue: //the array of an element is kept
+		result.params = make([]*valueExpr, len(v.params))
+		for i, param := range v.params {
+			result.params[i] = propagateValue(param, known)
+		}
+	}
+	return &result
+}
+
+// constValue returns the value of a number or a call of the predefined functions on numbers
+// return int, bool (false if the value isn't constant)
+func constValue(v *valueExpr) (int, bool) {
+	switch v.kind {
+	case numberValue:
+		n, err := strconv.Atoi(v.text)
+		return n, err == nil
+	case varValue, indexValue:
+		return 0, false
+	}
+	if funcIndex(v.text) == -1 { //the functions defined with PROC and the registered ones are never folded
+		return 0, false
+	}
+
+	params := make([]int, len(v.params))
+	for i, param := range v.params {
+		n, ok := constValue(param)
+		if !ok {
+			return 0, false
+		}
+		params[i] = n
+	}
+	n, err := execFunc(v.text, params)
+	return n, err == nil
+}
+
+// foldStmts replaces the calls of the predefined functions on numbers by their result on a list of statements
+// return []stmt (the optimized statements)
+func (o *optimizer) foldStmts(stmts []stmt) []stmt {
+	result := make([]stmt, len(stmts))
+	for i, s := range stmts {
+		if s.cond != nil {
+			s.cond = foldLogicExpr(s.cond)
+		}
+		if s.value != nil {
+			s.value = foldValue(s.value)
+		}
+		if s.index != nil {
+			s.index = foldValue(s.index)
+		}
+		s.body = o.foldStmts(s.body)
+		result[i] = s
+	}
+	return result
+}
+
+// foldLogicExpr replaces the calls of the predefined functions on numbers by their result on a logic expression
+// return *logicExpr (a copy of the logic expression)
+func foldLogicExpr(l *logicExpr) *logicExpr {
+	result := *l
+	if l.left != nil {
+		result.left = foldLogicExpr(l.left)
+		if l.right != nil {
+			result.right = foldLogicExpr(l.right)
+		}
+		return &result
+	}
+	result.first = foldValue(l.first)
+	result.second = foldValue(l.second)
+	return &result
+}
+
+// foldValue replaces the calls of the predefined functions on numbers by their result on a value
+// return *valueExpr (a copy of the value)
+func foldValue(v *valueExpr) *valueExpr {
+	if v.kind != callValue && v.kind != indexValue {
+		return v
+	}
+	if n, ok := constValue(v); ok && n >= 0 { //the negative numbers can't be written on the code
+		return &valueExpr{kind: numberValue, text: strconv.Itoa(n), offset: v.offset}
+	}
+
+	result := *v
+	result.params = make([]*valueExpr, len(v.params))
+	for i, param := range v.params {
+		result.params[i] = foldValue(param)
+	}
+	return &result
+}
+
+// removeDeadLoops removes the loops whose condition is always false, found with the interval analysis
+// return []stmt (the optimized statements)
+func (o *optimizer) removeDeadLoops(stmts []stmt) []stmt {
+	p := initProgram()
+	p.name = o.name
+	p.stmts = stmts
+	conds := p.newRangeAnalyzer(o.code).conds
+
+	var remove func(stmts []stmt) []stmt
+	remove = func(stmts []stmt) []stmt {
+		result := []stmt{}
+		for _, s := range stmts {
+			if s.op == whileFuncSTRING {
+				funcs := []string{}
+				getLogicExprFuncs(s.cond, &funcs)
+				if cc, ok := conds[s.offset]; ok && !cc.Value && len(funcs) == 0 { //the functions called may not terminate
+					continue
+				}
+			}
+			s.body = remove(s.body)
+			result = append(result, s)
+		}
+		return result
+	}
+	return remove(stmts)
+}
+
+// removeDeadStores removes the declarations and assignments of the variables never read, until every variable is read
+// return []stmt (the optimized statements)
+func (o *optimizer) removeDeadStores(stmts []stmt) []stmt {
+	result := make([]stmt, len(stmts))
+	for i, s := range stmts {
+		if s.op == procSTRING { //the functions read the variables of their returned value
+			read := []string{}
+			getValueVars(s.value, &read)
+			s.body = removeUnread(s.body, read)
+		}
+		result[i] = s
+	}
+	return removeUnread(result, []string{resultVarSTRING})
+}
+
+// removeUnread removes the declarations and assignments of the variables never read on a list of statements
+// return []stmt (the optimized statements)
+func removeUnread(stmts []stmt, outputs []string) []stmt {
+	for {
+		read := map[string]bool{}
+		vars := append([]string{}, outputs...)
+		getReadVars(stmts, &vars)
+		getCallingVars(stmts, &vars) //the variables of the values that call functions are kept, with their declaration
+		for _, name := range vars {
+			read[name] = true
+		}
+
+		removed := false
+		var remove func(stmts []stmt) []stmt
+		remove = func(stmts []stmt) []stmt {
+			result := []stmt{}
+			for _, s := range stmts {
+				switch s.op {
+				case whileFuncSTRING:
+					s.body = remove(s.body)
+				case declareOPSTRING, assignOPSTRING:
+					if !read[s.name] {
+						removed = true
+						continue
+					}
+				}
+				result = append(result, s)
+			}
+			return result
+		}
+		stmts = remove(stmts)
+		if !removed {
+			return stmts
+		}
+	}
+}
+
+// getCallingVars saves on vars the variables declared or assigned with a value that calls a function defined with PROC
+// (the functions called may not terminate), and the arrays declared or assigned (their sizes and indices may fail)
+func getCallingVars(stmts []stmt, vars *[]string) {
+	for _, s := range stmts {
+		switch s.op {
+		case procSTRING, importSTRING, printSTRING, readSTRING: //the PRINTs and the READs are never removed
+			continue
+		case whileFuncSTRING:
+			getCallingVars(s.body, vars)
+		default:
+			funcs := []string{}
+			getValueFuncs(s.value, &funcs)
+			if s.index != nil {
+				getValueFuncs(s.index, &funcs)
+			}
+			if len(funcs) > 0 || s.index != nil || isArrayDecl(s) {
+				addName(vars, s.name)
+			}
+		}
+	}
+}
+
+// getReadVars saves on vars the variables read on a list of statements (not on the functions defined)
+func getReadVars(stmts []stmt, vars *[]string) {
+	for _, s := range stmts {
+		switch s.op {
+		case procSTRING, importSTRING:
+			continue
+		case whileFuncSTRING:
+			getLogicExprVars(s.cond, vars)
+			getReadVars(s.body, vars)
+		case readSTRING: //the variable read keeps its declaration, since the READ is never removed
+			addName(vars, s.name)
+		default:
+			getValueVars(s.value, vars)
+			if s.index != nil { //the index of an assigned element is read
+				getValueVars(s.index, vars)
+			}
+		}
+	}
+}
+
+// Optimize applies the given passes of the optimization pipeline (in the order of OptPasses) to the program
+// and returns the optimized program, whose code is formatted with one statement per line
+// return *Program, error
+func (prog *Program) Optimize(passes ...OptPass) (*Program, error) {
+	enabled := map[OptPass]bool{}
+	for _, pass := range passes {
+		defined := false
+		for _, p := range OptPasses {
+			defined = defined || p == pass
+		}
+		if !defined {
+			return nil, errors.New("Optimize: optimization pass '" + string(pass) + "' not defined")
+		}
+		enabled[pass] = true
+	}
+
+	o := &optimizer{name: prog.name, code: prog.code}
+	stmts := prog.stmts
+	if enabled[OptConstProp] {
+		stmts = o.propagateStmts(stmts, map[string]int{})
+	}
+	if enabled[OptFold] {
+		stmts = o.foldStmts(stmts)
+	}
+	if enabled[OptDeadLoops] {
+		stmts = o.removeDeadLoops(stmts)
+	}
+	if enabled[OptDeadStores] {
+		stmts = o.removeDeadStores(stmts)
+	}
+	return prog.funcs.parseProgramCode(prog.name, formatCode(prog.code, stmts)) //the code is parsed to know the new positions
+}
@@ -0,0 +1,211 @@
+package whileinterp
+
+import "testing"
+
+const testOptCode = `# constants
+a := 2
+b := inc(a)
+c := 0
+WHILE(c > b) DO
+    c = dec(c)
+OD
+x0 := 0
+WHILE(x0 < b) DO
+    x0 = inc(x0)
+    c = val(a)
+OD
+`
+
+/*********************** TESTING ***********************/
+func TestOptimizeConstProp(t *testing.T) {
+    expec := `# constants
+a := 2
+b := inc(2)
+c := 0
+WHILE(c > 3) DO
+    c = dec(c)
+OD
+x0 := 0
+WHILE(x0 < 3) DO
+    x0 = inc(x0)
+    c = val(2)
+OD
+`
+
+    doTestOptimize(testOptCode, []OptPass{OptConstProp}, expec, t)
+}
+
+func TestOptimizeFold(t *testing.T) {
+    expec := `# constants
+a := 2
+b := 3
+c := 0
+WHILE(c > 3) DO
+    c = dec(c)
+OD
+x0 := 0
+WHILE(x0 < 3) DO
+    x0 = inc(x0)
+    c = 2
+OD
+`
+
+    doTestOptimize(testOptCode, []OptPass{OptFold, OptConstProp}, expec, t)
+}
+
+func TestOptimizeDeadLoops(t *testing.T) {
+    expec := `# constants
+a := 2
+b := inc(a)
+c := 0
+x0 := 0
+WHILE(x0 < b) DO
+    x0 = inc(x0)
+    c = val(a)
+OD
+`
+
+    doTestOptimize(testOptCode, []OptPass{OptDeadLoops}, expec, t)
+}
+
+func TestOptimizeDeadStores(t *testing.T) {
+    expec := `# constants
+a := 2
+b := inc(a)
+x0 := 0
+WHILE(x0 < b) DO
+    x0 = inc(x0)
+OD
+`
+
+    doTestOptimize(testOptCode, []OptPass{OptDeadLoops, OptDeadStores}, expec, t)
+}
+
+func TestOptimizeDeadStoresArray(t *testing.T) {
+    code := "a := array(2); a[5] = 1; b := array(dec(0)); r := 1; PRINT r"
+    expec := `a := array(2)
+a[5] = 1
+b := array(dec(0))
+r := 1
+PRINT r
+`
+
+    doTestOptimize(code, []OptPass{OptDeadStores}, expec, t)
+
+    prog, _ := ParseCode(code)
+    optimized, err := prog.Optimize(OptDeadStores)
+    if err != nil {
+        t.Fatal(err)
+    }
+    expected := "2:1: index 5 out of the bounds of array 'a' (2 elements)"
+    if err := optimized.NewExecution(0).Run(); err == nil || err.Error() != expected {
+        t.Error("expected the bounds error kept, returned: ", err)
+    }
+}
+
+func TestOptimizeAll(t *testing.T) {
+    expec := `# constants
+x0 := 0
+WHILE(x0 < 3) DO
+    x0 = inc(x0)
+OD
+`
+
+    doTestOptimize(testOptCode, OptPasses[:], expec, t)
+}
+
+func TestOptimizeProc(t *testing.T) {
+    code := `PROC f(a) DO
+    b := 1
+    d := val(a)
+    d = f(b)
+    WHILE(b > 1) DO
+        a = inc(a)
+    OD
+    RETURN inc(b)
+OD
+y := 0
+y = f(1)
+x0 := dec(0)
+`
+    expec := `PROC f(a) DO
+    d := val(a)
+    d = f(1)
+    RETURN 2
+OD
+y := 0
+y = f(1)
+x0 := dec(0)
+`
+
+    doTestOptimize(code, OptPasses[:], expec, t)
+}
+
+func TestOptimizeShadowed(t *testing.T) {
+    code := "x := 5; i := 0; WHILE(i < 2) DO x := 0; i = inc(x) OD; x0 := val(x)"
+    expec := `x := 5
+i := 0
+WHILE(i < 2) DO
+    x := 0
+    i = inc(0)
+OD
+x0 := val(5)
+`
+
+    doTestOptimize(code, []OptPass{OptConstProp}, expec, t)
+}
+
+func TestOptimizeSameResult(t *testing.T) {
+    for _, code := range testAccelCodes {
+        prog, err := ParseCode("x0 := 0; x1 := 3; x2 := 2; " + code)
+        if err != nil {
+            t.Error(err)
+            continue
+        }
+        opt, err := prog.Optimize(OptPasses[:]...)
+        if err != nil {
+            t.Error(err)
+            continue
+        }
+
+        slowResult, slowErr := doTestExecResult(prog)
+        fastResult, fastErr := doTestExecResult(opt)
+        if slowErr != fastErr || slowResult != fastResult {
+            t.Error("unexpected optimized result of '", code, "': ", fastResult, " ", fastErr, ", expected: ", slowResult, " ", slowErr)
+        }
+    }
+}
+
+func TestOptimizeErrors(t *testing.T) {
+    prog, _ := ParseCode("x := 1")
+    if _, err := prog.Optimize("inline"); err == nil || err.Error() != "Optimize: optimization pass 'inline' not defined" {
+        t.Error("unexpected returned error: ", err)
+    }
+}
+
+func doTestExecResult(prog *Program) (int, error) {
+    p := initProgram()
+    p.maxSteps = 1000
+    p.stmts = prog.stmts
+    if err := p.parseProgram(); err != nil {
+        return 0, err
+    }
+    result, err := p.getVar(resultVarSTRING)
+    return result.value, err
+}
+
+func doTestOptimize(code string, passes []OptPass, expec string, t *testing.T) {
+    prog, err := ParseCode(code)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    opt, err := prog.Optimize(passes...)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    if opt.String() != expec {
+        t.Error("unexpected optimized program:\n returned:\n", opt.String(), "\n expected:\n", expec)
+    }
+}
@@ -0,0 +1,714 @@
+package whileinterp
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// tokenKind defines the different kinds of tokens of the code
+type tokenKind int
+
+const (
+	eofToken tokenKind = iota //end of the code
+	identToken //name of a variable or a function (e.g. x1, or math.add for a function of a library)
+	numberToken //number (e.g. 2)
+	keywordToken //reserved word (e.g. WHILE)
+	opToken //operator or delimiter (e.g. ":=", "(", ";")
+	stringToken //text between double quotes (e.g. "lib/math.while"), with the quotes
+	commentToken //comment until the end of the line (e.g. "# first value"), only returned by scanComments
+)
+
+// keywords lists the reserved words of the language
+var keywords = [...]string{whileFuncSTRING, doSTRING, odSTRING, andOPSTRING, orOPSTRING, notOPSTRING, procSTRING, returnSTRING, importSTRING, printSTRING, readSTRING}
+
+// delimiters lists the characters used to delimit the different parts of the code
+var delimiters = [...]string{"(", ")", ",", ";", "[", "]"}
+
+// commentPrefixes lists the prefixes of the comments, which last until the end of the line
+var commentPrefixes = [...]string{"#", "//"}
+
+// token is every word of the code (e.g. "WHILE", "x1", ":=")
+type token struct {
+	kind tokenKind //kind of the token
+	text string //code of the token
+	offset int //offset of the token on the source code
+	newline bool //the token is the first one of its line (a newline ends the previous statement)
+}
+
+// end returns the offset of the code right after the token
+// return int
+func (t token) end() int {
+	return t.offset + len(t.text)
+}
+
+// isKeyword checks if a word is a reserved word of the language
+// return bool
+func isKeyword(word string) bool {
+	for _, k := range keywords {
+		if k == word {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidName checks if a name of a variable or a function can be written on the code
+// return bool
+func isValidName(name string) bool {
+	valid := name != "" && !isKeyword(name) && !unicode.IsDigit(rune(name[0]))
+	for _, c := range name {
+		valid = valid && isIdentChar(c)
+	}
+	return valid
+}
+
+// isIdentChar checks if a character can be part of the name of a variable or a function
+// return bool
+func isIdentChar(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// tokenize splits a code into its tokens
+// return []token, error
+func tokenize(name string, code string) ([]token, error) {
+	tokens := []token{}
+	newline := false
+	add := func(t token) { //the token is marked if a newline has been found since the previous one
+		t.newline = newline
+		newline = false
+		tokens = append(tokens, t)
+	}
+
+	for i := 0; i < len(code); {
+		c := rune(code[i])
+		switch {
+		case c == '\n':
+			newline = true
+			i++
+		case unicode.IsSpace(c):
+			i++
+		case isComment(code[i:]): //the comment is skipped until the end of the line
+			for i < len(code) && code[i] != '\n' {
+				i++
+			}
+		case c == '_' || unicode.IsLetter(c): //name or reserved word
+			end := i
+			for end < len(code) && isIdentChar(rune(code[end])) {
+				end++
+			}
+			if end + 1 < len(code) && code[end] == '.' && (code[end + 1] == '_' || unicode.IsLetter(rune(code[end + 1]))) { //qualified name
+				end++
+				for end < len(code) && isIdentChar(rune(code[end])) {
+					end++
+				}
+			}
+			t := token{kind: identToken, text: code[i:end], offset: i}
+			if isKeyword(t.text) {
+				t.kind = keywordToken
+			}
+			add(t)
+			i = end
+		case c == '"': //the text can't span several lines
+			end := strings.IndexAny(code[i + 1:], "\"\n")
+			if end == -1 || code[i + 1 + end] == '\n' {
+				return nil, &CheckError{Pos: newPosition(name, code, i), Msg: "text not terminated"}
+			}
+			add(token{kind: stringToken, text: code[i:i + end + 2], offset: i})
+			i += end + 2
+		case unicode.IsDigit(c) || (c == '-' && i + 1 < len(code) && unicode.IsDigit(rune(code[i + 1]))): //number
+			end := i + 1
+			for end < len(code) && unicode.IsDigit(rune(code[end])) {
+				end++
+			}
+			if c == '-' { //the numbers are naturals
+				return nil, &CheckError{Pos: newPosition(name, code, i), Msg: "number '" + code[i:end] + "' is not a natural number"}
+			}
+			add(token{kind: numberToken, text: code[i:end], offset: i})
+			i = end
+		default: //operator or delimiter, the longest one is taken (e.g. "<=" instead of "<")
+			text := ""
+			for _, op := range possOP {
+				if strings.HasPrefix(code[i:], op) && len(op) > len(text) {
+					text = op
+				}
+			}
+			for _, d := range delimiters {
+				if strings.HasPrefix(code[i:], d) {
+					text = d
+				}
+			}
+			if text == "" {
+				return nil, &CheckError{Pos: newPosition(name, code, i), Msg: "character '" + string(c) + "' not expected"}
+			}
+			add(token{kind: opToken, text: text, offset: i})
+			i += len(text)
+		}
+	}
+	add(token{kind: eofToken, offset: len(code)})
+	return tokens, nil
+}
+
+// scanComments returns the comments of a code, in order of appearance
+// return []token
+func scanComments(code string) []token {
+	comments := []token{}
+	for i := 0; i < len(code); i++ {
+		if code[i] == '"' { //a text (e.g. the path of an IMPORT) can contain the prefix of a comment
+			if end := strings.IndexAny(code[i + 1:], "\"\n"); end != -1 {
+				i += end + 1
+			}
+			continue
+		}
+		if isComment(code[i:]) {
+			end := strings.IndexByte(code[i:], '\n')
+			if end == -1 {
+				end = len(code) - i
+			}
+			comments = append(comments, token{kind: commentToken, text: strings.TrimRight(code[i:i + end], " \t\r"), offset: i})
+			i += end
+		}
+	}
+	return comments
+}
+
+// isComment checks if a code starts with a comment
+// return bool
+func isComment(code string) bool {
+	for _, prefix := range commentPrefixes {
+		if strings.HasPrefix(code, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parser parses the tokens of a code into its statements
+type parser struct {
+	name string //name of the source of the code, shown on the positions
+	code string //source code (used to compute the positions and the content of the statements)
+	tokens []token //tokens of the code
+	current int //index of the next token to parse
+	depth int //number of nested blocks (WHILE or PROC) being parsed
+	chain []string //files importing the code, the last one is the code itself (to detect the import cycles)
+}
+
+// peek returns the next token to parse, without consuming it
+// return token
+func (ps *parser) peek() token {
+	return ps.tokens[ps.current]
+}
+
+// next consumes the next token to parse
+// return token
+func (ps *parser) next() token {
+	t := ps.tokens[ps.current]
+	if t.kind != eofToken {
+		ps.current++
+	}
+	return t
+}
+
+// last returns the last consumed token
+// return token
+func (ps *parser) last() token {
+	return ps.tokens[ps.current - 1]
+}
+
+// is checks if the next token to parse is the given operator, delimiter or reserved word
+// return bool
+func (ps *parser) is(text string) bool {
+	t := ps.peek()
+	return (t.kind == opToken || t.kind == keywordToken) && t.text == text
+}
+
+// errorf returns an error found on a token
+// return error
+func (ps *parser) errorf(t token, msg string) error {
+	return &CheckError{Pos: newPosition(ps.name, ps.code, t.offset), Msg: msg}
+}
+
+// describe returns the description of a token for an error message
+// return string
+func describe(t token) string {
+	if t.kind == eofToken {
+		return "end of code"
+	}
+	return "'" + t.text + "'"
+}
+
+// expect consumes the next token, which must be the given operator, delimiter or reserved word
+// return error
+func (ps *parser) expect(text string) error {
+	if !ps.is(text) {
+		return ps.errorf(ps.peek(), "'" + text + "' expected, found " + describe(ps.peek()))
+	}
+	ps.next()
+	return nil
+}
+
+// parseStmts parses the statements divided by ";" or newlines until the end of the code or the given reserved word (e.g. OD)
+// return []stmt, error
+func (ps *parser) parseStmts(end string) ([]stmt, error) {
+	stmts := []stmt{}
+	for {
+		for ps.is(";") { //empty statements
+			ps.next()
+		}
+
+		t := ps.peek()
+		if t.kind == eofToken {
+			if end != "" {
+				return nil, ps.errorf(t, "'" + end + "' expected, found end of code")
+			}
+			return stmts, nil
+		}
+		if t.kind == keywordToken && t.text == end {
+			return stmts, nil
+		}
+
+		s, err := ps.parseStmt()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, s)
+
+		if t := ps.peek(); !ps.is(";") && !t.newline && t.kind != eofToken && !(t.kind == keywordToken && t.text == end) { //a newline ends the statement too
+			return nil, ps.errorf(t, "';' expected, found " + describe(t))
+		}
+	}
+}
+
+// parseStmt parses a statement: a declaration, an assignment, a WHILE, a PROC or an IMPORT
+// return stmt, error
+func (ps *parser) parseStmt() (stmt, error) {
+	start := ps.peek()
+	s := stmt{offset: start.offset}
+
+	switch {
+	case ps.is(whileFuncSTRING): //WHILE cond DO stmts OD
+		ps.next()
+		s.op = whileFuncSTRING
+
+		cond, err := ps.parseLogicExpr()
+		if err != nil {
+			return s, err
+		}
+		s.cond = cond
+
+		if err := ps.expect(doSTRING); err != nil {
+			return s, err
+		}
+		if s.body, err = ps.parseBlock(odSTRING); err != nil {
+			return s, err
+		}
+		if err := ps.expect(odSTRING); err != nil {
+			return s, err
+		}
+	case ps.is(procSTRING): //PROC name(params) DO stmts RETURN value OD
+		if ps.depth > 0 {
+			return s, ps.errorf(start, "functions can only be defined outside of any block")
+		}
+		if err := ps.parseProc(&s); err != nil {
+			return s, err
+		}
+	case ps.is(importSTRING): //IMPORT library
+		if ps.depth > 0 {
+			return s, ps.errorf(start, "libraries can only be imported outside of any block")
+		}
+		if err := ps.parseImport(&s); err != nil {
+			return s, err
+		}
+	case ps.is(printSTRING): //PRINT value
+		ps.next()
+		s.op = printSTRING
+
+		value, err := ps.parseOperand()
+		if err != nil {
+			return s, err
+		}
+		s.value = value
+	case ps.is(readSTRING): //READ name
+		ps.next()
+		s.op = readSTRING
+
+		name := ps.next()
+		if name.kind != identToken {
+			return s, ps.errorf(name, "name of the variable expected, found " + describe(name))
+		}
+		if err := ps.checkPlainName(name); err != nil {
+			return s, err
+		}
+		s.name = name.text
+		s.nameOffset = name.offset
+	case start.kind == identToken: //name := value, name = value or name[index] = value
+		ps.next()
+		if err := ps.checkPlainName(start); err != nil {
+			return s, err
+		}
+		s.name = start.text
+
+		if ps.is("[") {
+			index, err := ps.parseIndex()
+			if err != nil {
+				return s, err
+			}
+			s.index = index
+			if !ps.is(assignOPSTRING) { //an element can't be declared
+				return s, ps.errorf(ps.peek(), "'" + assignOPSTRING + "' expected, found " + describe(ps.peek()))
+			}
+		}
+		if !ps.is(declareOPSTRING) && !ps.is(assignOPSTRING) {
+			return s, ps.errorf(ps.peek(), "'" + declareOPSTRING + "' or '" + assignOPSTRING + "' expected, found " + describe(ps.peek()))
+		}
+		s.op = ps.next().text
+
+		value, err := ps.parseValue()
+		if err != nil {
+			return s, err
+		}
+		s.value = value
+	default:
+		return s, ps.errorf(start, "statement expected, found " + describe(start))
+	}
+
+	s.content = ps.code[s.offset:ps.last().end()]
+	return s, nil
+}
+
+// parseProc parses the name, the parameters, the body and the returned value of a function definition
+// return error
+func (ps *parser) parseProc(s *stmt) error {
+	ps.next()
+	s.op = procSTRING
+
+	name := ps.next()
+	if name.kind != identToken {
+		return ps.errorf(name, "name of the function expected, found " + describe(name))
+	}
+	if err := ps.checkPlainName(name); err != nil {
+		return err
+	}
+	s.name = name.text
+	s.nameOffset = name.offset
+
+	if err := ps.expect("("); err != nil {
+		return err
+	}
+	s.params = []string{}
+	for !ps.is(")") {
+		if len(s.params) > 0 {
+			if err := ps.expect(","); err != nil {
+				return err
+			}
+		}
+		param := ps.next()
+		if param.kind != identToken {
+			return ps.errorf(param, "name of the parameter expected, found " + describe(param))
+		}
+		if err := ps.checkPlainName(param); err != nil {
+			return err
+		}
+		s.params = append(s.params, param.text)
+		s.paramOffsets = append(s.paramOffsets, param.offset)
+	}
+	ps.next()
+
+	if err := ps.expect(doSTRING); err != nil {
+		return err
+	}
+	body, err := ps.parseBlock(returnSTRING)
+	if err != nil {
+		return err
+	}
+	s.body = body
+
+	if err := ps.expect(returnSTRING); err != nil {
+		return err
+	}
+	if s.value, err = ps.parseOperand(); err != nil {
+		return err
+	}
+	for ps.is(";") {
+		ps.next()
+	}
+	return ps.expect(odSTRING)
+}
+
+// parseImport parses the standard library (IMPORT std) or the path of a file (IMPORT "lib/math.while") imported,
+// which is loaded
+// return error
+func (ps *parser) parseImport(s *stmt) error {
+	ps.next()
+	s.op = importSTRING
+
+	name := ps.next()
+	s.nameOffset = name.offset
+	switch {
+	case name.kind == stringToken:
+		s.path = name.text[1:len(name.text) - 1]
+		lib, err := ps.importFile(name, s.path)
+		if err != nil {
+			return err
+		}
+		s.name, s.lib = lib.ns, lib
+	case name.kind == identToken && name.text == stdSTRING:
+		lib, err := stdLibrary()
+		if err != nil {
+			return ps.errorf(name, "library '" + name.text + "' not valid: " + err.Error())
+		}
+		s.name, s.lib = name.text, lib
+	case name.kind == identToken:
+		return ps.errorf(name, "library '" + name.text + "' not found")
+	default:
+		return ps.errorf(name, "name of the library or path of a file expected, found " + describe(name))
+	}
+	return nil
+}
+
+// importFile loads the library of a file, whose path is relative to the file being parsed (or to the working
+// directory if the code has no name). The functions are qualified with the name of the file (e.g. math.add)
+// return *library, error
+func (ps *parser) importFile(t token, path string) (*library, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(ps.name), path)
+	}
+	ns := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if !isValidName(ns) {
+		return nil, ps.errorf(t, "name '" + ns + "' of library '" + path + "' is not valid")
+	}
+
+	chain := append(append([]string{}, ps.chain...), path)
+	for _, importing := range ps.chain {
+		if samePath(importing, path) {
+			return nil, ps.errorf(t, "import cycle: " + strings.Join(chain, " -> "))
+		}
+	}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ps.errorf(t, "library '" + path + "' not found")
+	} else if err != nil {
+		return nil, ps.errorf(t, "library '" + path + "' can't be read: " + err.Error())
+	}
+
+	lib, err := newLibrary(path, ns, string(content), chain)
+	if err != nil { //the errors of the library are chained after the import
+		return nil, ps.errorf(t, "library '" + path + "' not valid: " + err.Error())
+	}
+	return lib, nil
+}
+
+// checkPlainName checks that a name is not qualified with a library (only the calls of the functions
+// of a library are, e.g. math.add(a, b))
+// return error
+func (ps *parser) checkPlainName(t token) error {
+	if strings.Contains(t.text, ".") {
+		return ps.errorf(t, "name '" + t.text + "' is not valid, only the calls of the functions of a library are qualified")
+	}
+	return nil
+}
+
+// samePath checks if two paths are the same file (e.g. "lib/a.while" and "/home/lib/a.while")
+// return bool
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+// parseBlock parses the statements of a block (the body of a WHILE or a PROC) until the given reserved word
+// return []stmt, error
+func (ps *parser) parseBlock(end string) ([]stmt, error) {
+	ps.depth++
+	defer func() { ps.depth-- }()
+
+	return ps.parseStmts(end)
+}
+
+// parseValue parses the value of a declaration or an assignment: a number or a function call
+// return *valueExpr, error
+func (ps *parser) parseValue() (*valueExpr, error) {
+	t := ps.peek()
+	if t.kind == identToken && ps.tokens[ps.current + 1].text == "[" {
+		return nil, ps.errorf(t, "number or function call expected, found " + describe(t) + " (use val(" + t.text + "[...]))")
+	}
+	if t.kind == identToken && ps.tokens[ps.current + 1].text != "(" {
+		return nil, ps.errorf(t, "number or function call expected, found " + describe(t) + " (use val(" + t.text + "))")
+	}
+	return ps.parseOperand()
+}
+
+// parseIndex parses the index of an element of an array between brackets (e.g. [inc(i)])
+// return *valueExpr, error
+func (ps *parser) parseIndex() (*valueExpr, error) {
+	ps.next()
+	index, err := ps.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return index, ps.expect("]")
+}
+
+// parseOperand parses a number, a variable, an element of an array or a function call (e.g. 2, x1, a[i], inc(x1))
+// return *valueExpr, error
+func (ps *parser) parseOperand() (*valueExpr, error) {
+	t := ps.next()
+	switch t.kind {
+	case numberToken:
+		return &valueExpr{kind: numberValue, text: t.text, offset: t.offset}, nil
+	case identToken:
+		if !ps.is("(") {
+			if err := ps.checkPlainName(t); err != nil {
+				return nil, err
+			}
+		}
+		if ps.is("[") {
+			index, err := ps.parseIndex()
+			if err != nil {
+				return nil, err
+			}
+			return &valueExpr{kind: indexValue, text: t.text, params: []*valueExpr{index}, offset: t.offset}, nil
+		}
+		if !ps.is("(") {
+			return &valueExpr{kind: varValue, text: t.text, offset: t.offset}, nil
+		}
+		ps.next()
+
+		call := &valueExpr{kind: callValue, text: t.text, params: []*valueExpr{}, offset: t.offset}
+		for !ps.is(")") {
+			if len(call.params) > 0 {
+				if err := ps.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			p, err := ps.parseOperand() //the parameters can be function calls too
+			if err != nil {
+				return nil, err
+			}
+			call.params = append(call.params, p)
+		}
+		ps.next()
+		return call, nil
+	default:
+		return nil, ps.errorf(t, "number, variable or function call expected, found " + describe(t))
+	}
+}
+
+// parseLogicExpr parses a logic expression: expressions joined by OR
+// return *logicExpr, error
+func (ps *parser) parseLogicExpr() (*logicExpr, error) {
+	left, err := ps.parseAndExpr()
+	for err == nil && ps.is(orOPSTRING) {
+		op := ps.next()
+		var right *logicExpr
+		if right, err = ps.parseAndExpr(); err == nil {
+			left = &logicExpr{op: orOPSTRING, left: left, right: right, offset: op.offset}
+		}
+	}
+	return left, err
+}
+
+// parseAndExpr parses expressions joined by AND (AND has precedence over OR)
+// return *logicExpr, error
+func (ps *parser) parseAndExpr() (*logicExpr, error) {
+	left, err := ps.parseNotExpr()
+	for err == nil && ps.is(andOPSTRING) {
+		op := ps.next()
+		var right *logicExpr
+		if right, err = ps.parseNotExpr(); err == nil {
+			left = &logicExpr{op: andOPSTRING, left: left, right: right, offset: op.offset}
+		}
+	}
+	return left, err
+}
+
+// parseNotExpr parses a negated expression, a parenthesized expression or a comparison
+// return *logicExpr, error
+func (ps *parser) parseNotExpr() (*logicExpr, error) {
+	switch {
+	case ps.is(notOPSTRING):
+		op := ps.next()
+		expr, err := ps.parseNotExpr()
+		if err != nil {
+			return nil, err
+		}
+		return &logicExpr{op: notOPSTRING, left: expr, offset: op.offset}, nil
+	case ps.is("("):
+		ps.next()
+		expr, err := ps.parseLogicExpr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, ps.expect(")")
+	default:
+		return ps.parseComparison()
+	}
+}
+
+// parseComparison parses the comparison of two values (e.g. x1 <= x2, inc(x1) < 10)
+// return *logicExpr, error
+func (ps *parser) parseComparison() (*logicExpr, error) {
+	first, err := ps.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	op := ps.next()
+	if op.kind != opToken || !isComparator(op.text) {
+		return nil, ps.errorf(op, "comparator expected, found " + describe(op))
+	}
+
+	second, err := ps.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &logicExpr{op: op.text, first: first, second: second, offset: first.offset}, nil
+}
+
+// isComparator checks if an operator compares two values
+// return bool
+func isComparator(op string) bool {
+	for _, c := range comparators {
+		if c == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCode parses a code into its statements
+// return []stmt, error
+func parseCode(code string) ([]stmt, error) {
+	return parseSource("", code)
+}
+
+// parseSource parses the code of a named source (e.g. a file) into its statements
+// return []stmt, error
+func parseSource(name string, code string) ([]stmt, error) {
+	chain := []string{}
+	if name != "" {
+		chain = append(chain, filepath.Clean(name))
+	}
+	return parseImported(name, code, chain)
+}
+
+// parseImported parses the code of a named source imported through a chain of files (the last one is the source)
+// return []stmt, error
+func parseImported(name string, code string, chain []string) ([]stmt, error) {
+	tokens, err := tokenize(name, code)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := &parser{name: name, code: code, tokens: tokens, chain: chain}
+	stmts, err := ps.parseStmts("")
+	if err != nil {
+		return nil, err
+	}
+	return stmts, nil
+}
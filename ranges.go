@@ -0,0 +1,535 @@
+package whileinterp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+    Abstract interpretation of the programs over the domain of the intervals: the possible values of every
+    variable are approximated by an interval [lo, hi] (both bounds can be infinite), without executing the program.
+
+    The WHILEs are analyzed until their invariant is stable, widening the intervals that keep growing
+    (their bounds are moved to the infinite) and narrowing them afterwards. The functions called can return
+    any number, and the bodies of the PROCs are analyzed with any number as their parameters. As on the
+    execution, "dec" of zero is -1.
+*/
+
+// narrowingSteps defines the number of times the invariant of a loop is narrowed after the widening
+const narrowingSteps = 2
+
+// Interval is the range of the possible values of a variable
+type Interval struct {
+	Lo int //lowest possible value (ignored if the interval has no lower bound)
+	Hi int //highest possible value (ignored if the interval has no upper bound)
+	LoInf bool //the values have no lower bound
+	HiInf bool //the values have no upper bound
+}
+
+// String returns the interval with the format "[lo, hi]" (e.g. "[-inf, 3]" or "[0, +inf]")
+// return string
+func (i Interval) String() string {
+	lo, hi := strconv.Itoa(i.Lo), strconv.Itoa(i.Hi)
+	if i.LoInf {
+		lo = "-inf"
+	}
+	if i.HiInf {
+		hi = "+inf"
+	}
+	return "[" + lo + ", " + hi + "]"
+}
+
+// topInterval is the interval of any number
+var topInterval = Interval{LoInf: true, HiInf: true}
+
+// isEmpty checks if there is no value on the interval
+// return bool
+func (i Interval) isEmpty() bool {
+	return !i.LoInf && !i.HiInf && i.Lo > i.Hi
+}
+
+// isSingle checks if there is only one value on the interval
+// return bool
+func (i Interval) isSingle() bool {
+	return !i.LoInf && !i.HiInf && i.Lo == i.Hi
+}
+
+// join returns the smallest interval with the values of both intervals
+// return Interval
+func (i Interval) join(j Interval) Interval {
+	return Interval{Lo: minInt(i.Lo, j.Lo), Hi: maxInt(i.Hi, j.Hi), LoInf: i.LoInf || j.LoInf, HiInf: i.HiInf || j.HiInf}
+}
+
+// meet returns the interval with the values on both intervals
+// return Interval
+func (i Interval) meet(j Interval) Interval {
+	result := Interval{LoInf: i.LoInf && j.LoInf, HiInf: i.HiInf && j.HiInf}
+	switch {
+	case i.LoInf:
+		result.Lo = j.Lo
+	case j.LoInf:
+		result.Lo = i.Lo
+	default:
+		result.Lo = maxInt(i.Lo, j.Lo)
+	}
+	switch {
+	case i.HiInf:
+		result.Hi = j.Hi
+	case j.HiInf:
+		result.Hi = i.Hi
+	default:
+		result.Hi = minInt(i.Hi, j.Hi)
+	}
+	return result
+}
+
+// widen returns the interval i, moving to the infinite the bounds that have grown on the interval j
+// return Interval
+func (i Interval) widen(j Interval) Interval {
+	result := i
+	if j.LoInf || (!i.LoInf && j.Lo < i.Lo) {
+		result.LoInf = true
+	}
+	if j.HiInf || (!i.HiInf && j.Hi > i.Hi) {
+		result.HiInf = true
+	}
+	return result
+}
+
+// add returns the interval with the values of i plus n
+// return Interval
+func (i Interval) add(n int) Interval {
+	return Interval{Lo: i.Lo + n, Hi: i.Hi + n, LoInf: i.LoInf, HiInf: i.HiInf}
+}
+
+// below returns the interval of the values lower than the highest value of i (orEqual to include it)
+// return Interval
+func (i Interval) below(orEqual bool) Interval {
+	if i.HiInf {
+		return topInterval
+	}
+	if orEqual {
+		return Interval{Hi: i.Hi, LoInf: true}
+	}
+	return Interval{Hi: i.Hi - 1, LoInf: true}
+}
+
+// above returns the interval of the values higher than the lowest value of i (orEqual to include it)
+// return Interval
+func (i Interval) above(orEqual bool) Interval {
+	if i.LoInf {
+		return topInterval
+	}
+	if orEqual {
+		return Interval{Lo: i.Lo, HiInf: true}
+	}
+	return Interval{Lo: i.Lo + 1, HiInf: true}
+}
+
+// minInt returns the lowest of two numbers
+// return int
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxInt returns the highest of two numbers
+// return int
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// rangeEnv relates every variable with its interval on a point of the program (nil if the point is unreachable)
+type rangeEnv map[string]Interval
+
+// copyEnv returns a copy of an environment
+// return rangeEnv
+func (env rangeEnv) copyEnv() rangeEnv {
+	if env == nil {
+		return nil
+	}
+	result := rangeEnv{}
+	for name, i := range env {
+		result[name] = i
+	}
+	return result
+}
+
+// joinEnv returns the environment with the values of both environments (a variable missing on one of them is dropped)
+// return rangeEnv
+func (env rangeEnv) joinEnv(other rangeEnv) rangeEnv {
+	if env == nil {
+		return other.copyEnv()
+	}
+	if other == nil {
+		return env.copyEnv()
+	}
+	result := rangeEnv{}
+	for name, i := range env {
+		if j, ok := other[name]; ok {
+			result[name] = i.join(j)
+		}
+	}
+	return result
+}
+
+// widenEnv returns the environment other, widening its intervals that have grown from env
+// return rangeEnv
+func (env rangeEnv) widenEnv(other rangeEnv) rangeEnv {
+	if env == nil {
+		return other.copyEnv()
+	}
+	result := rangeEnv{}
+	for name, i := range env.joinEnv(other) {
+		result[name] = env[name].widen(i)
+	}
+	return result
+}
+
+// equalEnv checks if two environments have the same intervals
+// return bool
+func (env rangeEnv) equalEnv(other rangeEnv) bool {
+	if (env == nil) != (other == nil) || len(env) != len(other) {
+		return false
+	}
+	for name, i := range env {
+		if j, ok := other[name]; !ok || i != j {
+			return false
+		}
+	}
+	return true
+}
+
+// RangePoint is the range of every variable before a statement is executed
+type RangePoint struct {
+	Pos Position //position of the statement
+	Stmt string //code of the statement
+	Reachable bool //the statement can be executed
+	Ranges map[string]Interval //interval of every variable declared before the statement
+}
+
+// String returns the ranges with the format "line:column: stmt {x: [lo, hi], ...}" (the names sorted)
+// return string
+func (rp *RangePoint) String() string {
+	if !rp.Reachable {
+		return rp.Pos.String() + ": " + rp.Stmt + " unreachable"
+	}
+	names := []string{}
+	for name := range rp.Ranges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ranges := make([]string, len(names))
+	for i, name := range names {
+		ranges[i] = name + ": " + rp.Ranges[name].String()
+	}
+	return rp.Pos.String() + ": " + rp.Stmt + " {" + strings.Join(ranges, ", ") + "}"
+}
+
+// ConstantCondition is a condition of a WHILE that is always true or always false when it is evaluated
+type ConstantCondition struct {
+	Pos Position //position of the WHILE
+	Cond string //code of the condition
+	Value bool //value of the condition
+}
+
+// String returns the condition with the format "line:column: condition 'cond' is always true"
+// return string
+func (cc *ConstantCondition) String() string {
+	return cc.Pos.String() + ": condition '" + cc.Cond + "' is always " + strconv.FormatBool(cc.Value)
+}
+
+// RangeAnalysis is the result of the abstract interpretation of a program
+type RangeAnalysis struct {
+	Points []*RangePoint //ranges before every statement, in order of appearance
+	Conditions []*ConstantCondition //conditions always true or always false, in order of appearance
+}
+
+// rangeAnalyzer interprets the statements of a program over the intervals
+type rangeAnalyzer struct {
+	name string //name of the source of the code, shown on the positions
+	code string //source code of the program (used to compute the positions)
+	record bool //the points are saved (false while the invariant of a loop is searched)
+	points map[int]*RangePoint //ranges before every statement by its offset
+	conds map[int]*ConstantCondition //conditions always true or always false by the offset of their WHILE
+	heads map[int]rangeEnv //invariant of every WHILE by its offset
+	entries map[int]rangeEnv //ranges before entering every WHILE by its offset
+}
+
+// analyzeStmts interprets a block of statements from an environment
+// return rangeEnv (environment after the statements, without the variables declared on the block)
+func (a *rangeAnalyzer) analyzeStmts(stmts []stmt, env rangeEnv) rangeEnv {
+	shadowed := map[string]*Interval{} //range of the variables when shadowed by the block (nil if not declared before)
+	for _, s := range stmts {
+		if s.op == procSTRING || s.op == importSTRING {
+			continue
+		}
+		if a.record {
+			a.points[s.offset] = &RangePoint{Pos: newPosition(a.name, a.code, s.offset), Stmt: stmtHead(s), Reachable: env != nil, Ranges: env.copyEnv()}
+		}
+		if env == nil {
+			if s.op == whileFuncSTRING {
+				a.analyzeStmts(s.body, nil) //the points of the body are unreachable too
+			}
+			continue
+		}
+
+		switch s.op {
+		case whileFuncSTRING:
+			env = a.analyzeWhile(s, env)
+			continue
+		case printSTRING:
+			continue
+		case readSTRING: //any natural can be read
+			env = env.copyEnv()
+			env[s.name] = Interval{Lo: 0, HiInf: true}
+			continue
+		case declareOPSTRING:
+			if _, ok := shadowed[s.name]; !ok {
+				if i, ok := env[s.name]; ok {
+					shadowed[s.name] = &i
+				} else {
+					shadowed[s.name] = nil
+				}
+			}
+		}
+		if s.index != nil || isArrayDecl(s) { //the arrays have no range
+			continue
+		}
+		env = env.copyEnv()
+		env[s.name] = a.evalInterval(s.value, env)
+	}
+
+	if env == nil {
+		return nil
+	}
+	env = env.copyEnv()
+	for name, i := range shadowed {
+		if i == nil {
+			delete(env, name)
+		} else {
+			env[name] = *i
+		}
+	}
+	return env
+}
+
+// analyzeWhile interprets a WHILE from an environment until its invariant is stable
+// return rangeEnv (environment after the WHILE)
+func (a *rangeAnalyzer) analyzeWhile(s stmt, entry rangeEnv) rangeEnv {
+	record := a.record
+	a.record = false
+
+	head := entry
+	for { //the invariant is widened until it is stable
+		next := head.widenEnv(entry.joinEnv(a.analyzeStmts(s.body, a.filter(s.cond, head, true))))
+		if next.equalEnv(head) {
+			break
+		}
+		head = next
+	}
+	for i := 0; i < narrowingSteps; i++ {
+		head = entry.joinEnv(a.analyzeStmts(s.body, a.filter(s.cond, head, true)))
+	}
+
+	a.record = record
+	body := a.filter(s.cond, head, true)
+	exit := a.filter(s.cond, head, false)
+	if a.record {
+		a.points[s.offset].Ranges = head.copyEnv() //the condition is evaluated with the invariant
+		a.heads[s.offset] = head
+		a.entries[s.offset] = entry
+		pos := newPosition(a.name, a.code, s.offset)
+		if body == nil {
+			a.conds[s.offset] = &ConstantCondition{Pos: pos, Cond: formatLogicExpr(s.cond), Value: false}
+		} else if exit == nil {
+			a.conds[s.offset] = &ConstantCondition{Pos: pos, Cond: formatLogicExpr(s.cond), Value: true}
+		}
+		a.analyzeStmts(s.body, body) //the points of the body are saved with the stable invariant
+	}
+	return exit
+}
+
+// evalInterval returns the interval of the possible values of a number, a variable or a function call
+// return Interval
+func (a *rangeAnalyzer) evalInterval(v *valueExpr, env rangeEnv) Interval {
+	switch v.kind {
+	case numberValue:
+		n, _ := strconv.Atoi(v.text)
+		return Interval{Lo: n, Hi: n}
+	case varValue:
+		if i, ok := env[v.text]; ok {
+			return i
+		}
+		return topInterval
+	case indexValue:
+		return topInterval
+	}
+
+	switch v.text {
+	case "zero":
+		return Interval{}
+	case "val":
+		return a.evalInterval(v.params[0], env)
+	case "inc":
+		return a.evalInterval(v.params[0], env).add(1)
+	case "dec":
+		return a.evalInterval(v.params[0], env).add(-1)
+	}
+	return topInterval //the functions defined with PROC can return any number
+}
+
+// negations relates every comparator with its negation
+var negations = map[string]string{
+	littleofOPSTRING: biggerofOrIsOPSTRING, littleofOrIsOPSTRING: biggerofOPSTRING,
+	biggerofOPSTRING: littleofOrIsOPSTRING, biggerofOrIsOPSTRING: littleofOPSTRING,
+	isOPSTRING: isNotOPSTRING, isNotOPSTRING: isOPSTRING,
+}
+
+// filter returns the environment where a logic expression has the given value
+// return rangeEnv (nil if the logic expression can't have the value)
+func (a *rangeAnalyzer) filter(l *logicExpr, env rangeEnv, value bool) rangeEnv {
+	if env == nil {
+		return nil
+	}
+
+	switch l.op {
+	case notOPSTRING:
+		return a.filter(l.left, env, !value)
+	case andOPSTRING, orOPSTRING:
+		if (l.op == andOPSTRING) == value { //both must have the value
+			return a.filter(l.right, a.filter(l.left, env, value), value)
+		}
+		return a.filter(l.left, env, value).joinEnv(a.filter(l.right, env, value))
+	}
+
+	op := l.op
+	if !value {
+		op = negations[op]
+	}
+	first, second := a.evalInterval(l.first, env), a.evalInterval(l.second, env)
+	switch op {
+	case littleofOPSTRING:
+		first, second = first.meet(second.below(false)), second.meet(first.above(false))
+	case littleofOrIsOPSTRING:
+		first, second = first.meet(second.below(true)), second.meet(first.above(true))
+	case biggerofOPSTRING:
+		first, second = first.meet(second.above(false)), second.meet(first.below(false))
+	case biggerofOrIsOPSTRING:
+		first, second = first.meet(second.above(true)), second.meet(first.below(true))
+	case isOPSTRING:
+		first = first.meet(second)
+		second = first
+	case isNotOPSTRING:
+		if first.isSingle() && second.isSingle() && first.Lo == second.Lo {
+			return nil
+		}
+		first, second = excludeSingle(first, second), excludeSingle(second, first)
+	}
+	if first.isEmpty() || second.isEmpty() {
+		return nil
+	}
+
+	env = env.copyEnv()
+	for _, operand := range []struct {
+		v *valueExpr
+		i Interval
+	}{{l.first, first}, {l.second, second}} {
+		if operand.v.kind == varValue { //only the variables compared are refined
+			if old, ok := env[operand.v.text]; ok {
+				env[operand.v.text] = old.meet(operand.i)
+				if env[operand.v.text].isEmpty() {
+					return nil
+				}
+			}
+		}
+	}
+	return env
+}
+
+// excludeSingle returns the interval i without the value of j, if j has only one value on a bound of i
+// return Interval
+func excludeSingle(i, j Interval) Interval {
+	if !j.isSingle() {
+		return i
+	}
+	if !i.LoInf && i.Lo == j.Lo {
+		i.Lo++
+	} else if !i.HiInf && i.Hi == j.Lo {
+		i.Hi--
+	}
+	return i
+}
+
+// stmtHead returns the code of a statement, without the body if it is a WHILE
+// return string
+func stmtHead(s stmt) string {
+	if s.op == whileFuncSTRING {
+		return whileFuncSTRING + "(" + formatLogicExpr(s.cond) + ")"
+	}
+	return formatStmt(s)
+}
+
+// newRangeAnalyzer interprets the statements of a program and its PROCs over the intervals
+// return *rangeAnalyzer (with the points saved)
+func (p *program) newRangeAnalyzer(code string) *rangeAnalyzer {
+	a := &rangeAnalyzer{name: p.name, code: code, record: true, points: map[int]*RangePoint{}, conds: map[int]*ConstantCondition{}, heads: map[int]rangeEnv{}, entries: map[int]rangeEnv{}}
+
+	env := rangeEnv{}
+	for _, v := range p.vars {
+		env[v.name] = Interval{Lo: v.value, Hi: v.value}
+	}
+	a.analyzeStmts(p.stmts, env)
+	for _, s := range p.stmts {
+		if s.op == procSTRING { //the parameters can have any value
+			params := rangeEnv{}
+			for _, param := range s.params {
+				params[param] = topInterval
+			}
+			a.analyzeStmts(s.body, params)
+		}
+	}
+	return a
+}
+
+// analyzeRanges interprets the statements of a program over the intervals
+// return *RangeAnalysis
+func (p *program) analyzeRanges(code string) *RangeAnalysis {
+	a := p.newRangeAnalyzer(code)
+
+	result := &RangeAnalysis{}
+	offsets := []int{}
+	for offset := range a.points {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+	for _, offset := range offsets {
+		result.Points = append(result.Points, a.points[offset])
+		if cc, ok := a.conds[offset]; ok {
+			result.Conditions = append(result.Conditions, cc)
+		}
+	}
+	return result
+}
+
+// AnalyzeRanges interprets the code over the intervals, without executing it, and returns the possible range
+// of every variable before every statement and the conditions of the WHILEs that are always true or always false
+// the code must pass the static checker first, otherwise its errors are returned
+// return *RangeAnalysis, error
+func AnalyzeRanges(code string) (*RangeAnalysis, error) {
+	p := initProgram()
+	if err := p.getStmts(code); err != nil {
+		return nil, err
+	}
+	if errs := p.check(code); len(errs) > 0 {
+		return nil, errs
+	}
+	return p.analyzeRanges(code), nil
+}
@@ -0,0 +1,166 @@
+package whileinterp
+
+import "testing"
+
+/*********************** TESTING ***********************/
+func TestAnalyzeRangesStraight(t *testing.T) {
+    code := "xo := 2; x1 := inc(xo); x1 = dec(dec(dec(dec(x1))));"
+    expecPoints := []string{
+        "1:1: xo := 2 {}",
+        "1:10: x1 := inc(xo) {xo: [2, 2]}",
+        "1:25: x1 = dec(dec(dec(dec(x1)))) {x1: [3, 3], xo: [2, 2]}",
+    }
+
+    doTestAnalyzeRanges(code, expecPoints, []string{}, t)
+}
+
+func TestAnalyzeRangesLoop(t *testing.T) {
+    code := "xo := 0; WHILE(xo < 3) DO xo = inc(xo) OD; x1 := val(xo);"
+    expecPoints := []string{
+        "1:1: xo := 0 {}",
+        "1:10: WHILE(xo < 3) {xo: [0, 3]}",
+        "1:27: xo = inc(xo) {xo: [0, 2]}",
+        "1:44: x1 := val(xo) {xo: [3, 3]}",
+    }
+
+    doTestAnalyzeRanges(code, expecPoints, []string{}, t)
+}
+
+func TestAnalyzeRangesBlockScope(t *testing.T) {
+    code := "xo := 5; x1 := 0; WHILE(x1 < 2) DO xo := 0; x2 := inc(x1); x1 = val(x2) OD; x3 := val(xo);"
+    expecPoints := []string{
+        "1:1: xo := 5 {}",
+        "1:10: x1 := 0 {xo: [5, 5]}",
+        "1:19: WHILE(x1 < 2) {x1: [0, 2], xo: [5, 5]}",
+        "1:36: xo := 0 {x1: [0, 1], xo: [5, 5]}",
+        "1:45: x2 := inc(x1) {x1: [0, 1], xo: [0, 0]}",
+        "1:60: x1 = val(x2) {x1: [0, 1], x2: [1, 2], xo: [0, 0]}",
+        "1:77: x3 := val(xo) {x1: [2, 2], xo: [5, 5]}",
+    }
+
+    doTestAnalyzeRanges(code, expecPoints, []string{}, t)
+}
+
+func TestAnalyzeRangesWidening(t *testing.T) {
+    code := "xo := 1; x1 := 0; WHILE(x1 != xo) DO xo = inc(xo); x1 = inc(inc(x1)) OD; WHILE(x1 < 0) DO x1 = dec(x1) OD;"
+    expecPoints := []string{
+        "1:1: xo := 1 {}",
+        "1:10: x1 := 0 {xo: [1, 1]}",
+        "1:19: WHILE(x1 != xo) {x1: [0, +inf], xo: [1, +inf]}",
+        "1:38: xo = inc(xo) {x1: [0, +inf], xo: [1, +inf]}",
+        "1:52: x1 = inc(inc(x1)) {x1: [0, +inf], xo: [2, +inf]}",
+        "1:74: WHILE(x1 < 0) {x1: [1, +inf], xo: [1, +inf]}",
+        "1:91: x1 = dec(x1) unreachable",
+    }
+    expecConds := []string{
+        "1:74: condition 'x1 < 0' is always false",
+    }
+
+    doTestAnalyzeRanges(code, expecPoints, expecConds, t)
+}
+
+func TestAnalyzeRangesConditions(t *testing.T) {
+    code := `x0 := 0
+xo := 1
+WHILE(xo > 0) DO
+    x0 = inc(x0)
+OD
+x1 := 0
+WHILE(x1 != 5 AND xo == 1) DO
+    x1 = inc(x1)
+OD`
+    expecConds := []string{
+        "3:1: condition 'xo > 0' is always true",
+    }
+
+    analysis, err := AnalyzeRanges(code)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    checkStrings(analysis.Conditions, expecConds, t)
+    //the statements after an infinite loop are unreachable
+    if len(analysis.Points) != 7 || analysis.Points[4].Reachable {
+        t.Error("unexpected returned points: ", analysis.Points)
+    }
+}
+
+func TestAnalyzeRangesUnbounded(t *testing.T) {
+    code := `PROC f(a) DO
+    b := 0
+    WHILE(b < a) DO
+        b = inc(b)
+    OD
+    RETURN b
+OD
+x1 := 0
+xo := f(x1)
+WHILE(NOT xo <= 2 OR xo == 0) DO
+    xo = inc(xo)
+OD`
+    expecPoints := []string{
+        "2:5: b := 0 {a: [-inf, +inf]}",
+        "3:5: WHILE(b < a) {a: [-inf, +inf], b: [0, +inf]}",
+        "4:9: b = inc(b) {a: [1, +inf], b: [0, +inf]}",
+        "8:1: x1 := 0 {}",
+        "9:1: xo := f(x1) {x1: [0, 0]}",
+        "10:1: WHILE(NOT xo <= 2 OR xo == 0) {x1: [0, 0], xo: [-inf, +inf]}",
+        "11:5: xo = inc(xo) {x1: [0, 0], xo: [0, +inf]}",
+    }
+
+    doTestAnalyzeRanges(code, expecPoints, []string{}, t)
+}
+
+func TestAnalyzeRangesBelowZero(t *testing.T) {
+    code := "xo := 0; x1 := dec(xo); WHILE(x1 < 5) DO x1 = dec(x1) OD;"
+    expecPoints := []string{
+        "1:1: xo := 0 {}",
+        "1:10: x1 := dec(xo) {xo: [0, 0]}",
+        "1:25: WHILE(x1 < 5) {x1: [-inf, -1], xo: [0, 0]}",
+        "1:42: x1 = dec(x1) {x1: [-inf, -1], xo: [0, 0]}",
+    }
+    expecConds := []string{
+        "1:25: condition 'x1 < 5' is always true",
+    }
+
+    doTestAnalyzeRanges(code, expecPoints, expecConds, t)
+}
+
+func TestAnalyzeRangesErrors(t *testing.T) {
+    if _, err := AnalyzeRanges("xo = 2;"); err == nil {
+        t.Error("expected error not returned")
+    }
+}
+
+func doTestAnalyzeRanges(code string, expecPoints []string, expecConds []string, t *testing.T) {
+    analysis, err := AnalyzeRanges(code)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    checkStrings(analysis.Points, expecPoints, t)
+    checkStrings(analysis.Conditions, expecConds, t)
+}
+
+func checkStrings(returned interface{}, expec []string, t *testing.T) {
+    strs := []string{}
+    switch values := returned.(type) {
+    case []*RangePoint:
+        for _, v := range values {
+            strs = append(strs, v.String())
+        }
+    case []*ConstantCondition:
+        for _, v := range values {
+            strs = append(strs, v.String())
+        }
+    }
+    if len(strs) != len(expec) {
+        t.Error("unexpected returned values:\n returned: ", strs, "\n expected: ", expec)
+        return
+    }
+    for i, s := range strs {
+        if s != expec[i] {
+            t.Error("unexpected returned value:\n returned: ", s, "\n expected: ", expec[i])
+        }
+    }
+}
@@ -0,0 +1,304 @@
+package whileinterp
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync/atomic"
+)
+
+/*
+    Snapshots of the executions: an execution can be paused between two statements of the main program
+    (the function calls being executed are finished first) and its state saved as a Snapshot, which can be
+    encoded as JSON, written to disk and resumed by another process:
+
+        {"version": 1, "name": string, "code": string, "vars": [{"name": string, "value": int, "array": [int]}],
+         "pc": [int], "scopes": [int], "done": bool, "steps": int, "maxSteps": int}
+
+    The program counter "pc" is the index of the next statement on every nested block, from the main program
+    to the innermost loop body being executed: every index but the last one is a WHILE of the loop stack.
+    The "array" saves the elements of the variables that are arrays (also empty, an array of no elements).
+    The "scopes" are the number of variables when every loop body of the loop stack was entered: the variables
+    that follow are local to the body (and may shadow the variables of the enclosing blocks).
+    A resumed execution counts the same steps and produces the same final result as an uninterrupted one.
+*/
+
+// snapshotVersion defines the version of the snapshots
+const snapshotVersion = 1
+
+// ErrPaused is returned when an execution is paused before finishing (it can be resumed)
+var ErrPaused = errors.New("paused: execution paused before a statement")
+
+// SnapshotVar is a variable saved on a snapshot
+type SnapshotVar struct {
+	Name string `json:"name"`
+	Value int `json:"value"`
+	Array []int `json:"array"` //elements of an array (nil for a natural number)
+}
+
+// snapshotVarJSON is a SnapshotVar without the array, for the natural numbers
+type snapshotVarJSON struct {
+	Name string `json:"name"`
+	Value int `json:"value"`
+}
+
+// MarshalJSON writes the array of the variable only if it is an array (an empty array is written as [])
+// return []byte, error
+func (sv SnapshotVar) MarshalJSON() ([]byte, error) {
+	if sv.Array == nil {
+		return json.Marshal(snapshotVarJSON{Name: sv.Name, Value: sv.Value})
+	}
+	type plain SnapshotVar //without the method, so it isn't called again
+	return json.Marshal(plain(sv))
+}
+
+// String returns the value of the variable, or its elements if it is an array
+// return string
+func (sv SnapshotVar) String() string {
+	return variable{name: sv.Name, value: sv.Value, array: sv.Array}.String()
+}
+
+// Snapshot is the state of an execution, which can be encoded as JSON and resumed later
+type Snapshot struct {
+	Version int `json:"version"`
+	Name string `json:"name,omitempty"` //name of the source of the code
+	Code string `json:"code"` //source code of the program
+	Vars []SnapshotVar `json:"vars"` //variables declared, in their order of declaration
+	PC []int `json:"pc,omitempty"` //next statement on every nested block (empty if the execution isn't started or is done)
+	Scopes []int `json:"scopes,omitempty"` //number of variables when every loop body of the program counter was entered
+	Done bool `json:"done,omitempty"` //the execution has finished
+	Steps int `json:"steps"` //statements executed
+	MaxSteps int `json:"maxSteps,omitempty"` //maximum number of statements to execute (0 for no limit)
+}
+
+// Execution is an execution of a program, which can be paused, saved as a snapshot and resumed
+type Execution struct {
+	p *program //program being executed
+	code string //source code of the program
+	pc []int //next statement when paused (nil if the execution isn't started)
+	done bool //the execution has finished
+	err error //error that stopped the execution (nil if it can continue)
+}
+
+// NewExecution prepares an execution of the program, which is started by Run.
+// maxSteps limits the statements to execute (0 for no limit)
+// return *Execution
+func (prog *Program) NewExecution(maxSteps int) *Execution {
+	p := prog.funcs.newProgram()
+	p.name = prog.name
+	p.code = prog.code
+	p.stmts = prog.stmts
+	p.maxSteps = maxSteps
+	p.defineProcs()
+
+	return &Execution{p: p, code: prog.code}
+}
+
+// NewExecution prepares an execution of the function with the given inputs, which is started by Run.
+// maxSteps limits the statements to execute (0 for no limit)
+// return *Execution, error
+func (f *Function) NewExecution(maxSteps int, inputs ...int) (*Execution, error) {
+	if len(inputs) != f.arity {
+		return nil, errors.New("NewExecution: function expects " + strconv.Itoa(f.arity) + " input(s), " + strconv.Itoa(len(inputs)) + " given")
+	}
+	p, err := initFunction(inputs)
+	if err != nil {
+		return nil, err
+	}
+	p.stmts = f.stmts
+	p.name = f.name
+	p.code = f.code
+	p.maxSteps = maxSteps
+	p.funcs = f.funcs
+	p.accelerate = f.funcs.accelerates()
+	p.defineProcs()
+
+	return &Execution{p: p, code: f.code}, nil
+}
+
+// Resume prepares the execution saved on a snapshot, which is continued by Run
+// return *Execution, error
+func Resume(snap *Snapshot) (*Execution, error) {
+	return defaultInterpreter.Resume(snap)
+}
+
+// Resume prepares the execution saved on a snapshot, like Resume, which can call the functions of the interpreter
+// (the snapshot only saves their names)
+// return *Execution, error
+func (in *Interpreter) Resume(snap *Snapshot) (*Execution, error) {
+	if snap.Version != snapshotVersion {
+		return nil, errors.New("Resume: snapshot version " + strconv.Itoa(snap.Version) + " not supported")
+	}
+	p := in.newProgram()
+	p.name = snap.Name
+	p.code = snap.Code
+	if err := p.getStmts(snap.Code); err != nil {
+		return nil, err
+	}
+	if len(snap.PC) > 0 && !validPC(p.stmts, snap.PC) {
+		return nil, errors.New("Resume: program counter doesn't point to a statement of the code")
+	}
+	if (len(snap.PC) > 0 && len(snap.Scopes) != len(snap.PC) - 1) || (len(snap.PC) == 0 && len(snap.Scopes) > 0) {
+		return nil, errors.New("Resume: scopes don't match the loop stack of the program counter")
+	}
+	for i, start := range snap.Scopes {
+		if start > len(snap.Vars) || (i > 0 && start < snap.Scopes[i - 1]) {
+			return nil, errors.New("Resume: scope " + strconv.Itoa(i) + " out of the variables")
+		}
+	}
+	for i, v := range snap.Vars { //the variables are declared on their block
+		for len(p.scopes) < len(snap.Scopes) && snap.Scopes[len(p.scopes)] == i {
+			p.scopes = append(p.scopes, i)
+		}
+		if err := p.addVar(&variable{name: v.Name, value: v.Value, array: copyArray(v.Array)}); err != nil {
+			return nil, errors.New("Resume: variable '" + v.Name + "' saved twice on the same block")
+		}
+	}
+	for len(p.scopes) < len(snap.Scopes) {
+		p.scopes = append(p.scopes, len(snap.Vars))
+	}
+	p.steps = snap.Steps
+	p.maxSteps = snap.MaxSteps
+	p.defineProcs()
+
+	e := &Execution{p: p, code: snap.Code, done: snap.Done}
+	if len(snap.PC) > 0 {
+		e.pc = append([]int{}, snap.PC...)
+	}
+	return e, nil
+}
+
+// Run starts or continues the execution until it finishes, it's paused (ErrPaused) or it fails
+// return error
+func (e *Execution) Run() error {
+	if e.done || e.err != nil {
+		return e.err
+	}
+
+	var err error
+	if e.pc == nil {
+		err = e.p.execStmts(e.p.stmts)
+	} else {
+		err = e.p.resumeStmts(e.p.stmts, e.pc)
+	}
+
+	switch err {
+		case nil:
+			e.done, e.pc = true, nil
+		case ErrPaused:
+			e.pc = stmtPath(e.p.stmts, e.p.current)
+		default:
+			e.err = err
+	}
+	return err
+}
+
+// Pause asks the execution to pause before the next statement of the main program (it can be called while
+// Run is executing, from another goroutine)
+func (e *Execution) Pause() {
+	atomic.StoreInt32(&e.p.pauseRequested, 1)
+}
+
+// PauseAfter asks the execution to pause before the first statement of the main program executed once the
+// given number of steps are counted
+func (e *Execution) PauseAfter(steps int) {
+	e.p.pauseAt = steps
+}
+
+// Done returns whether the execution has finished
+// return bool
+func (e *Execution) Done() bool {
+	return e.done
+}
+
+// Steps returns the number of statements executed
+// return int
+func (e *Execution) Steps() int {
+	return e.p.steps
+}
+
+// Var returns the value of a variable declared by the execution
+// return int, error
+func (e *Execution) Var(name string) (int, error) {
+	v, err := e.p.getVar(name)
+	if err != nil {
+		return 0, errors.New("Var: variable '" + name + "' not declared")
+	}
+	return v.value, nil
+}
+
+// Snapshot returns the state of the execution, which must not be running (it's paused, done or not started)
+// return *Snapshot, error
+func (e *Execution) Snapshot() (*Snapshot, error) {
+	if e.err != nil {
+		return nil, errors.New("Snapshot: execution stopped by an error: " + e.err.Error())
+	}
+
+	return &Snapshot{Version: snapshotVersion, Name: e.p.name, Code: e.code, Vars: frameVars(e.p), PC: append([]int{}, e.pc...),
+		Scopes: append([]int{}, e.p.scopes...), Done: e.done, Steps: e.p.steps, MaxSteps: e.p.maxSteps}, nil
+}
+
+// shouldPause returns whether the main program has been asked to pause before its next statement,
+// the request is consumed
+// return bool
+func (p *program) shouldPause() bool {
+	if atomic.SwapInt32(&p.pauseRequested, 0) != 0 || (p.pauseAt > 0 && p.steps >= p.pauseAt) {
+		p.pauseAt = 0
+		return true
+	}
+	return false
+}
+
+// resumeStmts executes a list of statements from the statement of a program counter: the WHILEs of the loop
+// stack finish the iteration being executed and continue with the next ones
+// return error
+func (p *program) resumeStmts(stmts []stmt, pc []int) error {
+	i := pc[0]
+	if len(pc) > 1 {
+		s := stmts[i]
+		p.level++
+		err := p.resumeStmts(s.body, pc[1:])
+		if err == nil {
+			p.endBlock() //the body was entered before the pause
+			err = p.step() //the evaluation of the expression is a step too
+		}
+		if err == nil {
+			err = p.iterateWhile(s)
+		}
+		p.level--
+		if err != nil {
+			return err
+		}
+		i++
+	}
+	return p.execStmts(stmts[i:])
+}
+
+// stmtPath returns the index of a statement on every nested block of a list of statements
+// return []int (nil if the statement isn't found)
+func stmtPath(stmts []stmt, target *stmt) []int {
+	for i := range stmts {
+		if &stmts[i] == target {
+			return []int{i}
+		}
+		if stmts[i].op == whileFuncSTRING {
+			if path := stmtPath(stmts[i].body, target); path != nil {
+				return append([]int{i}, path...)
+			}
+		}
+	}
+	return nil
+}
+
+// validPC returns whether a program counter points to a statement of a list of statements through its WHILEs
+// return bool
+func validPC(stmts []stmt, pc []int) bool {
+	i := pc[0]
+	if i < 0 || i >= len(stmts) || stmts[i].op == procSTRING || stmts[i].op == importSTRING {
+		return false
+	}
+	if len(pc) == 1 {
+		return true
+	}
+	return stmts[i].op == whileFuncSTRING && validPC(stmts[i].body, pc[1:])
+}
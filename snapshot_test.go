@@ -0,0 +1,241 @@
+package whileinterp
+
+import (
+    "encoding/json"
+    "reflect"
+    "strings"
+    "testing"
+)
+
+var testSnapshotCodes = []string{
+    "a := 3; b := 0; WHILE(a > 0) DO b = inc(b); a = dec(a) OD",
+    "a := 2; r := 0; c := 0; WHILE(a > 0) DO c = 3; WHILE(c > 0) DO r = inc(r); c = dec(c) OD; a = dec(a) OD",
+    "PROC add(a, b) DO WHILE(b > 0) DO a = inc(a); b = dec(b) OD RETURN a OD; x := 0; i := 0; WHILE(i < 3) DO x = add(x, i); i = inc(i) OD",
+    "x := 0; WHILE(x < 2) DO WHILE(x < 5) DO x = inc(x) OD; x = inc(x) OD; y := val(x)",
+    "x := 5; i := 0; WHILE(i < 3) DO x := val(i); c := 0; WHILE(c < 2) DO d := inc(c); c = val(d) OD; i = inc(x) OD; y := val(x)",
+    "e := array(0); n := 3; a := array(n); i := 0; WHILE(i < n) DO a[i] = inc(i); i = inc(i) OD; s := val(a[2])",
+}
+
+// doTestSnapshotRun executes a program until the given steps, saves it as JSON and resumes it on a new execution
+// of the interpreter
+// return *Execution (resumed execution, finished)
+func doTestSnapshotRun(in *Interpreter, code string, pauseAt int, t *testing.T) *Execution {
+    prog, err := in.ParseCode(code)
+    if err != nil {
+        t.Fatal(err)
+    }
+    e := prog.NewExecution(0)
+    e.PauseAfter(pauseAt)
+    if err := e.Run(); err != ErrPaused && err != nil {
+        t.Fatal(err)
+    }
+
+    snap, err := e.Snapshot()
+    if err != nil {
+        t.Fatal(err)
+    }
+    data, err := json.Marshal(snap)
+    if err != nil {
+        t.Fatal(err)
+    }
+    loaded := new(Snapshot)
+    if err := json.Unmarshal(data, loaded); err != nil {
+        t.Fatal(err)
+    }
+
+    resumed, err := in.Resume(loaded)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := resumed.Run(); err != nil {
+        t.Fatal(err)
+    }
+    return resumed
+}
+
+/*********************** TESTING ***********************/
+func TestSnapshotSameResult(t *testing.T) {
+    for _, code := range testSnapshotCodes {
+        for _, accelerate := range []bool{true, false} {
+            in := NewInterpreter()
+            in.SetAcceleration(accelerate)
+            prog, err := in.ParseCode(code)
+            if err != nil {
+                t.Fatal(err)
+            }
+            whole := prog.NewExecution(0)
+            if err := whole.Run(); err != nil {
+                t.Fatal(err)
+            }
+            expected, _ := whole.Snapshot()
+
+            for pauseAt := 1; pauseAt <= whole.Steps() + 1; pauseAt++ {
+                returned, _ := doTestSnapshotRun(in, code, pauseAt, t).Snapshot()
+                if !returned.Done || returned.Steps != expected.Steps || !reflect.DeepEqual(returned.Vars, expected.Vars) {
+                    t.Error("unexpected resumed execution of '", code, "' paused after ", pauseAt, " steps:",
+                        "\n returned: ", returned.Vars, " ", returned.Steps, "\n expected: ", expected.Vars, " ", expected.Steps)
+                }
+            }
+        }
+    }
+}
+
+func TestSnapshotPauseAccelerated(t *testing.T) {
+    slow := NewInterpreter()
+    slow.SetAcceleration(false)
+    for _, code := range testSnapshotCodes {
+        if strings.HasPrefix(code, procSTRING) { //a function call is finished before the pause
+            continue
+        }
+        prog, _ := ParseCode(code)
+        slowProg, _ := slow.ParseCode(code)
+        whole := prog.NewExecution(0)
+        whole.Run()
+
+        for pauseAt := 1; pauseAt <= whole.Steps(); pauseAt++ {
+            e, expected := prog.NewExecution(0), slowProg.NewExecution(0)
+            e.PauseAfter(pauseAt)
+            expected.PauseAfter(pauseAt)
+            err, expecErr := e.Run(), expected.Run()
+            snap, _ := e.Snapshot()
+            expecSnap, _ := expected.Snapshot()
+            if err != expecErr || !reflect.DeepEqual(snap, expecSnap) { //the pause is taken where every iteration is executed
+                t.Error("unexpected pause of '", code, "' after ", pauseAt, " steps: ", snap.Steps, " ", snap.PC, " ", err,
+                    ", expected: ", expecSnap.Steps, " ", expecSnap.PC, " ", expecErr)
+            }
+        }
+    }
+
+    prog, _ := ParseCode(testSnapshotCodes[0])
+    e := prog.NewExecution(0)
+    e.PauseAfter(4) //a := 3; b := 0; WHILE; b = inc(b) -> before a = dec(a)
+    e.Run()
+    if snap, _ := e.Snapshot(); snap.Steps != 4 || !reflect.DeepEqual(snap.PC, []int{2, 1}) {
+        t.Error("unexpected snapshot: ", snap.PC, " ", snap.Steps)
+    }
+}
+
+func TestSnapshotProgramCounter(t *testing.T) {
+    in := NewInterpreter()
+    in.SetAcceleration(false)
+    prog, _ := in.ParseCode(testSnapshotCodes[1])
+    e := prog.NewExecution(0)
+    e.PauseAfter(7) //a := 2; r := 0; c := 0; WHILE; c = 3; WHILE; r = inc(r) -> before c = dec(c)
+    if err := e.Run(); err != ErrPaused {
+        t.Fatal("expected ErrPaused, returned: ", err)
+    }
+    snap, _ := e.Snapshot()
+    if !reflect.DeepEqual(snap.PC, []int{3, 1, 1}) || snap.Steps != 7 || snap.Done {
+        t.Error("unexpected snapshot: ", snap.PC, " ", snap.Steps, " ", snap.Done)
+    }
+
+    e.PauseAfter(9)
+    if err := e.Run(); err != ErrPaused { //paused twice on the same execution
+        t.Fatal("expected ErrPaused, returned: ", err)
+    }
+    if err := e.Run(); err != nil || !e.Done() {
+        t.Fatal("expected the execution finished, returned: ", err)
+    }
+    if r, _ := e.Var("r"); r != 6 {
+        t.Error("expected r = 6, returned: ", r)
+    }
+}
+
+func TestSnapshotScopes(t *testing.T) {
+    prog, _ := ParseCode(testSnapshotCodes[4])
+    e := prog.NewExecution(0)
+    e.PauseAfter(7) //x := 5; i := 0; WHILE; x := val(i); c := 0; WHILE; d := inc(c) -> before c = val(d)
+    e.Run()
+    snap, _ := e.Snapshot()
+    if !reflect.DeepEqual(snap.Scopes, []int{2, 4}) || len(snap.Vars) != 5 {
+        t.Fatal("unexpected snapshot: ", snap.Vars, " ", snap.Scopes)
+    }
+
+    snap.Scopes = []int{2}
+    if _, err := Resume(snap); err == nil {
+        t.Error("expected an error resuming scopes that don't match the program counter")
+    }
+    snap.Scopes = []int{2, 5}
+    if _, err := Resume(snap); err != nil {
+        t.Error(err)
+    }
+    snap.Scopes = []int{0, 4} //x is declared twice on the same block
+    if _, err := Resume(snap); err == nil {
+        t.Error("expected an error resuming a variable declared twice on a block")
+    }
+}
+
+func TestSnapshotArrays(t *testing.T) {
+    prog, _ := ParseCode(testSnapshotCodes[5])
+    e := prog.NewExecution(0)
+    e.Run()
+    snap, _ := e.Snapshot()
+    data, err := json.Marshal(snap.Vars[:3])
+    expected := `[{"name":"e","value":0,"array":[]},{"name":"n","value":3},{"name":"a","value":0,"array":[1,2,3]}]`
+    if err != nil || string(data) != expected {
+        t.Error("unexpected variables:\n returned: ", string(data), " ", err, "\n expected: ", expected)
+    }
+}
+
+func TestSnapshotPause(t *testing.T) {
+    prog, _ := ParseCode("a := 1; b := inc(a)")
+    e := prog.NewExecution(0)
+    e.Pause()
+    if err := e.Run(); err != ErrPaused || e.Steps() != 0 {
+        t.Fatal("expected paused before the first statement, returned: ", err, " ", e.Steps())
+    }
+    if err := e.Run(); err != nil {
+        t.Fatal(err)
+    }
+    if b, _ := e.Var("b"); b != 2 {
+        t.Error("expected b = 2, returned: ", b)
+    }
+}
+
+func TestSnapshotFunction(t *testing.T) {
+    f, _ := ParseFunction("c := val(x2); WHILE(c > 0) DO x0 = inc(x0); c = dec(c) OD; x0 = val(x0)", 2)
+    e, err := f.NewExecution(100, 3, 4)
+    if err != nil {
+        t.Fatal(err)
+    }
+    e.PauseAfter(3)
+    e.Run()
+
+    snap, _ := e.Snapshot()
+    resumed, err := Resume(snap)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := resumed.Run(); err != nil {
+        t.Fatal(err)
+    }
+    if x0, _ := resumed.Var("x0"); x0 != 4 {
+        t.Error("expected x0 = 4, returned: ", x0)
+    }
+    if _, err := f.NewExecution(0, 1); err == nil {
+        t.Error("expected an error with the wrong number of inputs")
+    }
+}
+
+func TestSnapshotErrors(t *testing.T) {
+    snaps := []*Snapshot{
+        {Version: 2, Code: "a := 0"},
+        {Version: 1, Code: "a := 0", PC: []int{1}},
+        {Version: 1, Code: "a := 0; WHILE(a < 1) DO a = 1 OD", PC: []int{0, 0}},
+        {Version: 1, Code: "a := 0", Vars: []SnapshotVar{{Name: "a", Value: 1}, {Name: "a", Value: 2}}},
+    }
+    for _, snap := range snaps {
+        if _, err := Resume(snap); err == nil {
+            t.Error("expected an error resuming ", snap)
+        }
+    }
+
+    prog, _ := ParseCode("a := 0; WHILE(a == 0) DO a = 0 OD")
+    e := prog.NewExecution(10)
+    if err := e.Run(); err != ErrUndefined {
+        t.Fatal("expected ErrUndefined, returned: ", err)
+    }
+    if _, err := e.Snapshot(); err == nil {
+        t.Error("expected an error saving a failed execution")
+    }
+}
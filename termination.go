@@ -0,0 +1,526 @@
+package whileinterp
+
+import (
+	"sort"
+	"strconv"
+)
+
+/*
+    Termination analysis of the loops: for every WHILE, a linear ranking function over its variables is searched
+    on the comparisons of the condition (e.g. "y - x" for "WHILE(x < y) DO x = inc(x) OD"). A ranking function is
+    positive while the condition holds and decreases on every iteration, so the loop terminates.
+
+    The change of every variable on one iteration is computed symbolically over the body, and the ranges of the
+    interval analysis tell the conditions that are always true or always false. A loop terminates only if its
+    nested loops and the functions it calls terminate too.
+*/
+
+// TerminationVerdict is the result of the termination analysis of a loop
+type TerminationVerdict string
+
+// Terminates is the verdict of the loops that always terminate
+const Terminates TerminationVerdict = "terminates"
+
+// MayNotTerminate is the verdict of the loops that don't terminate if they are executed
+const MayNotTerminate TerminationVerdict = "may not terminate"
+
+// TerminationUnknown is the verdict of the loops whose termination couldn't be proved
+const TerminationUnknown TerminationVerdict = "unknown"
+
+// verdictOrder sorts the verdicts from the best to the worst
+var verdictOrder = map[TerminationVerdict]int{Terminates: 0, TerminationUnknown: 1, MayNotTerminate: 2}
+
+// LoopTermination is the result of the termination analysis of a WHILE
+type LoopTermination struct {
+	Pos Position //position of the WHILE
+	Cond string //code of the condition
+	Verdict TerminationVerdict //verdict of the analysis
+	Reason string //ranking function found or reason of the verdict
+}
+
+// String returns the result with the format "line:column: WHILE(cond) verdict (reason)"
+// return string
+func (lt *LoopTermination) String() string {
+	return lt.Pos.String() + ": " + whileFuncSTRING + "(" + lt.Cond + ") " + string(lt.Verdict) + " (" + lt.Reason + ")"
+}
+
+// linearExpr is a linear expression over the variables (e.g. y - x + 1)
+type linearExpr struct {
+	coefs map[string]int //coefficient of every variable
+	constant int //constant added
+}
+
+// sub returns the linear expression l - m
+// return linearExpr
+func (l linearExpr) sub(m linearExpr) linearExpr {
+	result := linearExpr{coefs: map[string]int{}, constant: l.constant - m.constant}
+	for name, c := range l.coefs {
+		result.coefs[name] += c
+	}
+	for name, c := range m.coefs {
+		result.coefs[name] -= c
+	}
+	return result
+}
+
+// scale returns the linear expression n*l + k
+// return linearExpr
+func (l linearExpr) scale(n int, k int) linearExpr {
+	result := linearExpr{coefs: map[string]int{}, constant: n*l.constant + k}
+	for name, c := range l.coefs {
+		result.coefs[name] = n * c
+	}
+	return result
+}
+
+// String returns the linear expression with the positive terms first (e.g. "y - x + 1")
+// return string
+func (l linearExpr) String() string {
+	names := []string{}
+	for name, c := range l.coefs {
+		if c != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	term := func(name string, c int) string {
+		if c < 0 {
+			c = -c
+		}
+		if c == 1 {
+			return name
+		}
+		return strconv.Itoa(c) + "*" + name
+	}
+
+	result := ""
+	for _, name := range names {
+		if c := l.coefs[name]; c > 0 {
+			if result != "" {
+				result += " + "
+			}
+			result += term(name, c)
+		}
+	}
+	constant := l.constant
+	if result == "" && constant > 0 { //the constant goes first if there are only negative terms
+		result, constant = strconv.Itoa(constant), 0
+	}
+	for _, name := range names {
+		if c := l.coefs[name]; c < 0 {
+			if result == "" {
+				result = "-" + term(name, c)
+			} else {
+				result += " - " + term(name, c)
+			}
+		}
+	}
+	switch {
+	case result == "":
+		result = strconv.Itoa(constant)
+	case constant > 0:
+		result += " + " + strconv.Itoa(constant)
+	case constant < 0:
+		result += " - " + strconv.Itoa(-constant)
+	}
+	return result
+}
+
+// linearOf returns the linear expression of a compared value
+// return linearExpr, bool (false if the value is a PROC call)
+func linearOf(v *valueExpr) (linearExpr, bool) {
+	switch v.kind {
+	case numberValue:
+		n, _ := strconv.Atoi(v.text)
+		return linearExpr{coefs: map[string]int{}, constant: n}, true
+	case varValue:
+		return linearExpr{coefs: map[string]int{v.text: 1}}, true
+	case indexValue: //the elements of an array aren't tracked
+		return linearExpr{}, false
+	}
+
+	switch v.text {
+	case "zero":
+		return linearExpr{coefs: map[string]int{}}, true
+	case "val":
+		return linearOf(v.params[0])
+	case "inc":
+		l, ok := linearOf(v.params[0])
+		return l.scale(1, 1), ok
+	case "dec":
+		l, ok := linearOf(v.params[0])
+		return l.scale(1, -1), ok
+	}
+	return linearExpr{}, false
+}
+
+// symValue is the value of a variable after some statements of a loop body, relative to the values before the body
+type symValue struct {
+	base string //variable whose initial value is added (empty if the value is a constant)
+	off int //number added to the value of base
+	known bool //the value is known exactly
+}
+
+// comparison is a comparison of a condition, with its comparator negated if the condition must be false
+type comparison struct {
+	op string //comparator
+	first *valueExpr //first value (left)
+	second *valueExpr //second value (right)
+}
+
+// conjuncts returns the comparisons that must hold for a logic expression to have the given value
+// return []comparison, bool (false if the logic expression isn't a conjunction of comparisons)
+func conjuncts(l *logicExpr, value bool) ([]comparison, bool) {
+	switch l.op {
+	case notOPSTRING:
+		return conjuncts(l.left, !value)
+	case andOPSTRING, orOPSTRING:
+		if (l.op == andOPSTRING) != value {
+			return nil, false
+		}
+		left, ok := conjuncts(l.left, value)
+		if !ok {
+			return nil, false
+		}
+		right, ok := conjuncts(l.right, value)
+		return append(left, right...), ok
+	}
+
+	op := l.op
+	if !value {
+		op = negations[op]
+	}
+	return []comparison{{op: op, first: l.first, second: l.second}}, true
+}
+
+// terminationAnalyzer looks for the ranking functions of the loops of a program
+type terminationAnalyzer struct {
+	ranges *rangeAnalyzer //interval analysis of the program, with the invariant of every loop
+	procs map[string]stmt //functions defined by their name
+	registry *Interpreter //interpreter whose registered functions can be called (Go functions, which always return)
+	loops map[int]*LoopTermination //result of every analyzed loop by its offset
+	funcs map[string]TerminationVerdict //verdict of every analyzed function
+	visiting map[string]bool //functions being analyzed (a recursive call can't be proved to terminate)
+}
+
+// analyzeStmts analyzes the loops of a list of statements
+func (t *terminationAnalyzer) analyzeStmts(stmts []stmt) {
+	for _, s := range stmts {
+		if s.op == whileFuncSTRING {
+			t.analyzeLoop(s)
+		} else if s.op == procSTRING {
+			t.analyzeStmts(s.body)
+		}
+	}
+}
+
+// analyzeLoop analyzes a WHILE, together with its nested loops and the functions it calls
+// return *LoopTermination
+func (t *terminationAnalyzer) analyzeLoop(s stmt) *LoopTermination {
+	if lt, ok := t.loops[s.offset]; ok {
+		return lt
+	}
+	lt := &LoopTermination{Pos: newPosition(t.ranges.name, t.ranges.code, s.offset), Cond: formatLogicExpr(s.cond)}
+	t.loops[s.offset] = lt
+
+	executed := false
+	lt.Verdict, lt.Reason, executed = t.rankLoop(s)
+	for _, nested := range s.body {
+		if nested.op != whileFuncSTRING {
+			continue
+		}
+		if n := t.analyzeLoop(nested); executed && verdictOrder[n.Verdict] > verdictOrder[lt.Verdict] {
+			lt.Verdict, lt.Reason = n.Verdict, "depends on the loop at " + n.Pos.String()
+		}
+	}
+
+	vars, funcs := []string{}, []string{}
+	getLogicExprFuncs(s.cond, &funcs)
+	getNames(s.body, &vars, &funcs)
+	for _, f := range funcs {
+		if v := t.analyzeFunc(f); executed && verdictOrder[v] > verdictOrder[lt.Verdict] {
+			lt.Verdict, lt.Reason = v, "depends on the function '" + f + "'"
+		}
+	}
+	return lt
+}
+
+// analyzeFunc analyzes the loops of a function and the functions it calls
+// return TerminationVerdict (the worst verdict found)
+func (t *terminationAnalyzer) analyzeFunc(name string) TerminationVerdict {
+	if v, ok := t.funcs[name]; ok {
+		return v
+	}
+	proc, ok := t.procs[name]
+	if _, isGo := t.registry.registered(name); !ok && isGo { //the registered functions are Go functions, which always return
+		return Terminates
+	} else if !ok {
+		return TerminationUnknown
+	}
+	if t.visiting[name] {
+		return TerminationUnknown
+	}
+	t.visiting[name] = true
+	defer delete(t.visiting, name)
+
+	verdict := Terminates
+	vars, funcs := []string{}, []string{}
+	getNames(proc.body, &vars, &funcs)
+	getValueFuncs(proc.value, &funcs)
+	for _, f := range funcs {
+		if v := t.analyzeFunc(f); verdictOrder[v] > verdictOrder[verdict] {
+			verdict = v
+		}
+	}
+	for _, s := range proc.body {
+		if s.op != whileFuncSTRING {
+			continue
+		}
+		if lt := t.analyzeLoop(s); verdictOrder[lt.Verdict] > verdictOrder[verdict] {
+			verdict = lt.Verdict
+		}
+	}
+
+	t.funcs[name] = verdict
+	return verdict
+}
+
+// rankLoop looks for a ranking function of a WHILE
+// return TerminationVerdict, string (reason), bool (the body can be executed)
+func (t *terminationAnalyzer) rankLoop(s stmt) (TerminationVerdict, string, bool) {
+	a := t.ranges
+	head := a.heads[s.offset]
+	if head == nil {
+		return Terminates, "never executed", false
+	}
+	body := a.filter(s.cond, head, true)
+	if body == nil {
+		return Terminates, "condition always false", false
+	}
+	if a.filter(s.cond, head, false) == nil {
+		return MayNotTerminate, "condition always true", true
+	}
+
+	syms := map[string]symValue{}
+	evalBody(s.body, syms)
+	delta := func(l linearExpr) (int, bool) {
+		result := 0
+		for name, c := range l.coefs {
+			if sv, ok := syms[name]; ok && c != 0 {
+				if !sv.known || sv.base != name {
+					return 0, false
+				}
+				result += c * sv.off
+			}
+		}
+		return result, true
+	}
+
+	vars, funcs := []string{}, []string{}
+	getLogicExprVars(s.cond, &vars)
+	getLogicExprFuncs(s.cond, &funcs)
+	unmodified := len(funcs) == 0
+	for _, name := range vars {
+		if sv, ok := syms[name]; ok && (!sv.known || sv.base != name || sv.off != 0) {
+			unmodified = false
+		}
+	}
+	if unmodified {
+		return MayNotTerminate, "variables of the condition not modified in the body", true
+	}
+
+	comps, isConj := conjuncts(s.cond, true)
+	kept := isConj //every comparison is kept true by the body
+	for _, c := range comps {
+		first, ok := linearOf(c.first)
+		second, ok2 := linearOf(c.second)
+		if !ok || !ok2 {
+			kept = false
+			continue
+		}
+		diff := second.sub(first)
+		d, ok := delta(diff)
+		if !ok {
+			kept = false
+			continue
+		}
+
+		var rank linearExpr //positive while the comparison holds
+		switch c.op {
+		case littleofOPSTRING:
+			rank = diff
+		case littleofOrIsOPSTRING:
+			rank = diff.scale(1, 1)
+		case biggerofOPSTRING:
+			rank, d = diff.scale(-1, 0), -d
+		case biggerofOrIsOPSTRING:
+			rank, d = diff.scale(-1, 1), -d
+		case isNotOPSTRING: //the difference must reach zero one by one from the side it starts
+			entry := a.entries[s.offset]
+			fi, si := a.evalInterval(c.first, entry), a.evalInterval(c.second, entry)
+			if d == -1 && !fi.HiInf && !si.LoInf && fi.Hi <= si.Lo {
+				return Terminates, "ranking function " + diff.String(), true
+			}
+			if d == 1 && !si.HiInf && !fi.LoInf && si.Hi <= fi.Lo {
+				return Terminates, "ranking function " + diff.scale(-1, 0).String(), true
+			}
+			kept = kept && d == 0
+			continue
+		default:
+			kept = kept && d == 0
+			continue
+		}
+		if d < 0 {
+			return Terminates, "ranking function " + rank.String(), true
+		}
+	}
+	if kept {
+		return MayNotTerminate, "the body never makes the condition false", true
+	}
+	return TerminationUnknown, "no ranking function found", true
+}
+
+// evalBody computes symbolically the values of the variables after the statements of a loop body
+// (the variables declared on the body are local to it, the variables they shadow keep their value)
+func evalBody(stmts []stmt, syms map[string]symValue) {
+	shadowed := map[string]*symValue{} //value of the variables when shadowed (nil if not modified yet)
+	for _, s := range stmts {
+		switch s.op {
+		case whileFuncSTRING: //the values after a nested loop are unknown
+			modified := map[string]bool{}
+			getModifiedVars(s.body, modified)
+			for name := range modified {
+				syms[name] = symValue{}
+			}
+			continue
+		case printSTRING:
+			continue
+		case readSTRING: //the value read is unknown
+			syms[s.name] = symValue{}
+			continue
+		case declareOPSTRING:
+			if _, ok := shadowed[s.name]; !ok {
+				if sv, ok := syms[s.name]; ok {
+					shadowed[s.name] = &sv
+				} else {
+					shadowed[s.name] = nil
+				}
+			}
+		}
+		if s.index != nil || isArrayDecl(s) { //the elements of an array are unknown
+			syms[s.name] = symValue{}
+			continue
+		}
+		syms[s.name] = evalSym(s.value, syms)
+	}
+
+	for name, sv := range shadowed {
+		if sv == nil {
+			delete(syms, name)
+		} else {
+			syms[name] = *sv
+		}
+	}
+}
+
+// evalSym computes symbolically a value assigned on a loop body
+// return symValue
+func evalSym(v *valueExpr, syms map[string]symValue) symValue {
+	switch v.kind {
+	case numberValue:
+		n, _ := strconv.Atoi(v.text)
+		return symValue{off: n, known: true}
+	case varValue:
+		if sv, ok := syms[v.text]; ok {
+			return sv
+		}
+		return symValue{base: v.text, known: true} //not modified yet
+	case indexValue:
+		return symValue{}
+	}
+
+	switch v.text {
+	case "zero":
+		return symValue{known: true}
+	case "val":
+		return evalSym(v.params[0], syms)
+	case "inc", "dec":
+		sv := evalSym(v.params[0], syms)
+		if v.text == "inc" {
+			sv.off++
+		} else {
+			sv.off--
+		}
+		return sv
+	}
+	return symValue{} //the value returned by a PROC is unknown
+}
+
+// newTerminationAnalyzer initializes the analysis of a program, whose source code is given. The functions of its
+// libraries are analyzed on their own code, once (analyzed saves their verdicts by the name of their library)
+// return *terminationAnalyzer
+func (p *program) newTerminationAnalyzer(code string, analyzed map[string]map[string]TerminationVerdict) *terminationAnalyzer {
+	t := &terminationAnalyzer{ranges: p.newRangeAnalyzer(code), procs: map[string]stmt{}, registry: p.funcs, loops: map[int]*LoopTermination{},
+		funcs: map[string]TerminationVerdict{}, visiting: map[string]bool{}}
+	for _, s := range p.stmts {
+		if s.op == procSTRING {
+			t.procs[s.name] = s
+		}
+	}
+
+	libs, _ := p.libraries()
+	for _, lib := range libs {
+		verdicts, ok := analyzed[lib.name]
+		if !ok {
+			analyzed[lib.name] = map[string]TerminationVerdict{} //the standard library is imported by itself too
+			lp := initProgram()
+			lp.name, lp.code, lp.stmts, lp.funcs = lib.name, lib.code, lib.stmts, p.funcs
+			lt := lp.newTerminationAnalyzer(lib.code, analyzed)
+			verdicts = map[string]TerminationVerdict{}
+			for _, s := range lib.stmts {
+				if s.op == procSTRING {
+					verdicts[s.name] = lt.analyzeFunc(s.name)
+				}
+			}
+			analyzed[lib.name] = verdicts
+		}
+		for name, verdict := range verdicts {
+			t.funcs[lib.qualify(name)] = verdict
+		}
+	}
+	return t
+}
+
+// analyzeTermination analyzes the termination of every loop of a program, whose source code is given
+// return []*LoopTermination (in order of appearance)
+func (p *program) analyzeTermination(code string) []*LoopTermination {
+	t := p.newTerminationAnalyzer(code, map[string]map[string]TerminationVerdict{})
+	t.analyzeStmts(p.stmts)
+
+	offsets := []int{}
+	for offset := range t.loops {
+		offsets = append(offsets, offset)
+	}
+	sort.Ints(offsets)
+	result := make([]*LoopTermination, len(offsets))
+	for i, offset := range offsets {
+		result[i] = t.loops[offset]
+	}
+	return result
+}
+
+// AnalyzeTermination looks for a ranking function of every loop of the code, and returns whether every loop
+// "terminates", "may not terminate" or its termination is "unknown"
+// the code must pass the static checker first, otherwise its errors are returned
+// return []*LoopTermination, error
+func AnalyzeTermination(code string) ([]*LoopTermination, error) {
+	p := initProgram()
+	if err := p.getStmts(code); err != nil {
+		return nil, err
+	}
+	if errs := p.check(code); len(errs) > 0 {
+		return nil, errs
+	}
+	return p.analyzeTermination(code), nil
+}
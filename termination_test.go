@@ -0,0 +1,209 @@
+package whileinterp
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+/*********************** TESTING ***********************/
+func TestAnalyzeTerminationRanking(t *testing.T) {
+    code := `x := 0
+y := 10
+WHILE(x < y) DO
+    x = inc(x)
+OD
+WHILE(y > 0) DO
+    y = dec(y)
+    x = inc(x)
+OD
+WHILE(x >= inc(inc(y)) AND y != 100) DO
+    x = dec(dec(x))
+    y = inc(y)
+OD
+WHILE(dec(x) > y) DO
+    x = dec(x)
+OD`
+    expec := []string{
+        "3:1: WHILE(x < y) terminates (ranking function y - x)",
+        "6:1: WHILE(y > 0) terminates (ranking function y)",
+        "10:1: WHILE(x >= inc(inc(y)) AND y != 100) terminates (ranking function x - y - 1)",
+        "14:1: WHILE(dec(x) > y) terminates (ranking function x - y - 1)",
+    }
+
+    doTestAnalyzeTermination(code, expec, t)
+}
+
+func TestAnalyzeTerminationNotEqual(t *testing.T) {
+    code := `x := 0
+y := 5
+WHILE(x != y) DO
+    x = inc(x)
+OD
+z := 7
+WHILE(y != z) DO
+    y = inc(y)
+OD
+WHILE(x != z) DO
+    x = inc(inc(x))
+OD`
+    expec := []string{
+        "3:1: WHILE(x != y) terminates (ranking function y - x)",
+        "7:1: WHILE(y != z) terminates (ranking function z - y)",
+        "10:1: WHILE(x != z) unknown (no ranking function found)",
+    }
+
+    doTestAnalyzeTermination(code, expec, t)
+}
+
+func TestAnalyzeTerminationMayNotTerminate(t *testing.T) {
+    code := `x := 1
+y := 3
+WHILE(x < y) DO
+    y = inc(y)
+    x = inc(x)
+OD
+WHILE(x > 5 OR y > 5) DO
+    x = val(x)
+OD
+WHILE(x > 0) DO
+    x = inc(x)
+OD`
+    expec := []string{
+        "3:1: WHILE(x < y) may not terminate (the body never makes the condition false)",
+        "7:1: WHILE(x > 5 OR y > 5) may not terminate (variables of the condition not modified in the body)",
+        "10:1: WHILE(x > 0) may not terminate (condition always true)",
+    }
+
+    doTestAnalyzeTermination(code, expec, t)
+}
+
+func TestAnalyzeTerminationShadowed(t *testing.T) {
+    code := `PROC f(x) DO
+    WHILE(x < 3) DO
+        x := inc(x)
+    OD
+RETURN x OD
+PROC g(x) DO
+    WHILE(x < 3) DO
+        x = inc(x)
+        x := 0
+    OD
+RETURN x OD`
+    expec := []string{
+        "2:5: WHILE(x < 3) may not terminate (variables of the condition not modified in the body)",
+        "7:5: WHILE(x < 3) terminates (ranking function 3 - x)",
+    }
+
+    doTestAnalyzeTermination(code, expec, t)
+}
+
+func TestAnalyzeTerminationNested(t *testing.T) {
+    code := `PROC mult(a, b) DO
+    r := 0
+    c := 0
+    WHILE(a > 0) DO
+        c = val(b)
+        WHILE(c > 0) DO
+            r = inc(r)
+            c = dec(c)
+        OD
+        a = dec(a)
+    OD
+    RETURN r
+OD
+PROC loop(a) DO
+    WHILE(a == a) DO
+        a = inc(a)
+    OD
+    RETURN a
+OD
+x := 3
+WHILE(x > 10) DO
+    x = loop(x)
+OD
+WHILE(x > 0) DO
+    x = dec(x)
+    x = mult(x, loop(2))
+OD
+y := 4
+WHILE(y > 0) DO
+    y = dec(y)
+    WHILE(x != y) DO
+        x = inc(inc(x))
+    OD
+OD`
+    expec := []string{
+        "4:5: WHILE(a > 0) terminates (ranking function a)",
+        "6:9: WHILE(c > 0) terminates (ranking function c)",
+        "15:5: WHILE(a == a) may not terminate (the body never makes the condition false)",
+        "21:1: WHILE(x > 10) terminates (condition always false)",
+        "24:1: WHILE(x > 0) may not terminate (depends on the function 'loop')",
+        "29:1: WHILE(y > 0) unknown (depends on the loop at 31:5)",
+        "31:5: WHILE(x != y) unknown (no ranking function found)",
+    }
+
+    doTestAnalyzeTermination(code, expec, t)
+}
+
+func TestAnalyzeTerminationImported(t *testing.T) {
+    dir := doTestLibraryFiles(map[string]string{
+        "lib.while": "IMPORT std\nPROC loop(n) DO WHILE(n == n) DO n = val(n) OD RETURN n OD\nPROC square(n) DO RETURN mult(n, n) OD",
+    }, t)
+    defer os.RemoveAll(dir)
+
+    codes := map[string]string{
+        "IMPORT \"lib.while\"; x := 1; WHILE(x > 0) DO x = dec(x); y := lib.loop(x) OD": "1:29: WHILE(x > 0) may not terminate (depends on the function 'lib.loop')",
+        "IMPORT \"lib.while\"; x := 1; WHILE(x > 0) DO x = dec(x); y := lib.square(x) OD": "1:29: WHILE(x > 0) terminates (ranking function x)",
+        "IMPORT std; x := 1; WHILE(x > 0) DO x = dec(x); y := mult(x, x) OD": "1:21: WHILE(x > 0) terminates (ranking function x)",
+    }
+    name := filepath.Join(dir, "main.while")
+    for code, expected := range codes {
+        prog, err := ParseReader(name, strings.NewReader(code))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if loops := prog.Termination(); len(loops) != 1 || strings.TrimPrefix(loops[0].String(), name + ":") != expected {
+            t.Error("unexpected termination of '", code, "'\n returned: ", loops, "\n expected: ", expected)
+        }
+    }
+}
+
+func TestAnalyzeTerminationErrors(t *testing.T) {
+    if _, err := AnalyzeTermination("WHILE(x < 2) DO x = inc(x) OD"); err == nil {
+        t.Error("expected error not returned")
+    }
+}
+
+func TestLinearExprString(t *testing.T) {
+    tests := map[string]linearExpr{
+        "y - x + 1": {coefs: map[string]int{"x": -1, "y": 1}, constant: 1},
+        "10 - x": {coefs: map[string]int{"x": -1}, constant: 10},
+        "-x - 2": {coefs: map[string]int{"x": -1}, constant: -2},
+        "2*a + b": {coefs: map[string]int{"a": 2, "b": 1, "c": 0}},
+        "0": {coefs: map[string]int{}},
+    }
+    for expec, l := range tests {
+        if l.String() != expec {
+            t.Error("unexpected returned expression:\n returned: ", l.String(), "\n expected: ", expec)
+        }
+    }
+}
+
+func doTestAnalyzeTermination(code string, expec []string, t *testing.T) {
+    results, err := AnalyzeTermination(code)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    if len(results) != len(expec) {
+        t.Error("unexpected returned results:\n returned: ", results, "\n expected: ", expec)
+        return
+    }
+    for i, lt := range results {
+        if lt.String() != expec[i] {
+            t.Error("unexpected returned result:\n returned: ", lt, "\n expected: ", expec[i])
+        }
+    }
+}
@@ -7,12 +7,24 @@
         - arithmetic operators like: "+", "-", "*", "/", "%" are not defined. Instead use the declared functions.
         - the declaration of variables is used using the operator ":=".
         - setting the variable's value is possible using "=" (the variable must to be already declared).
-        - comparator operators are: "<", ">", "==", "!=".
-        - for the moment, a ";" has to be used to divide the different parts/blocks of the code.
-        - for the moment, just a command can be used inside the WHILE.
+        - comparator operators are: "<", "<=", ">", ">=", "==", "!=".
+        - comparisons can be joined with "AND", "OR", "NOT" and parenthesis (NOT has precedence over AND, and AND over OR).
+        - the statements are divided by ";" or by newlines (the last ";" and blank lines are optional).
+        - the statements inside the WHILE are divided the same way, and WHILEs can be nested.
+        - comments start with "#" or "//" and last until the end of the line.
+        - the compared values can be numbers, variables or function calls (e.g. "WHILE(inc(x1) < 10)").
+        - the parameters of a function can be function calls too (e.g. "x1 = inc(inc(x1))").
+        - functions are defined with "PROC name(a, b) DO ... RETURN value OD" and can only use their parameters and variables.
+        - Go functions can be registered on an Interpreter (RegisterFunc) and called by its programs.
+        - arrays are declared with "a := array(n)" (n elements, all 0), and their elements are read and assigned
+          with "a[i]" (e.g. "a[i] = inc(a[i])"), starting at 0. An index out of the bounds stops the execution.
+        - "IMPORT std" imports the standard library: add, sub, mult, div, mod and exp (e.g. "x := mult(2, 3)").
     
     Example code:
 	   "xo := 2; x1 := inc(3); x2 := dec(2); WHILE(xo != x1) DO xo = inc(xo) OD;"
+	   "xo := 0; x1 := 3; x2 := 5; WHILE(xo < x1 AND NOT(x2 == xo)) DO xo = inc(xo); x2 = dec(x2) OD;"
+	   "PROC add(a, b) DO WHILE(b > 0) DO a = inc(a); b = dec(b) OD RETURN a OD; xo := add(2, 3);"
+	   "xo := 2  # first value\n x1 := 3\n\n WHILE(xo < x1) DO\n xo = inc(xo)\n OD"
 */
 
 package whileinterp
@@ -22,12 +34,16 @@ import (
 	"strings"
 	"errors"
 	"strconv"
+	"io"
 )
 
 // possOP lists the current operations available
-var possOP = [...]string{"=", ":=", "<", ">", "==", "!="}
+var possOP = [...]string{"=", ":=", "<", "<=", ">", ">=", "==", "!="}
 
-// possFunc lists the current functions available
+// comparators lists the operations that compare two values
+var comparators = [...]string{littleofOPSTRING, littleofOrIsOPSTRING, biggerofOPSTRING, biggerofOrIsOPSTRING, isOPSTRING, isNotOPSTRING}
+
+// possFunc lists the predefined functions available on every program (defined on predefinedFuncs)
 var possFunc = [...]string{"zero", "inc", "dec", "val"}
 
 // whileFuncSTRING defines the while syntax in a string
@@ -51,93 +67,245 @@ const littleofOPSTRING = "<"
 // biggerofOPSTRING defines the operator for the ">" functionality
 const biggerofOPSTRING = ">"
 
+// littleofOrIsOPSTRING defines the operator for the "<=" functionality
+const littleofOrIsOPSTRING = "<="
+
+// biggerofOrIsOPSTRING defines the operator for the ">=" functionality
+const biggerofOrIsOPSTRING = ">="
+
 // isOPSTRING defines the operator for the comparation "==" functionaliy
 const isOPSTRING = "=="
 
 // isNotOPSTRING defines the operator for the comparation "!=" functionality
 const isNotOPSTRING = "!="
 
-// sizeDeclareOP defines the size of the declare operator
-const sizeDeclareOP = len(declareOPSTRING)
+// andOPSTRING defines the operator for the logic "AND" functionality
+const andOPSTRING = "AND"
+
+// orOPSTRING defines the operator for the logic "OR" functionality
+const orOPSTRING = "OR"
+
+// notOPSTRING defines the operator for the logic "NOT" functionality
+const notOPSTRING = "NOT"
+
+// procSTRING defines the syntax of a function definition in a string
+const procSTRING = "PROC"
+
+// returnSTRING defines the syntax of the returned value of a function in a string
+const returnSTRING = "RETURN"
+
+// importSTRING defines the syntax of the import of a library in a string
+const importSTRING = "IMPORT"
 
-// sizeAssignOP defines the size of the assign operator
-const sizeAssignOP = len(assignOPSTRING)
+// arraySTRING defines the function that declares an array in a string
+const arraySTRING = "array"
 
-// sizeParenthesis defines the size of a parenthesis
-const sizeParenthesis = 1
+// maxCallDepth defines the maximum number of nested function calls
+const maxCallDepth = 10000
 
 // variable type is used for defining every variable and his value
 type variable struct {
 	name string //name of the variable (used as a id for the variable)
 	value int	//value of the variable
+	array []int //elements of the variable if it is an array (nil for a natural number)
+}
+
+// Position defines a location (line and column) on the source code
+type Position struct {
+	File string //name of the source of the code (empty if the code has no name)
+	Line int //line of the location, starting at 1
+	Column int //column of the location, starting at 1
+}
+
+// String returns the position with the format "file:line:column" (or "line:column" if the code has no name)
+// return string
+func (pos Position) String() string {
+	lineColumn := strconv.Itoa(pos.Line) + ":" + strconv.Itoa(pos.Column)
+	if pos.File == "" {
+		return lineColumn
+	}
+	return pos.File + ":" + lineColumn
+}
+
+// newPosition returns the position of an offset on the source code named name
+// return Position
+func newPosition(name string, code string, offset int) Position {
+	line := 1 + strings.Count(code[:offset], "\n")
+	column := offset - strings.LastIndex(code[:offset], "\n")
+	
+	return Position{File: name, Line: line, Column: column}
+}
+
+// valueKind defines the different kinds of values
+type valueKind int
+
+const (
+	numberValue valueKind = iota //number (e.g. 2)
+	varValue //variable (e.g. x1)
+	callValue //function call (e.g. inc(x1))
+	indexValue //element of an array (e.g. a[i]), whose index is the only parameter
+)
+
+// valueExpr is any value defined on the code: a number, a variable or a function call
+type valueExpr struct {
+	kind valueKind //kind of the value
+	text string //digits of the number, name of the variable or name of the function
+	params []*valueExpr //parameters of the function call
+	offset int //offset of the value on the source code
 }
 
-// stmt defines every block of code divided by ";"
+// stmt defines every statement of the code (a declaration, an assignment, a WHILE, a PROC, an IMPORT, a PRINT or a READ)
 type stmt struct {
 	content string //code of the stmt
+	offset int //offset of the stmt on the source code
+	op string //operation of the stmt: ":=", "=", "WHILE", "PROC", "IMPORT", "PRINT" or "READ"
+	name string //variable declared, assigned or read, function defined or library imported (its namespace for a file)
+	value *valueExpr //value declared, assigned or printed, or value returned by the function
+	cond *logicExpr //logic expression of the WHILE
+	body []stmt //statements of the body of the WHILE or the PROC
+	params []string //parameters of the PROC
+	nameOffset int //offset of the name of the PROC, the library imported or the variable read on the source code
+	paramOffsets []int //offsets of the parameters of the PROC on the source code
+	index *valueExpr //index of the element assigned (nil if the whole variable is declared or assigned)
+	lib *library //library imported, or library that defines the PROC (nil for the PROCs of the program)
+	path string //path of the file imported, as written on the code (empty for the standard library)
 }
 
-//l ogicExpr is any possible logic expression defined (e.g. x1 > 2)
+// logicExpr is any possible logic expression defined (e.g. x1 > x2, x1 < x2 AND NOT(x3 == x4))
 type logicExpr struct {
-	op string //operation that is defined 
-	firstVar variable //first variable (left)
-	secondVar variable //second variable (right)
+	op string //operation that is defined: a comparator or a logic operator (AND, OR, NOT)
+	first *valueExpr //first value (left) of a comparison
+	second *valueExpr //second value (right) of a comparison
+	left *logicExpr //first expression (left) of AND, OR, or the negated expression of NOT
+	right *logicExpr //second expression (right) of AND, OR
+	offset int //offset of the expression on the source code
 }
 
 // parseExpr parses an expression and saves it to the current object
 // return error
 func (l *logicExpr) parseExpr(exprString string) error {
-	if pos := strings.Index(exprString, littleofOPSTRING); pos != -1 { //if expression contains <
-		l.op = littleofOPSTRING		
-		l.firstVar.name = strings.TrimSpace(exprString[:pos]);
-		l.secondVar.name = strings.TrimSpace(exprString[pos + len(littleofOPSTRING):]) 
-
-		return nil
-	} else if pos := strings.Index(exprString, biggerofOPSTRING); pos != -1 { //if expression contains >
-		l.op = biggerofOPSTRING
-		l.firstVar.name = strings.TrimSpace(exprString[:pos]);
-		l.secondVar.name = strings.TrimSpace(exprString[pos + len(biggerofOPSTRING):])
+	tokens, err := tokenize("", exprString)
+	if err != nil {
+		return err
+	}
 
-		return nil
-	} else if pos := strings.Index(exprString, isOPSTRING); pos != -1 { //if expression contains ==
-		l.op = isOPSTRING
-		l.firstVar.name = strings.TrimSpace(exprString[:pos]);
-		l.secondVar.name = strings.TrimSpace(exprString[pos + len(isOPSTRING):])
-		
-		return nil
-	} else if pos := strings.Index(exprString, isNotOPSTRING); pos != -1 { //if expression contains !=
-		l.op = isNotOPSTRING
-		l.firstVar.name = strings.TrimSpace(exprString[:pos]);
-		l.secondVar.name = strings.TrimSpace(exprString[pos + len(isNotOPSTRING):])
-		
-		return nil
-	} else { //expression format unknown
-		return errors.New("parseExpr: operation not defined '" + exprString + "'")
+	ps := &parser{code: exprString, tokens: tokens}
+	expr, err := ps.parseLogicExpr()
+	if err != nil {
+		return err
 	}
+	if t := ps.peek(); t.kind != eofToken {
+		return ps.errorf(t, "end of expression expected, found " + describe(t))
+	}
+
+	*l = *expr
+	return nil
 }
 
-// evalLogicExpr evaluates if the expression is true or false
-// return bool
-func (l *logicExpr) evalLogicExpr() bool {
-	switch(l.op) {
+// eval evaluates if the expression is true or false with the variables of a program
+// return bool, error
+func (l *logicExpr) eval(p *program) (bool, error) {
+	switch l.op {
+		case andOPSTRING:
+			if ok, err := l.left.eval(p); !ok || err != nil {
+				return false, err
+			}
+			return l.right.eval(p)
+		case orOPSTRING:
+			if ok, err := l.left.eval(p); ok || err != nil {
+				return ok, err
+			}
+			return l.right.eval(p)
+		case notOPSTRING:
+			ok, err := l.left.eval(p)
+			return !ok, err
+	}
+
+	first, err := p.evalValue(l.first)
+	if err != nil {
+		return false, err
+	}
+	second, err := p.evalValue(l.second)
+	if err != nil {
+		return false, err
+	}
+
+	switch l.op {
 		case littleofOPSTRING:
-			return l.firstVar.value < l.secondVar.value
+			return first < second, nil
+		case littleofOrIsOPSTRING:
+			return first <= second, nil
 		case biggerofOPSTRING:
-			return l.firstVar.value > l.secondVar.value		
+			return first > second, nil
+		case biggerofOrIsOPSTRING:
+			return first >= second, nil
 		case isOPSTRING:
-			return l.firstVar.value == l.secondVar.value
+			return first == second, nil
 		case isNotOPSTRING:
-			return l.firstVar.value != l.secondVar.value
+			return first != second, nil
 		default:
-			return false
+			return false, errors.New("eval: operation not defined '" + l.op + "'")
 	}
 }
 
+// RuntimeError is an error found while executing a statement (e.g. an index out of the bounds of an array)
+type RuntimeError struct {
+	Pos Position //position of the value or the statement that failed
+	Msg string //description of the error
+}
+
+// Error returns the error with the format "line:column: message"
+// return string
+func (e *RuntimeError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
 // program is the main class that envolves any variable and statement defined
-// annotation: program can have different "subprograms"
 type program struct {
+	name string //name of the source of the code (e.g. the file), shown on the positions
+	code string //source code of the program, used to compute the positions of the runtime errors
 	vars []variable //slice of the different variables declared on the program
 	stmts []stmt //slice of the different statements declared on the program
+	procs map[string]*stmt //functions defined on the program
+	steps int //number of statements executed by the program
+	maxSteps int //maximum number of statements to execute (0 for no limit)
+	depth int //number of nested function calls being executed
+	level int //number of nested blocks (loop bodies and function calls) being executed
+	tracer tracer //notified before every statement is executed (nil if the program is not traced)
+	caller *program //program that called the function being executed (nil for the main program)
+	procName string //name of the function being executed (empty for the main program)
+	current *stmt //statement being executed
+	accelerate bool //the counting loops are executed at once
+	pauseRequested int32 //set (atomically) to pause the main program before its next statement
+	pauseAt int //steps after which the main program is paused before its next statement (0 for never)
+	scopes []int //number of variables when every loop body being executed was entered (its variables follow them)
+	funcs *Interpreter //interpreter whose registered functions can be called (nil for only the predefined ones)
+	stdio programIO //input of the READ statements and output of the PRINT statements (nil for the ones of the interpreter)
+}
+
+// tracer is notified by a program while it is executed (e.g. by a debugger)
+type tracer interface {
+	// beforeStmt is called before a statement is executed (or before the value of a PROC is returned),
+	// the execution is stopped if an error is returned
+	beforeStmt(p *program, s *stmt) error
+	// stepLimit is called when the program exhausts its steps, before ErrUndefined is returned
+	stepLimit(p *program)
+}
+
+// ErrUndefined is returned when a program exhausts its steps before finishing (it may diverge)
+var ErrUndefined = errors.New("undefined: step budget exhausted")
+
+// step counts an executed statement of a program
+// return error (ErrUndefined if the steps are exhausted)
+func (p *program) step() error {
+	p.steps++
+	if p.maxSteps > 0 && p.steps > p.maxSteps {
+		if p.tracer != nil {
+			p.tracer.stepLimit(p)
+		}
+		return ErrUndefined
+	}
+	return nil
 }
 
 // initProgram initializes the properties of a program
@@ -146,30 +314,43 @@ func initProgram() *program {
 	p := new(program)
 	p.vars = []variable{}
 	p.stmts = []stmt{}
+	p.procs = map[string]*stmt{}
+	p.accelerate = true //unless its interpreter executes the counting loops one by one
 	
 	return p
 }
 
-// getStmts returns the different statements defined on a code
+// getStmts parses the different statements defined on a code
 // return error
 func (p *program) getStmts(code string) error {
-	listStmts := strings.Split(code, ";")	
-	if len(listStmts) == 0 {
-		return errors.New("getStmts: code doesn't have any delimiter")
+	stmts, err := parseSource(p.name, code)
+	if err != nil {
+		return err
 	}
 	
-	for _, v := range listStmts {
-		stmt := new(stmt)
-		stmt.content = strings.TrimSpace(v)		
-		p.stmts = append(p.stmts, *stmt) //save every statement to the program object
-	}
+	p.stmts = append(p.stmts, stmts...) //save every statement to the program object
 	return nil
 }
 
-// isVarPresent checks if a variable has been already declared in a program
+// isVarPresent checks if a variable has been already declared in a program (on the current block or an enclosing one)
 // return bool
 func (p *program) isVarPresent(name string) bool {
-	for _, v := range p.vars {
+	for i := len(p.vars) - 1; i >= 0; i-- {
+		if p.vars[i].name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isVarLocal checks if a variable has been already declared on the current block of a program
+// return bool
+func (p *program) isVarLocal(name string) bool {
+	start := 0
+	if len(p.scopes) > 0 {
+		start = p.scopes[len(p.scopes) - 1]
+	}
+	for _, v := range p.vars[start:] {
 		if v.name == name {
 			return true
 		}
@@ -177,21 +358,21 @@ func (p *program) isVarPresent(name string) bool {
 	return false
 }
 
-// addVar adds a variable in a program
+// addVar adds a variable in the current block of a program, shadowing the variables of the enclosing blocks
 // return error
 func (p *program) addVar(newVar *variable) error {
-    if !p.isVarPresent(newVar.name) {
+    if !p.isVarLocal(newVar.name) {
         p.vars = append(p.vars, *newVar)
         return nil
     }    
     return errors.New("addVar: variable '" + newVar.name + "' already present")
 }
 
-// setVar modifies a variable in a program
+// setVar modifies a variable in a program (the innermost one, if shadowed)
 // return error
 func (p *program) setVar(newVar *variable) error {
-	for i, v := range p.vars {
-		if v.name == newVar.name {
+	for i := len(p.vars) - 1; i >= 0; i-- {
+		if p.vars[i].name == newVar.name {
 			p.vars[i] = *newVar
 			return nil
 		}
@@ -199,179 +380,339 @@ func (p *program) setVar(newVar *variable) error {
 	return errors.New("setVar: variable not present")
 }
 
-// getVar returns a variable from the program given a name (id)
+// getVar returns a variable from the program given a name (id), the innermost one if shadowed
 // return variable, error
 func (p *program) getVar(name string) (variable, error) {
-	for _, v := range p.vars {
-		if v.name == name {
-			return v, nil
+	for i := len(p.vars) - 1; i >= 0; i-- {
+		if p.vars[i].name == name {
+			return p.vars[i], nil
 		}
 	}
 	return *new(variable), errors.New("getVar: variable not present")
 }
 
-// parseWhile parses a while statement given a code and returns the different properties (logic expression, body code, error)
-// return *logicExpr, string, error
-func (p *program) parseWhile(whileCode string) (*logicExpr, string, error) {
-	whileCode = strings.TrimSpace(whileCode)
-	
-	exprString := getExprFromWhile(whileCode) //get the logic expression
-	stmtString := getStmtFromWhile(whileCode) //get the statement to do
-	
-	expr := new(logicExpr)	
-	if err := expr.parseExpr(exprString); err != nil {
-		return new(logicExpr), "", err
-	}
-	
-	if val, err := p.getVar(expr.firstVar.name); err == nil { //get the value of the defined variable from the program object
-		expr.firstVar = val
-	} else {
-		return new(logicExpr), "", errors.New("parseWhile: variable '" + expr.firstVar.name + "' not defined")
-	}	
-		
-	if val, err := p.getVar(expr.secondVar.name); err == nil { //get the value of the defined variable from the program object
-		expr.secondVar = val
-	} else {
-		return new(logicExpr), "", errors.New("parseWhile: variable '" + expr.secondVar.name + "' not defined")
-	}
-	
-	return expr, stmtString, nil
-}
-
-// getExprFromWhile returns the logic expression from a while block
-// return string
-func getExprFromWhile(whileCode string) string {
-	result := whileCode[strings.Index(whileCode, whileFuncSTRING) : strings.Index(whileCode, doSTRING)]
-	result = result[strings.Index(result, "(") + sizeParenthesis : strings.Index(result, ")")]
-
-	return result
+// parseProgram executes the whole program
+// all the operations made will be saved on the program object
+// return error
+func (p *program) parseProgram() error {
+	p.defineProcs()
+	return p.execStmts(p.stmts)
 }
 
-// getStmtFromWhile returns the statement to do from a while block
-// return string
-func getStmtFromWhile(whileCode string) string {
-	return strings.TrimSpace(whileCode[strings.Index(whileCode, doSTRING) + len(doSTRING) : strings.Index(whileCode, odSTRING)])	
+// defineProcs saves the functions imported and defined on the program, which can be called before their definition
+func (p *program) defineProcs() {
+	libs, _ := p.libraries()
+	for _, lib := range libs {
+		for i, s := range lib.stmts {
+			if s.op == procSTRING {
+				p.procs[lib.qualify(s.name)] = &lib.stmts[i]
+			}
+		}
+	}
+	for i, s := range p.stmts {
+		if s.op == procSTRING {
+			p.procs[s.name] = &p.stmts[i]
+		}
+	}
 }
 
-// parseProgram parses and executes the whole program
-// all the operations made will be saved on the program object
+// execStmts executes a list of statements
 // return error
-func (p *program) parseProgram() error {
-	for _, s := range p.stmts {
-		if pos := strings.Index(s.content, "WHILE"); pos != - 1 { //if is a "WHILE" statement
-			expr, stmtWhile, err := p.parseWhile(s.content)
-			if err != nil {
+func (p *program) execStmts(stmts []stmt) error {
+	for i, s := range stmts {
+		if s.op == procSTRING || s.op == importSTRING { //the functions are only executed when called
+			continue
+		}
+		p.current = &stmts[i]
+		if p.caller == nil && p.shouldPause() { //the programs are only paused between the statements of the main program
+			return ErrPaused
+		}
+		if p.tracer != nil {
+			if err := p.tracer.beforeStmt(p, p.current); err != nil {
 				return err
 			}
-			
-			subprogram := initProgram() //a subprogram is the "do" statement from the WHILE block
-			subprogram.getStmts(stmtWhile)
-			subprogram.vars = p.vars
-			
-			for expr.evalLogicExpr() { //the subprogram will be executed as much the expr will be false				
-				subprogram.parseProgram() //the subprogram has to be parsed
-				
-				expr.firstVar, _ = subprogram.getVar(expr.firstVar.name) //refresh the changes to the expression variables
-				expr.secondVar, _ = subprogram.getVar(expr.secondVar.name)		
-			}
-			
-			p.vars = subprogram.vars //save the changes on the main program, if finished
-			
-		} else { //normal statement (declarations, assignations)
-			if pos := strings.Index(s.content, ":="); pos != -1 { //if a declaration
-				v := new(variable)
-				v.name = strings.TrimSpace(s.content[:pos])
-				
-				if p.isVarPresent(v.name) { //if variable already on the program -> error
-					return errors.New("parseProgram: error using operator ':='. variable '" + v.name + "' already present.")
+		}
+		if err := p.step(); err != nil {
+			return err
+		}
+		
+		switch s.op {
+			case whileFuncSTRING:
+				if err := p.execWhile(s); err != nil {
+					return err
+				}
+			case printSTRING:
+				if err := p.execPrint(s); err != nil {
+					return err
+				}
+			case readSTRING:
+				if err := p.execRead(s); err != nil {
+					return err
+				}
+			case declareOPSTRING: //if a declaration
+				if p.isVarLocal(s.name) { //if variable already on the block -> error
+					return errors.New("parseProgram: error using operator ':='. variable '" + s.name + "' already present.")
 				}
 				
-				val, err := strconv.Atoi(s.content[(pos+sizeDeclareOP)+1:]) //get value of the declaration
-				if err != nil {
-					val, err = p.execFunc(s.content[(pos+sizeDeclareOP)+1:]) //execute the function for this operator (:=)
+				if isArrayDecl(s) { //the elements of an array start at 0
+					length, err := p.evalValue(s.value.params[0])
 					if err != nil {
 						return err
 					}
+					if length < 0 {
+						return p.runtimeError(s.value.offset, "array '" + s.name + "' can't have " + strconv.Itoa(length) + " elements")
+					}
+					p.addVar(&variable{name: s.name, array: make([]int, length)})
+					continue
 				}
 				
-				v.value = val		
-						
-				p.addVar(v) //add the variable to the program
-			} else if pos := strings.Index(s.content, "="); pos != -1 { //if an assignment
-				v := new(variable)
-				v.name = strings.TrimSpace(s.content[:pos])
-				
-				if !p.isVarPresent(v.name) { //if variable is not on the program -> error
-					return errors.New("parseProgram: error using operator '='. variable '" + v.name + "' is not present.")
-				}
-				
-				val, err := strconv.Atoi(s.content[(pos+sizeAssignOP)+1:]) //get value of the assignment
+				val, err := p.evalValue(s.value) //get value of the declaration
 				if err != nil {
-					val, err = p.execFunc(s.content[(pos+sizeAssignOP)+1:]) //execute the function for this operator (=)
+					return err
+				}
+				p.addVar(&variable{name: s.name, value: val}) //add the variable to the program
+			case assignOPSTRING: //if an assignment
+				if !p.isVarPresent(s.name) { //if variable is not on the program -> error
+					return errors.New("parseProgram: error using operator '='. variable '" + s.name + "' is not present.")
+				}
+				if s.index != nil { //an element of an array
+					array, i, err := p.element(s.name, s.index, s.offset)
 					if err != nil {
 						return err
 					}
+					val, err := p.evalValue(s.value) //the element keeps its value if the evaluation fails
+					if err != nil {
+						return err
+					}
+					array[i] = val
+					continue
 				}
-			
-				v.value = val 
-				p.setVar(v) //set the value of the variable on the program
-			}
+				
+				val, err := p.evalValue(s.value) //get value of the assignment
+				if err != nil {
+					return err
+				}
+				p.setVar(&variable{name: s.name, value: val}) //set the value of the variable on the program
 		}
 	}
 	return nil
 }
 
-// execFunc executes the the different declared functions from a statement
-// return int, error
-func (p *program) execFunc(code string) (int, error) {
-	if strings.Index(code, "zero()") != -1 { //if the zero function was called
-		return zero(), nil
-	} else if pos := strings.Index(code, "val"); pos != -1 { //if the val function was called
-		valueString := code[strings.Index(code, "(") + sizeParenthesis:strings.Index(code, ")")] //extract the parameter's value of the function
-		
-		v, err := strconv.Atoi(valueString)
-		if err != nil { //if value is not a number
-			if currVar, err := p.getVar(valueString); err == nil { //check on the program if a variable has this id
-				return val(currVar.value), nil
-			}
-			return 0, err
+// execWhile executes the body of a WHILE statement as long as its logic expression is true
+// return error
+func (p *program) execWhile(s stmt) error {
+	p.level++
+	defer func() { p.level-- }()
+	
+	return p.iterateWhile(s)
+}
+
+// iterateWhile executes the iterations of a WHILE statement, from the evaluation of its logic expression
+// return error
+func (p *program) iterateWhile(s stmt) error {
+	if p.accelerate && p.tracer == nil && p.accelerateWhile(s) { //the traced programs execute every iteration
+		return nil
+	}
+	for {
+		ok, err := s.cond.eval(p)
+		if err != nil || !ok {
+			return err
 		}
-		return val(v), nil
-	} else if pos := strings.Index(code, "inc"); pos != -1 { //if th inc function was called
-		valueString := code[strings.Index(code, "(") + sizeParenthesis:strings.Index(code, ")")] //extract the parameter's value of the function	
 		
-		v, err := strconv.Atoi(valueString)
-		if err != nil { //if value is not a number
-			if currVar, err := p.getVar(valueString); err == nil { //check on the program if a variable has this id
-				return inc(currVar.value), nil
-			}
-			return 0, err
+		if err := p.execBlock(s.body); err != nil {
+			return err
 		}
-		return inc(v), nil
-	} else if pos := strings.Index(code, "dec"); pos != -1 {
-		valueString := code[strings.Index(code, "(") + sizeParenthesis:strings.Index(code, ")")] //extract the parameter's value of the function
-		
-		v, err := strconv.Atoi(valueString)
-		if err != nil { //if value is not a number
-			if currVar, err := p.getVar(valueString); err == nil { //check on the program if a variable has this id
-				return dec(currVar.value), nil
+		if err := p.step(); err != nil { //the evaluation of the expression is a step too
+			return err
+		}
+	}
+}
+
+// execBlock executes a loop body, whose variables are declared again on every iteration: they are dropped
+// at the end of the body (and kept if the body is stopped, e.g. to pause the program)
+// return error
+func (p *program) execBlock(stmts []stmt) error {
+	p.scopes = append(p.scopes, len(p.vars))
+	if err := p.execStmts(stmts); err != nil {
+		return err
+	}
+	p.endBlock()
+	return nil
+}
+
+// endBlock drops the variables of the innermost loop body being executed
+func (p *program) endBlock() {
+	last := len(p.scopes) - 1
+	p.vars, p.scopes = p.vars[:p.scopes[last]], p.scopes[:last]
+}
+
+// evalValue returns the value of a number, a variable or a function call
+// return int, error
+func (p *program) evalValue(v *valueExpr) (int, error) {
+	switch v.kind {
+		case numberValue:
+			return strconv.Atoi(v.text)
+		case varValue:
+			currVar, err := p.getVar(v.text)
+			if err != nil {
+				return 0, errors.New("evalValue: variable '" + v.text + "' not defined")
+			}
+			if currVar.array != nil {
+				return 0, p.runtimeError(v.offset, "array '" + v.text + "' used without an index")
 			}
+			return currVar.value, nil
+		case indexValue:
+			array, i, err := p.element(v.text, v.params[0], v.offset)
+			if err != nil {
+				return 0, err
+			}
+			return array[i], nil
+	}
+
+	params := make([]int, len(v.params))
+	for i, param := range v.params {
+		val, err := p.evalValue(param)
+		if err != nil {
 			return 0, err
-			
 		}
-		return dec(v), nil
-	} else {		
-		return 0, errors.New("execFunc: function not detected")
+		params[i] = val
+	}
+	if proc, ok := p.procs[v.text]; ok {
+		return p.execProc(proc, params)
+	}
+	return p.funcs.execFunc(v.text, params)
+}
+
+// element returns the elements of an array and the index of one of them, which must be inside its bounds
+// (offset is the position of the access on the code)
+// return []int, int, error
+func (p *program) element(name string, index *valueExpr, offset int) ([]int, int, error) {
+	v, err := p.getVar(name)
+	if err != nil || v.array == nil {
+		return nil, 0, p.runtimeError(offset, "variable '" + name + "' is not an array")
+	}
+	i, err := p.evalValue(index)
+	if err != nil {
+		return nil, 0, err
+	}
+	if i < 0 || i >= len(v.array) {
+		return nil, 0, p.runtimeError(offset, "index " + strconv.Itoa(i) + " out of the bounds of array '" + name + "' (" + strconv.Itoa(len(v.array)) + " elements)")
+	}
+	return v.array, i, nil
+}
+
+// runtimeError returns an error found while executing the code on an offset
+// return error
+func (p *program) runtimeError(offset int, msg string) error {
+	if offset > len(p.code) { //the code is unknown
+		offset = 0
+	}
+	return &RuntimeError{Pos: newPosition(p.name, p.code, offset), Msg: msg}
+}
+
+// isArrayDecl checks if a statement declares an array (e.g. a := array(3))
+// return bool
+func isArrayDecl(s stmt) bool {
+	return s.op == declareOPSTRING && s.value.kind == callValue && s.value.text == arraySTRING
+}
+
+// execProc executes a function defined on the program with the given parameters
+// the function is executed as a subprogram, which only knows its parameters
+// return int, error
+func (p *program) execProc(proc *stmt, params []int) (int, error) {
+	if len(params) != len(proc.params) {
+		return 0, errors.New("execProc: function '" + proc.name + "' expects " + strconv.Itoa(len(proc.params)) + " parameter(s)")
+	}
+	if p.depth >= maxCallDepth {
+		return 0, errors.New("execProc: too many nested calls of function '" + proc.name + "'")
+	}
+	
+	subprogram := initProgram()
+	subprogram.procs = p.procs
+	subprogram.funcs = p.funcs
+	subprogram.stdio = p.stdio
+	subprogram.name = p.name
+	subprogram.code = p.code
+	subprogram.steps = p.steps //the steps of the subprogram count on the main program
+	subprogram.maxSteps = p.maxSteps
+	subprogram.depth = p.depth + 1
+	subprogram.level = p.level + 1
+	subprogram.tracer = p.tracer
+	subprogram.accelerate = p.accelerate
+	subprogram.caller = p
+	subprogram.procName = proc.name
+	if proc.lib != nil { //the function only knows the functions of its library, and it is not traced
+		subprogram.procs = proc.lib.procs
+		subprogram.name = proc.lib.name
+		subprogram.code = proc.lib.code
+		subprogram.tracer = nil
+	}
+	for i, name := range proc.params {
+		subprogram.addVar(&variable{name: name, value: params[i]})
+	}
+	
+	result := 0
+	err := subprogram.execStmts(proc.body)
+	if err == nil && subprogram.tracer != nil { //the RETURN is traced as a statement of the function
+		subprogram.current = proc
+		err = subprogram.tracer.beforeStmt(subprogram, proc)
+	}
+	if err == nil {
+		result, err = subprogram.evalValue(proc.value) //the returned value is evaluated with the variables of the function
+	}
+	
+	p.steps = subprogram.steps
+	return result, err
+}
+
+// execFunc executes one of the predefined functions with the given parameters
+// return int, error
+func execFunc(name string, params []int) (int, error) {
+	f, ok := predefinedFuncs[name]
+	if !ok {
+		return 0, errors.New("execFunc: function '" + name + "' not detected")
 	}
+	if f.arity != len(params) {
+		return 0, errors.New("execFunc: function '" + name + "' expects " + strconv.Itoa(f.arity) + " parameter(s)")
+	}
+	return f.fn(params)
 }
 
 //printVars prints the different variables of a program
 func (p *program) printVars() {
 	for _, v := range p.vars {
-		fmt.Println(v.name + " => " + strconv.Itoa(v.value))	
+		fmt.Println(v.name + " => " + v.String())	
 	}
 }
 
+// String returns the value of a variable, or its elements if it is an array (e.g. "[1, 0, 2]")
+// return string
+func (v variable) String() string {
+	if v.array == nil {
+		return strconv.Itoa(v.value)
+	}
+	return formatArray(v.array)
+}
+
+// copyArray returns a copy of the elements of an array
+// return []int (nil if the array is nil)
+func copyArray(array []int) []int {
+	if array == nil {
+		return nil
+	}
+	return append(make([]int, 0, len(array)), array...)
+}
+
+// formatArray returns the elements of an array (e.g. "[1, 0, 2]")
+// return string
+func formatArray(array []int) string {
+	elems := make([]string, len(array))
+	for i, e := range array {
+		elems[i] = strconv.Itoa(e)
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
 //printStmts prints the different statements of a program
 func (p *program) printStmts() {
 	for i, v := range p.stmts {
@@ -379,10 +720,111 @@ func (p *program) printStmts() {
 	}
 }
 
+// Program is a parsed while program, which can be executed or encoded
+type Program struct {
+	name string //name of the source of the code (e.g. the file)
+	code string //source code of the program
+	stmts []stmt //statements of the program
+	funcs *Interpreter //interpreter that parsed the program, whose registered functions can be called
+}
+
+// ParseCode parses the code of a program and checks it statically
+// return *Program, error
+func ParseCode(code string) (*Program, error) {
+	return defaultInterpreter.ParseCode(code)
+}
+
+// ParseReader parses the code read from r and checks it statically.
+// The name of the source (e.g. the file) is shown on the positions of the errors (e.g. "lib/mult.while:12:5: ...")
+// return *Program, error
+func ParseReader(name string, r io.Reader) (*Program, error) {
+	return defaultInterpreter.ParseReader(name, r)
+}
+
+// ParseFile parses the code of a file and checks it statically, the path is shown on the positions of the errors
+// return *Program, error
+func ParseFile(path string) (*Program, error) {
+	return defaultInterpreter.ParseFile(path)
+}
+
+// parseProgramCode parses the code of a named source and checks it statically
+// return *Program, error
+func (in *Interpreter) parseProgramCode(name string, code string) (*Program, error) {
+	p := in.newProgram()
+	p.name = name
+	if err := p.getStmts(code); err != nil {
+		return nil, err
+	}
+	if errs := p.check(code); len(errs) > 0 {
+		return nil, errs
+	}
+	return &Program{name: name, code: code, stmts: p.stmts, funcs: in}, nil
+}
+
+// String returns the source code of the program
+// return string
+func (prog *Program) String() string {
+	return prog.code
+}
+
+// Name returns the name of the source of the program (empty if the code has no name)
+// return string
+func (prog *Program) Name() string {
+	return prog.name
+}
+
+// Exec executes the program (set log to true, to display the progress per console)
+// return error
+func (prog *Program) Exec(log bool) error {
+	p := prog.funcs.newProgram()
+	p.name = prog.name
+	p.code = prog.code
+	p.stmts = prog.stmts
+
+	if log {
+		fmt.Print("Input program: ")
+		fmt.Println(prog.code)
+	}
+	return p.exec(log)
+}
+
+// Lint looks for suspicious statements on the program and returns every finding, except the ones of the disabled rules
+// return []*LintFinding
+func (prog *Program) Lint(disabled ...LintRule) []*LintFinding {
+	p := initProgram()
+	p.name = prog.name
+	p.stmts = prog.stmts
+
+	return p.lint(prog.code, disabled)
+}
+
+// Ranges interprets the program over the intervals and returns the possible range of every variable before
+// every statement and the conditions of the WHILEs that are always true or always false
+// return *RangeAnalysis
+func (prog *Program) Ranges() *RangeAnalysis {
+	p := initProgram()
+	p.name = prog.name
+	p.stmts = prog.stmts
+
+	return p.analyzeRanges(prog.code)
+}
+
+// Termination looks for a ranking function of every loop of the program, and returns whether every loop
+// "terminates", "may not terminate" or its termination is "unknown"
+// return []*LoopTermination
+func (prog *Program) Termination() []*LoopTermination {
+	p := initProgram()
+	p.name = prog.name
+	p.stmts = prog.stmts
+
+	return p.analyzeTermination(prog.code)
+}
+
 // ExecCode executes the code as a parameter (set log to true, to display the progress per console)
 // return bool
 func ExecCode(code string, log bool) error {
 	mainProgram := initProgram() //initialize the program
+	mainProgram.code = code
     
     if log {
 	   fmt.Print("Input program: ")
@@ -394,20 +836,34 @@ func ExecCode(code string, log bool) error {
 		return err
 	}
 	
+	if errs := mainProgram.check(code); len(errs) > 0 { //check the statements before executing them
+		fmt.Println(errs)
+		return errs
+	}
+	
+	if err := mainProgram.exec(log); err != nil {
+		fmt.Println(err)
+		return err
+	}
+	return nil
+}
+
+// exec executes the statements of a program already checked (set log to true, to display the progress per console)
+// return error
+func (p *program) exec(log bool) error {
     if log {
 	   fmt.Println("Code blocks: ")	
-	   mainProgram.printStmts()
+	   p.printStmts()
        fmt.Println("Loading...")
     }	
 	
-	if err := mainProgram.parseProgram(); err != nil { //parse the code and execute it
-		fmt.Println(err)
+	if err := p.parseProgram(); err != nil { //parse the code and execute it
 		return err
 	}
     
     if log { //if desired, print the output variables
 	   fmt.Println("Output: ")
-	   mainProgram.printVars()
+	   p.printVars()
     }
     
     return nil
@@ -1,6 +1,13 @@
 package whileinterp
 
-import "testing"
+import (
+    "io/ioutil"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strings"
+    "testing"
+)
 
 const testCode1 = "xo := 2; x1 := inc(3); x2 := dec(2); WHILE(xo != x1) DO xo = inc(xo) OD;"
 const testCode2 = "xo := zero(); x1 := 2; x2 := inc(x1); WHILE(x1 < x2) DO x2 = dec(x2) OD;"
@@ -41,59 +48,47 @@ func TestParseExprIsNot(t *testing.T) {
 }
 
 func TestEvalExprLittleOf(t *testing.T) {
-    le := new(logicExpr)
-    if err := le.parseExpr(testExprLittleOfSTRING); err != nil {
-        t.Error(err)
-    }
-    
-    le.firstVar.value = 2
-    le.secondVar.value = 3
-    
-    if ret := le.evalLogicExpr(); ret != true {
-        t.Error("returned value not valid")
-    } 
+    doTestEvalExpr(testExprLittleOfSTRING, 2, 3, true, t)
 }
 
 func TestEvalExprBiggerOf(t *testing.T) {
-    le := new(logicExpr)
-    if err := le.parseExpr(testExprBiggerOfSTRING); err != nil {
-        t.Error(err)
-    }
-    
-    le.firstVar.value = 3
-    le.secondVar.value = 2
-    
-    if ret := le.evalLogicExpr(); ret != true {
-        t.Error("returned value not valid")
-    }    
+    doTestEvalExpr(testExprBiggerOfSTRING, 3, 2, true, t)
 }
 
 func TestEvalExprIs(t *testing.T) {
-    le := new(logicExpr)
-    if err := le.parseExpr(testExprIsSTRING); err != nil {
-        t.Error(err)
-    }
-    
-    le.firstVar.value = 3
-    le.secondVar.value = 3
-    
-    if ret := le.evalLogicExpr(); ret != true {
-        t.Error("returned value not valid")
-    }   
+    doTestEvalExpr(testExprIsSTRING, 3, 3, true, t)
 }
 
 func TestEvalExprIsNot(t *testing.T) {
+    doTestEvalExpr(testExprIsNotSTRING, 3, 2, true, t)
+}
+
+func TestEvalExprLogic(t *testing.T) {
+    doTestEvalExpr("xo <= x1 AND x1 >= xo", 2, 2, true, t)
+    doTestEvalExpr("xo < x1 OR x1 < xo AND xo == x1", 3, 3, false, t)
+    doTestEvalExpr("(xo < x1 OR x1 < xo) AND NOT xo == x1", 3, 2, true, t)
+    doTestEvalExpr("NOT (xo != x1 OR xo > x1)", 3, 3, true, t)
+}
+
+func doTestEvalExpr(exprString string, first, second int, expecRet bool, t *testing.T) {
     le := new(logicExpr)
-    if err := le.parseExpr(testExprIsNotSTRING); err != nil {
+    if err := le.parseExpr(exprString); err != nil {
         t.Error(err)
+        return
+    }
+
+    p := initProgram()
+    p.addVar(&variable{name: "xo", value: first})
+    p.addVar(&variable{name: "x1", value: second})
+
+    ret, err := le.eval(p)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    if ret != expecRet {
+        t.Error("returned value not valid: ", exprString)
     }
-    
-    le.firstVar.value = 3
-    le.secondVar.value = 2
-    
-    if ret := le.evalLogicExpr(); ret != true {
-        t.Error("returned value not valid")
-    }   
 }
 
 func TestGetStmts1(t *testing.T) {
@@ -103,6 +98,56 @@ func TestGetStmts1(t *testing.T) {
     }
 }
 
+func TestGetStmtsNested(t *testing.T) {
+    p := initProgram()
+    code := "xo := 2; x1 := 0; WHILE(xo > x1) DO xo = dec(xo); WHILE(xo < x1) DO xo = inc(xo) OD OD;"
+    if err := p.getStmts(code); err != nil {
+        t.Error(err)
+        return
+    }
+
+    if len(p.stmts) != 3 || len(p.stmts[2].body) != 2 || p.stmts[2].body[1].content != "WHILE(xo < x1) DO xo = inc(xo) OD" {
+        t.Error("unexpected returned statements: ", p.stmts)
+    }
+}
+
+func TestGetStmtsNewlines(t *testing.T) {
+    code := `# counts until x1
+xo := 0 // first value
+x1 := 3;
+
+WHILE(xo < x1
+    AND NOT xo == 5) DO
+    xo = inc(xo)   # next value
+    x1 = val(x1);
+OD`
+    expecCode := "xo := 0; x1 := 3; WHILE(xo < x1 AND NOT xo == 5) DO xo = inc(xo); x1 = val(x1) OD;"
+
+    p := initProgram()
+    if err := p.getStmts(code); err != nil {
+        t.Error(err)
+        return
+    }
+    if retCode := formatStmts(p.stmts); retCode != expecCode {
+        t.Error("unexpected returned code:\n returned: ", retCode, "\n expected: ", expecCode)
+    }
+}
+
+func TestGetStmtsNewlinesError(t *testing.T) {
+    p := initProgram()
+    err := p.getStmts("xo := 0\nx1 := 3 xo = inc(xo) # missing ';'")
+    if err == nil || err.Error() != "2:9: ';' expected, found 'xo'" {
+        t.Error("unexpected returned error:\n returned: ", err, "\n expected: 2:9: ';' expected, found 'xo'")
+    }
+}
+
+func TestGetStmtsSyntaxError(t *testing.T) {
+    p := initProgram()
+    if err := p.getStmts("xo := 2; WHILE(xo > x1) DO xo = dec(xo);"); err == nil || err.Error() != "1:41: 'OD' expected, found end of code" {
+        t.Error("unexpected returned error: ", err)
+    }
+}
+
 func TestIsVarPresent(t *testing.T) {
     p := initProgram()
     v := new(variable)
@@ -151,121 +196,101 @@ func TestGetVar(t *testing.T) {
         t.Error(err)
         return
     }
-    if retVar != *v {
+    if !reflect.DeepEqual(retVar, *v) {
         t.Error("returned variable not equal")
     }
 }
 
 func TestGetExprFromWhile(t *testing.T) {
-    whileCode := "WHILE(xo != x1) DO xo = x1 OD"
+    whileCode := "WHILE(xo != x1) DO xo = val(x1) OD"
     expecExpr := "xo != x1"
     
-    if retExpr := getExprFromWhile(whileCode); retExpr != expecExpr {
+    s := doTestGetWhile(whileCode, t)
+    if s == nil {
+        return
+    }
+    if retExpr := formatLogicExpr(s.cond); retExpr != expecExpr {
         t.Error("unexpected returned expression:\n returned: ", retExpr, "\n expected: ", expecExpr)
     }
 }
 
 func TestGetStmtFromWhile(t *testing.T) {
-    whileCode := "WHILE(xo != x1) DO xo = x1 OD"
-    expecStmt := "xo = x1"
+    whileCode := "WHILE(xo != x1) DO xo = val(x1) OD"
+    expecStmt := "xo = val(x1)"
     
-    if retStmt := getStmtFromWhile(whileCode); retStmt != expecStmt {
+    s := doTestGetWhile(whileCode, t)
+    if s == nil {
+        return
+    }
+    if retStmt := s.body[0].content; retStmt != expecStmt {
         t.Error("unexpected returned statement:\n returned: ", retStmt, "\n expected: ", expecStmt)
     }
 }
 
 func TestParseWhile1(t *testing.T) {
-    p := initProgram()
-    xo := new(variable)
-    xo.name = "xo"
-    xo.value = 2
-    
-    x1 := new(variable)
-    x1.name = "x1"
-    x1.value = 3
-    
-    whileCode := "WHILE(xo != x1) DO xo = x1 OD"
-    expectedStmt := "xo = x1"
+    whileCode := "WHILE(xo != x1) DO xo = val(x1) OD"
     
-    expectedExpr := new(logicExpr)
-    expectedExpr.op = "!="
-    expectedExpr.firstVar = *xo
-    expectedExpr.secondVar = *x1
-    
-    doTestParseWhile(whileCode, xo, x1, p, expectedExpr, expectedStmt, t)
+    doTestParseWhile(whileCode, "!=", "xo", "x1", "xo = val(x1)", t)
 }
 
 func TestParseWhile2(t *testing.T) {
-    p := initProgram()
-    xo := new(variable)
-    xo.name = "xo"
-    xo.value = 2
-    
-    x1 := new(variable)
-    x1.name = "x1"
-    x1.value = 3
-    
     whileCode := "WHILE(xo > x1) DO xo = inc(x1) OD"
-    expectedStmt := "xo = inc(x1)"
-    
-    expectedExpr := new(logicExpr)
-    expectedExpr.op = ">"
-    expectedExpr.firstVar = *xo
-    expectedExpr.secondVar = *x1
     
-    doTestParseWhile(whileCode, xo, x1, p, expectedExpr, expectedStmt, t)
+    doTestParseWhile(whileCode, ">", "xo", "x1", "xo = inc(x1)", t)
 }
 
 func TestParseWhile3(t *testing.T) {
-    p := initProgram()
-    xo := new(variable)
-    xo.name = "xo"
-    xo.value = 2
-    
-    x1 := new(variable)
-    x1.name = "x1"
-    x1.value = 3
-    
     whileCode := "WHILE(xo == x1) DO xo = dec(x1) OD"
-    expectedStmt := "xo = dec(x1)"
-    
-    expectedExpr := new(logicExpr)
-    expectedExpr.op = "=="
-    expectedExpr.firstVar = *xo
-    expectedExpr.secondVar = *x1
     
-    doTestParseWhile(whileCode, xo, x1, p, expectedExpr, expectedStmt, t)
+    doTestParseWhile(whileCode, "==", "xo", "x1", "xo = dec(x1)", t)
 }
 
-func doTestParseWhile(whileCode string, firstVar, secondVar *variable, p *program, expectedExpr *logicExpr, expectedStmt string, t *testing.T) {        
-    if err := p.addVar(firstVar); err != nil {
-        t.Error(err)
+func TestParseWhile4(t *testing.T) {
+    whileCode := "WHILE(xo <= x1 OR NOT x1 >= xo) DO xo = dec(x1) OD"
+    
+    s := doTestGetWhile(whileCode, t)
+    if s == nil {
         return
-    }    
-    if err := p.addVar(secondVar); err != nil {
-        t.Error(err)
+    }
+    if s.cond.op != orOPSTRING || s.cond.left.op != "<=" || s.cond.right.op != notOPSTRING || s.cond.right.left.op != ">=" {
+        t.Error("unexpected returned expression: ", formatLogicExpr(s.cond))
+    }
+}
+
+func doTestParseWhile(whileCode string, expectedOp, expectedFirst, expectedSecond string, expectedStmt string, t *testing.T) {        
+    s := doTestGetWhile(whileCode, t)
+    if s == nil {
         return
-    }   
+    }
     
-    retExpr, retStmt, err := p.parseWhile(whileCode)
-    if err != nil {
-        t.Error(err)
-        return
-    }    
-    if *retExpr != *expectedExpr { //without the pointers not working
-        t.Error("unexpected returned expression:\n returned: ", retExpr, "\n expected: ", expectedExpr)
+    retExpr := s.cond
+    if retExpr.op != expectedOp || retExpr.first.text != expectedFirst || retExpr.second.text != expectedSecond {
+        t.Error("unexpected returned expression:\n returned: ", formatLogicExpr(retExpr), "\n expected: ", expectedFirst, expectedOp, expectedSecond)
     }    
-    if retStmt != expectedStmt {
+    if len(s.body) != 1 || s.body[0].content != expectedStmt {
         t.Error("unexpected returned statement")
     }
 }
 
+func doTestGetWhile(whileCode string, t *testing.T) *stmt {
+    p := initProgram()
+    if err := p.getStmts(whileCode); err != nil {
+        t.Error(err)
+        return nil
+    }
+    if len(p.stmts) != 1 || p.stmts[0].op != whileFuncSTRING {
+        t.Error("unexpected returned statements: ", p.stmts)
+        return nil
+    }
+    return &p.stmts[0]
+}
+
 func TestExecFuncZero(t *testing.T) {
     p := initProgram()    
     expecVal := 0
     
     funcCode := "xo = zero()"
-    retVal, err := p.execFunc(funcCode)
+    retVal, err := doTestExecFunc(p, funcCode)
     if err != nil {
         t.Error(err)
         return
@@ -281,7 +306,7 @@ func TestExecFuncValNum(t *testing.T) {
     expecVal := 2
     
     funcCode := "xo = val(2)"
-    retVal, err := p.execFunc(funcCode)
+    retVal, err := doTestExecFunc(p, funcCode)
     if err != nil {
         t.Error(err)
         return
@@ -305,7 +330,7 @@ func TestExecFuncValVar(t *testing.T) {
     expecVal := 2
     
     funcCode := "xo = val(x1)"
-    retVal, err := p.execFunc(funcCode)
+    retVal, err := doTestExecFunc(p, funcCode)
     if err != nil {
         t.Error(err)
         return
@@ -321,7 +346,7 @@ func TestExecFuncIncNum(t *testing.T) {
     expecVal := 3
     
     funcCode := "xo = inc(2)"
-    retVal, err := p.execFunc(funcCode)
+    retVal, err := doTestExecFunc(p, funcCode)
     if err != nil {
         t.Error(err)
         return
@@ -345,7 +370,7 @@ func TestExecFuncIncVar(t *testing.T) {
     expecVal := 3
     
     funcCode := "xo = inc(x1)"
-    retVal, err := p.execFunc(funcCode)
+    retVal, err := doTestExecFunc(p, funcCode)
     if err != nil {
         t.Error(err)
         return
@@ -361,7 +386,7 @@ func TestExecFuncDecNum(t *testing.T) {
     expecVal := 1
     
     funcCode := "xo = dec(2)"
-    retVal, err := p.execFunc(funcCode)
+    retVal, err := doTestExecFunc(p, funcCode)
     if err != nil {
         t.Error(err)
         return
@@ -385,7 +410,7 @@ func TestExecFuncDecVar(t *testing.T) {
     expecVal := 1
     
     funcCode := "xo = dec(x1)"
-    retVal, err := p.execFunc(funcCode)
+    retVal, err := doTestExecFunc(p, funcCode)
     if err != nil {
         t.Error(err)
         return
@@ -396,6 +421,148 @@ func TestExecFuncDecVar(t *testing.T) {
     }
 }
 
+func TestExecFuncNested(t *testing.T) {
+    p := initProgram()    
+    expecVal := 4
+    
+    retVal, err := doTestExecFunc(p, "xo = inc(inc(dec(val(3))))")
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    
+    if retVal != expecVal {
+        t.Error("unexpected returned expression:\n returned: ", retVal, "\n expected: ", expecVal)
+    }
+}
+
+func TestExecProc(t *testing.T) {
+    code := "xo := mult(3, 4); PROC add(a, b) DO WHILE(b > 0) DO a = inc(a); b = dec(b) OD RETURN a OD; " +
+        "PROC mult(a, b) DO r := 0; WHILE(b > 0) DO r = add(r, a); b = dec(b) OD RETURN r OD;"
+    doTestExecProc(code, 12, t)
+}
+
+func TestExecProcRecursive(t *testing.T) {
+    code := "PROC sum(n) DO r := 0; WHILE(n > 0) DO r = inc(sum(dec(n))); n = 0 OD RETURN r OD; xo := sum(20);"
+    doTestExecProc(code, 20, t)
+}
+
+func TestExecProcCompare(t *testing.T) {
+    code := "PROC double(a) DO b := val(a); WHILE(b > 0) DO a = inc(a); b = dec(b) OD RETURN a OD; " +
+        "xo := 0; WHILE(double(xo) < 10 AND inc(xo) != 7) DO xo = inc(xo) OD;"
+    doTestExecProc(code, 5, t)
+}
+
+func TestExecProcTooDeep(t *testing.T) {
+    p := initProgram()
+    p.getStmts("PROC loop(n) DO RETURN loop(inc(n)) OD; xo := loop(0);")
+    if err := p.parseProgram(); err == nil {
+        t.Error("expected error not returned")
+    }
+}
+
+func TestExecBlockScope(t *testing.T) {
+    code := "xo := 0; WHILE(xo < 3) DO t := inc(xo); xo = val(t) OD;"
+    doTestExecVars(code, []variable{{name: "xo", value: 3}}, t)
+}
+
+func TestExecBlockNested(t *testing.T) {
+    code := "a := 0; WHILE(a < 2) DO b := 0; WHILE(b < 2) DO c := inc(b); b = val(c) OD; a = inc(a) OD;"
+    doTestExecVars(code, []variable{{name: "a", value: 2}}, t)
+}
+
+func TestExecBlockShadow(t *testing.T) {
+    code := "x := 5; i := 0; WHILE(i < 2) DO x := val(i); x = inc(x); i = val(x) OD; y := val(x);"
+    doTestExecVars(code, []variable{{name: "x", value: 5}, {name: "i", value: 2}, {name: "y", value: 5}}, t)
+}
+
+func TestExecArray(t *testing.T) {
+    code := "n := 3; a := array(n); i := 0; WHILE(i < n) DO a[i] = inc(i); i = inc(i) OD; a[0] = inc(a[2]); s := val(a[1]);"
+    doTestExecVars(code, []variable{{name: "n", value: 3}, {name: "a", array: []int{4, 2, 3}}, {name: "i", value: 3}, {name: "s", value: 2}}, t)
+}
+
+func TestExecArraySort(t *testing.T) {
+    code := "n := 4; a := array(n); a[0] = 3; a[1] = 1; a[2] = 4; a[3] = 2; i := 0; " +
+        "WHILE(i < n) DO j := inc(i); WHILE(j < n) DO x := val(a[i]); y := val(a[j]); " +
+        "WHILE(y < x) DO a[i] = val(y); a[j] = val(x); x = val(y) OD; j = inc(j) OD; i = inc(i) OD;"
+    doTestExecVars(code, []variable{{name: "n", value: 4}, {name: "a", array: []int{1, 2, 3, 4}}, {name: "i", value: 4}}, t)
+}
+
+func TestExecArrayErrors(t *testing.T) {
+    codes := map[string]string{
+        "a := array(3); a[3] = 1;": "1:16: index 3 out of the bounds of array 'a' (3 elements)",
+        "a := array(2); x := val(a[1]);\ny := val(a[5]);": "2:10: index 5 out of the bounds of array 'a' (2 elements)",
+        "a := array(0); a[0] = 1;": "1:16: index 0 out of the bounds of array 'a' (0 elements)",
+    }
+    for code, expected := range codes {
+        err := ExecCode(code, false)
+        if _, ok := err.(*RuntimeError); !ok || err.Error() != expected {
+            t.Error("unexpected error executing '", code, "'\n returned: ", err, "\n expected: ", expected)
+        }
+    }
+}
+
+func TestExecArrayFailedAssignment(t *testing.T) {
+    code := "PROC f(n) DO WHILE(n > 0) DO n = inc(n) OD RETURN n OD; a := array(2); a[0] = 5; a[0] = f(1)"
+    prog, _ := ParseCode(code)
+    e := prog.NewExecution(20)
+    if err := e.Run(); err != ErrUndefined {
+        t.Fatal("expected ErrUndefined, returned: ", err)
+    }
+    if snap := frameVars(e.p); !reflect.DeepEqual(snap, []SnapshotVar{{Name: "a", Array: []int{5, 0}}}) {
+        t.Error("expected the element kept, returned: ", snap)
+    }
+
+    results := prog.Batch([]map[string]int{nil}, 1, 20)
+    if !reflect.DeepEqual(results[0].Vars, []SnapshotVar{{Name: "a", Array: []int{5, 0}}}) {
+        t.Error("expected the element kept on the batch, returned: ", results[0].Vars)
+    }
+}
+
+func doTestExecVars(code string, expecVars []variable, t *testing.T) {
+    p := initProgram()
+    if err := p.getStmts(code); err != nil {
+        t.Error(err)
+        return
+    }
+    if errs := p.check(code); len(errs) > 0 {
+        t.Error(errs)
+        return
+    }
+    if err := p.parseProgram(); err != nil {
+        t.Error(err)
+        return
+    }
+
+    if !reflect.DeepEqual(p.vars, expecVars) {
+        t.Error("unexpected variables:\n returned: ", p.vars, "\n expected: ", expecVars)
+    }
+}
+
+func doTestExecProc(code string, expecVal int, t *testing.T) {
+    p := initProgram()
+    if err := p.getStmts(code); err != nil {
+        t.Error(err)
+        return
+    }
+    if err := p.parseProgram(); err != nil {
+        t.Error(err)
+        return
+    }
+
+    if xo, _ := p.getVar("xo"); xo.value != expecVal {
+        t.Error("unexpected returned value:\n returned: ", xo.value, "\n expected: ", expecVal)
+    }
+}
+
+func doTestExecFunc(p *program, funcCode string) (int, error) {
+    s, err := parseCode(funcCode)
+    if err != nil {
+        return 0, err
+    }
+    return p.evalValue(s[0].value)
+}
+
 func TestParseProgram1(t *testing.T) {
     p := initProgram()
     p.getStmts(testCode1)
@@ -404,6 +571,19 @@ func TestParseProgram1(t *testing.T) {
     }
 }
 
+func TestParseProgram2(t *testing.T) {
+    p := initProgram()
+    p.getStmts("xo := 0; x1 := 3; x2 := 5; WHILE(xo < x1 AND NOT(x2 == xo)) DO xo = inc(xo); x2 = dec(x2) OD;")
+    if err := p.parseProgram(); err != nil {
+        t.Error(err)
+        return
+    }
+
+    if xo, _ := p.getVar("xo"); xo.value != 3 {
+        t.Error("unexpected returned value:\n returned: ", xo.value, "\n expected: ", 3)
+    }
+}
+
 func TestExecCode1(t *testing.T) {
     err := ExecCode(testCode1, false)
     if err != nil {
@@ -411,6 +591,69 @@ func TestExecCode1(t *testing.T) {
     }
 }
 
+func TestParseCode1(t *testing.T) {
+    p, err := ParseCode(testCode1)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    if p.String() != testCode1 {
+        t.Error("unexpected returned program:\n returned: ", p, "\n expected: ", testCode1)
+    }
+}
+
+func TestParseReaderNames(t *testing.T) {
+    code := "xo := 2\nWHILE(xo > 0) DO\n    xo = dec(y)\nOD"
+    expecErr := "lib/mult.while:3:14: variable 'y' used before its declaration"
+
+    _, err := ParseReader("lib/mult.while", strings.NewReader(code))
+    if err == nil || err.Error() != expecErr {
+        t.Error("unexpected returned error:\n returned: ", err, "\n expected: ", expecErr)
+    }
+
+    expecErr = "lib/mult.while:2:9: ';' expected, found 'xo'"
+    _, err = ParseReader("lib/mult.while", strings.NewReader("xo := 2\nx1 := 3 xo = inc(xo)"))
+    if err == nil || err.Error() != expecErr {
+        t.Error("unexpected returned error:\n returned: ", err, "\n expected: ", expecErr)
+    }
+}
+
+func TestParseFile(t *testing.T) {
+    dir, err := ioutil.TempDir("", "whileinterp")
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    defer os.RemoveAll(dir)
+
+    path := filepath.Join(dir, "prog.while")
+    if err := ioutil.WriteFile(path, []byte("xo := 2\nx1 := val(xo)"), 0644); err != nil {
+        t.Error(err)
+        return
+    }
+
+    p, err := ParseFile(path)
+    if err != nil {
+        t.Error(err)
+        return
+    }
+    if p.Name() != path {
+        t.Error("unexpected returned name:\n returned: ", p.Name(), "\n expected: ", path)
+    }
+    if err := p.Exec(false); err != nil {
+        t.Error(err)
+    }
+
+    expecFinding := path + ":2:1: variable 'x1' is declared but never read (unused-variable)"
+    if findings := p.Lint(); len(findings) != 1 || findings[0].String() != expecFinding {
+        t.Error("unexpected returned findings:\n returned: ", findings, "\n expected: ", expecFinding)
+    }
+
+    if _, err := ParseFile(filepath.Join(dir, "missing.while")); err == nil {
+        t.Error("expected error not returned")
+    }
+}
+
 /*********************** BENCHMARK TESTING ***********************/
 func BenchmarkParseProgram1(b *testing.B) {
     p := initProgram()